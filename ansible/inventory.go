@@ -0,0 +1,210 @@
+// Package ansible builds ansible dynamic inventories from device42 ips
+package ansible
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/internal/utilities"
+)
+
+// group name prefixes
+const (
+	GroupPrefixBuilding = "building_"
+	GroupPrefixVRFGroup = "vrf_"
+	GroupPrefixSubnet   = "subnet_"
+	GroupPrefixTag      = "tag_"
+)
+
+var groupReplacer = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Options type
+type Options struct {
+	// IncludeUnlabeled adds ips without a label or device, named by address
+	IncludeUnlabeled bool
+}
+
+// Group type
+type Group struct {
+	Hosts    []string               `json:"hosts,omitempty"`
+	Children []string               `json:"children,omitempty"`
+	Vars     map[string]interface{} `json:"vars,omitempty"`
+}
+
+// HostVars type
+type HostVars struct {
+	AnsibleHost string   `json:"ansible_host"`
+	IPID        int      `json:"device42_ip_id"`
+	Address     string   `json:"device42_address"`
+	MacAddress  string   `json:"device42_mac_address,omitempty"`
+	Label       string   `json:"device42_label,omitempty"`
+	Device      string   `json:"device42_device,omitempty"`
+	SubnetID    int      `json:"device42_subnet_id,omitempty"`
+	Subnet      string   `json:"device42_subnet,omitempty"`
+	Network     string   `json:"device42_network,omitempty"`
+	Gateway     string   `json:"device42_gateway,omitempty"`
+	VrfGroup    string   `json:"device42_vrf_group,omitempty"`
+	Buildings   []string `json:"device42_buildings,omitempty"`
+	Tags        []string `json:"device42_tags,omitempty"`
+}
+
+// Inventory type
+type Inventory struct {
+	Groups   map[string]*Group
+	HostVars map[string]HostVars
+}
+
+// Build will create an inventory with a host per ip, grouped by building,
+// vrf group, subnet and subnet tag
+func Build(subnets []device42.Subnet, ips []device42.IP, vrfGroups []device42.VRFGroup, opts Options) *Inventory {
+	inv := Inventory{
+		Groups:   map[string]*Group{},
+		HostVars: map[string]HostVars{},
+	}
+
+	subnetsByID := make(map[int]*device42.Subnet, len(subnets))
+	for i := range subnets {
+		subnetsByID[subnets[i].SubnetID] = &subnets[i]
+	}
+	buildings := make(map[string][]string, len(vrfGroups))
+	for _, v := range vrfGroups {
+		buildings[v.Name] = v.Buildings
+	}
+
+	names := utilities.UniqueNames{}
+
+	sorted := make([]device42.IP, len(ips))
+	copy(sorted, ips)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	for _, ip := range sorted {
+		a, err := ip.Addr()
+		if err != nil {
+			continue
+		}
+
+		name := ip.Label
+		if name == "" {
+			name = ip.Device
+		}
+		if name == "" {
+			if !opts.IncludeUnlabeled {
+				continue
+			}
+			name = a.String()
+		}
+		name = names.Name(name)
+
+		hv := HostVars{
+			AnsibleHost: a.String(),
+			IPID:        ip.ID,
+			Address:     a.String(),
+			MacAddress:  strings.ToLower(ip.MacAddress),
+			Label:       ip.Label,
+			Device:      ip.Device,
+			SubnetID:    ip.SubnetID,
+			Subnet:      ip.Subnet,
+			VrfGroup:    ip.VRFGroup,
+		}
+
+		groups := []string{}
+		if s, ok := subnetsByID[ip.SubnetID]; ok {
+			if p, err := s.Prefix(); err == nil {
+				hv.Network = p.String()
+			}
+			if g, err := s.GatewayAddr(); err == nil {
+				hv.Gateway = g.String()
+			}
+			if hv.Subnet == "" {
+				hv.Subnet = s.Name
+			}
+			if hv.VrfGroup == "" {
+				hv.VrfGroup = s.VrfGroupName
+			}
+			hv.Tags = s.Tags
+
+			subnet := s.Name
+			if subnet == "" {
+				subnet = hv.Network
+			}
+			groups = append(groups, GroupPrefixSubnet+subnet)
+			for _, t := range s.Tags {
+				groups = append(groups, GroupPrefixTag+t)
+			}
+		}
+		if hv.VrfGroup != "" {
+			hv.Buildings = buildings[hv.VrfGroup]
+			groups = append(groups, GroupPrefixVRFGroup+hv.VrfGroup)
+			for _, b := range hv.Buildings {
+				groups = append(groups, GroupPrefixBuilding+b)
+			}
+		}
+
+		inv.HostVars[name] = hv
+		for _, g := range groups {
+			inv.addHost(GroupName(g), name)
+		}
+		if len(groups) == 0 {
+			inv.addHost("ungrouped", name)
+		}
+	}
+
+	for _, g := range inv.Groups {
+		sort.Strings(g.Hosts)
+	}
+
+	return &inv
+}
+
+// GroupName will build an ansible safe group name
+func GroupName(n string) string {
+	return strings.Trim(groupReplacer.ReplaceAllString(strings.ToLower(n), "_"), "_")
+}
+
+func (inv *Inventory) addHost(group, host string) {
+	g, ok := inv.Groups[group]
+	if !ok {
+		g = &Group{}
+		inv.Groups[group] = g
+	}
+	for _, h := range g.Hosts {
+		if h == host {
+			return
+		}
+	}
+	g.Hosts = append(g.Hosts, host)
+}
+
+// List will render the inventory in the dynamic inventory --list schema
+func (inv *Inventory) List() ([]byte, error) {
+	all := Group{Children: []string{}}
+	for n := range inv.Groups {
+		all.Children = append(all.Children, n)
+	}
+	sort.Strings(all.Children)
+
+	l := map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"hostvars": inv.HostVars,
+		},
+		"all": all,
+	}
+	for n, g := range inv.Groups {
+		l[n] = g
+	}
+
+	return json.MarshalIndent(l, "", "  ")
+}
+
+// Host will render a host's vars in the dynamic inventory --host schema. an
+// unknown host renders as an empty object, as ansible expects
+func (inv *Inventory) Host(name string) ([]byte, error) {
+	hv, ok := inv.HostVars[name]
+	if !ok {
+		return []byte("{}"), nil
+	}
+	return json.MarshalIndent(hv, "", "  ")
+}
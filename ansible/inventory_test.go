@@ -0,0 +1,145 @@
+package ansible
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+func TestGroupName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "web", want: "web"},
+		{in: "subnet_Web Servers", want: "subnet_web_servers"},
+		{in: "vrf_10.0.0.0/24", want: "vrf_10_0_0_0_24"},
+		{in: "--tag_a--b--", want: "tag_a_b"},
+	}
+
+	for _, tt := range tests {
+		if got := GroupName(tt.in); got != tt.want {
+			t.Errorf("GroupName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	subnets := []device42.Subnet{
+		{SubnetID: 1, Name: "web", Network: "10.0.0.0", MaskBits: 24, Gateway: "10.0.0.1", VrfGroupName: "prod", Tags: []string{"dmz"}},
+		{SubnetID: 2, Network: "10.0.1.0", MaskBits: 24},
+	}
+	vrfGroups := []device42.VRFGroup{{Name: "prod", Buildings: []string{"HQ"}}}
+	ips := []device42.IP{
+		{ID: 4, Address: "10.0.0.13", Label: "web", SubnetID: 1},
+		{ID: 1, Address: "10.0.0.10", Label: "web", SubnetID: 1, MacAddress: "AA:BB:CC:DD:EE:FF"},
+		{ID: 2, Address: "10.0.0.11", Label: "web", SubnetID: 1},
+		{ID: 3, Address: "10.0.0.12", Label: "web-2", SubnetID: 1},
+		{ID: 5, Address: "10.0.1.5", Device: "db01", SubnetID: 2},
+		{ID: 6, Address: "10.0.1.6", SubnetID: 2},
+		{ID: 7, Address: "10.9.9.9", Label: "lonely"},
+		{ID: 8, Address: "bogus", Label: "broken"},
+	}
+
+	tests := []struct {
+		name   string
+		opts   Options
+		groups map[string][]string
+	}{
+		{
+			name: "labeled",
+			groups: map[string][]string{
+				"subnet_web":         {"web", "web-2", "web-2-2", "web-3"},
+				"tag_dmz":            {"web", "web-2", "web-2-2", "web-3"},
+				"vrf_prod":           {"web", "web-2", "web-2-2", "web-3"},
+				"building_hq":        {"web", "web-2", "web-2-2", "web-3"},
+				"subnet_10_0_1_0_24": {"db01"},
+				"ungrouped":          {"lonely"},
+			},
+		},
+		{
+			name: "unlabeled",
+			opts: Options{IncludeUnlabeled: true},
+			groups: map[string][]string{
+				"subnet_web":         {"web", "web-2", "web-2-2", "web-3"},
+				"tag_dmz":            {"web", "web-2", "web-2-2", "web-3"},
+				"vrf_prod":           {"web", "web-2", "web-2-2", "web-3"},
+				"building_hq":        {"web", "web-2", "web-2-2", "web-3"},
+				"subnet_10_0_1_0_24": {"10.0.1.6", "db01"},
+				"ungrouped":          {"lonely"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		inv := Build(subnets, ips, vrfGroups, tt.opts)
+
+		got := map[string][]string{}
+		for n, g := range inv.Groups {
+			got[n] = g.Hosts
+		}
+		if !reflect.DeepEqual(got, tt.groups) {
+			t.Errorf("%s: groups = %v, want %v", tt.name, got, tt.groups)
+		}
+
+		hosts := []string{}
+		for n := range inv.HostVars {
+			hosts = append(hosts, n)
+		}
+		sort.Strings(hosts)
+		for _, g := range tt.groups {
+			for _, h := range g {
+				if _, ok := inv.HostVars[h]; !ok {
+					t.Errorf("%s: host %s has no vars, hosts are %v", tt.name, h, hosts)
+				}
+			}
+		}
+	}
+
+	inv := Build(subnets, ips, vrfGroups, Options{})
+	want := HostVars{
+		AnsibleHost: "10.0.0.10",
+		IPID:        1,
+		Address:     "10.0.0.10",
+		MacAddress:  "aa:bb:cc:dd:ee:ff",
+		Label:       "web",
+		SubnetID:    1,
+		Subnet:      "web",
+		Network:     "10.0.0.0/24",
+		Gateway:     "10.0.0.1",
+		VrfGroup:    "prod",
+		Buildings:   []string{"HQ"},
+		Tags:        []string{"dmz"},
+	}
+	if got := inv.HostVars["web"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("host vars = %+v, want %+v", got, want)
+	}
+}
+
+func TestInventoryHost(t *testing.T) {
+	inv := Build(nil, []device42.IP{{ID: 1, Address: "10.0.0.1", Label: "a"}}, nil, Options{})
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "a", want: "10.0.0.1"},
+		{host: "missing", want: ""},
+	}
+
+	for _, tt := range tests {
+		b, err := inv.Host(tt.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hv := map[string]interface{}{}
+		if err := json.Unmarshal(b, &hv); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := hv["ansible_host"].(string); got != tt.want {
+			t.Errorf("Host(%q) ansible_host = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
@@ -101,6 +101,10 @@ func (api *API) SetBuilding(b *Building) (*Building, error) {
 		return nil, err
 	}
 
+	if api.IsDryRun() {
+		return b, nil
+	}
+
 	apiResponse := APIResponse{}
 	if err = json.Unmarshal(r, &apiResponse); err != nil {
 		return nil, err
@@ -3,10 +3,15 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/config"
+	"github.com/chopnico/device42-go/credentials"
 
 	CLI "github.com/chopnico/device42-go/internal/cli"
 
@@ -23,6 +28,17 @@ var (
 	DefaultLoggingLevel = "info"
 	DefaultPrintFormat  = "table"
 	DefaultTimeOut      = 60
+
+	// commands that do not talk to device42
+	offlineCommands = map[string]bool{
+		"config":  true,
+		"journal": true,
+		"webhook": true,
+		"login":   true,
+		"logout":  true,
+		"help":    true,
+		"h":       true,
+	}
 )
 
 func main() {
@@ -61,7 +77,7 @@ func main() {
 		},
 		&cli.StringFlag{
 			Name:  "format",
-			Usage: "printing format (json, list, table)",
+			Usage: "printing format (table, list, json, jsonl, yaml, csv, tsv)",
 			Value: "table",
 		},
 		&cli.StringFlag{
@@ -74,11 +90,89 @@ func main() {
 			Usage:   "set http proxy",
 			EnvVars: []string{"DEVICE42_PROXY"},
 		},
+		&cli.StringFlag{
+			Name:    "journal-dir",
+			Usage:   "`DIRECTORY` where change journals are kept",
+			EnvVars: []string{"DEVICE42_JOURNAL_DIR"},
+			Value:   device42.DefaultJournalDir(),
+		},
+		&cli.BoolFlag{
+			Name:  "no-journal",
+			Usage: "do not record changes in a journal",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:    "credentials",
+			Usage:   "`SOURCE` to look up the password in when it is not set (keyring, helper, file)",
+			EnvVars: []string{"DEVICE42_CREDENTIALS"},
+			Value:   credentials.SourceFile,
+		},
+		&cli.StringFlag{
+			Name:    "credential-helper",
+			Usage:   "credential helper `EXECUTABLE` for the helper source",
+			EnvVars: []string{"DEVICE42_CREDENTIAL_HELPER"},
+		},
+		&cli.StringFlag{
+			Name:    "credentials-file",
+			Usage:   "credentials `FILE` for the file source",
+			EnvVars: []string{"DEVICE42_CREDENTIALS_FILE"},
+			Value:   credentials.DefaultFile(),
+		},
+		&cli.StringFlag{
+			Name:    "config",
+			Usage:   "configuration `FILE`",
+			EnvVars: []string{"DEVICE42_CONFIG"},
+			Value:   config.DefaultPath(),
+		},
+		&cli.StringFlag{
+			Name:    "profile",
+			Usage:   "configuration `PROFILE` to use instead of the current one",
+			EnvVars: []string{"DEVICE42_PROFILE"},
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "log changes instead of making them",
+			Value: false,
+		},
 	}
+
+	var journal *device42.Journal
 	app.Before = func(c *cli.Context) error {
 		var err error
 		var api *device42.API
 
+		// flags and environment variables take precedence over the profile.
+		// a broken configuration must not stop it from being fixed
+		cfg, err := config.Load(c.String("config"))
+		if err != nil && c.Args().First() != "config" {
+			return err
+		}
+		var profile *config.Profile
+		if cfg != nil {
+			profile, err = cfg.Profile(c.String("profile"))
+			if err != nil && c.Args().First() != "config" {
+				return err
+			}
+		}
+		if profile != nil {
+			err = applyProfile(c, profile)
+			if err != nil {
+				return err
+			}
+		}
+
+		if offlineCommands[c.Args().First()] {
+			return nil
+		}
+
+		// look up the password rather than taking it from the environment
+		if c.String("password") == "" && c.String("host") != "" {
+			err = lookupCredentials(c)
+			if err != nil {
+				return err
+			}
+		}
+
 		if c.String("username") == "" {
 			cli.ShowAppHelp(c)
 			return errors.New(device42.ErrorEmptyUsername)
@@ -109,12 +203,31 @@ func main() {
 			api.IgnoreSSLErrors()
 		}
 
+		// only log what would change
+		if c.Bool("dry-run") {
+			api.DryRun()
+		}
+
+		// record changes so they can be undone
+		if !c.Bool("no-journal") && !c.Bool("dry-run") {
+			journal = device42.NewJournal(c.String("journal-dir"), strings.Join(c.Args().Slice(), " "))
+			api.Journal(journal)
+		}
+
 		ctx := context.WithValue(c.Context, device42.APIContextKey("api"), api)
 		c.Context = ctx
 
 		return nil
 	}
 
+	app.After = func(c *cli.Context) error {
+		if journal != nil && journal.Len() > 0 {
+			fmt.Fprintf(os.Stderr, "recorded %d changes in journal %s, revert with: %s undo %s\n",
+				journal.Len(), journal.ID, AppName, journal.ID)
+		}
+		return nil
+	}
+
 	// create cli commands
 	CLI.NewCommands(app)
 
@@ -127,3 +240,67 @@ func main() {
 	}
 	os.Exit(0)
 }
+
+// applyProfile will set the flags that were not set from a profile
+func applyProfile(c *cli.Context, p *config.Profile) error {
+	if p.Auth != "" && p.Auth != config.AuthBasic {
+		return errors.New("unsupported auth method " + p.Auth)
+	}
+
+	values := map[string]string{
+		"host":     p.Host,
+		"username": p.Username,
+		"proxy":    p.Proxy,
+		"format":   p.Format,
+
+		"credentials":       p.Credentials,
+		"credential-helper": p.CredentialHelper,
+		"credentials-file":  p.CredentialsFile,
+	}
+	if p.IgnoreSSL {
+		values["ignore-ssl"] = "true"
+	}
+	if p.Timeout != 0 {
+		values["timeout"] = strconv.Itoa(p.Timeout)
+	}
+
+	for k, v := range values {
+		if v == "" || c.IsSet(k) {
+			continue
+		}
+		if err := c.Set(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookupCredentials will set the username and password from the credential
+// source. missing credentials are left for the usual checks
+func lookupCredentials(c *cli.Context) error {
+	src, err := credentials.New(c.String("credentials"), c.String("credential-helper"), c.String("credentials-file"))
+	if err != nil {
+		return err
+	}
+
+	cred, err := src.Get(c.String("host"))
+	if errors.Is(err, credentials.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.String("username") == "" {
+		err = c.Set("username", cred.Username)
+		if err != nil {
+			return err
+		}
+	}
+	if c.String("username") != cred.Username {
+		// stored for someone else
+		return nil
+	}
+	return c.Set("password", cred.Password)
+}
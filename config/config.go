@@ -0,0 +1,164 @@
+// Package config reads and writes the device42 cli configuration file, which
+// holds named profiles for device42 appliances
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// auth methods
+const (
+	AuthBasic = "basic"
+)
+
+// ErrProfileNotFound is returned when a profile does not exist
+var ErrProfileNotFound = errors.New("unable to find profile")
+
+// Profile type
+// the settings used to reach a single appliance
+type Profile struct {
+	Host      string `yaml:"host" json:"host"`
+	Auth      string `yaml:"auth,omitempty" json:"auth,omitempty"`
+	Username  string `yaml:"username,omitempty" json:"username,omitempty"`
+	IgnoreSSL bool   `yaml:"ignore_ssl,omitempty" json:"ignore_ssl,omitempty"`
+	Proxy     string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	Timeout   int    `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Format    string `yaml:"format,omitempty" json:"format,omitempty"`
+	// Credentials is where the password is looked up (keyring, helper,
+	// file). passwords are never stored in the configuration file
+	Credentials      string `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+	CredentialHelper string `yaml:"credential_helper,omitempty" json:"credential_helper,omitempty"`
+	CredentialsFile  string `yaml:"credentials_file,omitempty" json:"credentials_file,omitempty"`
+}
+
+// Config type
+type Config struct {
+	Current  string              `yaml:"current,omitempty" json:"current,omitempty"`
+	Profiles map[string]*Profile `yaml:"profiles" json:"profiles"`
+
+	path string
+}
+
+// DefaultPath returns the default location of the configuration file
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "device42", "config.yaml")
+}
+
+// Load will read a configuration file. a missing file is an empty
+// configuration
+func Load(path string) (*Config, error) {
+	c := Config{
+		Profiles: map[string]*Profile{},
+		path:     path,
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		return nil, errors.New("unable to parse " + path + ": " + err.Error())
+	}
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+
+	return &c, nil
+}
+
+// Save will write the configuration file, only readable by its owner
+func (c *Config) Save() error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	err = os.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path)
+}
+
+// Path returns the location of the configuration file
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Profile will return a profile by name, or the current profile when the
+// name is empty. a nil profile is returned when no profile is selected
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrProfileNotFound, name)
+	}
+	return p, nil
+}
+
+// SetProfile will add or replace a profile. the first profile becomes the
+// current profile
+func (c *Config) SetProfile(name string, p *Profile) error {
+	if name == "" {
+		return errors.New("a profile name is required")
+	}
+	if p.Host == "" {
+		return errors.New("a profile host is required")
+	}
+	if p.Auth == "" {
+		p.Auth = AuthBasic
+	}
+
+	c.Profiles[name] = p
+	if c.Current == "" {
+		c.Current = name
+	}
+	return nil
+}
+
+// Switch will change the current profile
+func (c *Config) Switch(name string) error {
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("%w %s", ErrProfileNotFound, name)
+	}
+	c.Current = name
+	return nil
+}
+
+// Names returns the sorted profile names
+func (c *Config) Names() []string {
+	n := make([]string, 0, len(c.Profiles))
+	for k := range c.Profiles {
+		n = append(n, k)
+	}
+	sort.Strings(n)
+	return n
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadMissing(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Current != "" || len(c.Profiles) != 0 {
+		t.Errorf("Load = %+v, want an empty configuration", c)
+	}
+	if p, err := c.Profile(""); p != nil || err != nil {
+		t.Errorf("Profile(\"\") = %+v, %v, want no profile", p, err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("profiles: ["), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load succeeded on an invalid file")
+	}
+}
+
+func TestSetProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		p       Profile
+		wantErr bool
+	}{
+		{name: "first", profile: "lab", p: Profile{Host: "lab.example.com"}},
+		{name: "second", profile: "prod", p: Profile{Host: "prod.example.com", Credentials: "keyring"}},
+		{name: "no name", p: Profile{Host: "x"}, wantErr: true},
+		{name: "no host", profile: "x", wantErr: true},
+	}
+
+	c, err := Load(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		p := tt.p
+		err := c.SetProfile(tt.profile, &p)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: SetProfile error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && p.Auth != AuthBasic {
+			t.Errorf("%s: auth = %q, want %q", tt.name, p.Auth, AuthBasic)
+		}
+	}
+
+	if c.Current != "lab" {
+		t.Errorf("current = %q, want the first profile", c.Current)
+	}
+	if want := []string{"lab", "prod"}; !reflect.DeepEqual(c.Names(), want) {
+		t.Errorf("Names = %v, want %v", c.Names(), want)
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	c := &Config{Profiles: map[string]*Profile{"lab": {Host: "lab"}, "prod": {Host: "prod"}}, Current: "lab"}
+
+	tests := []struct {
+		name    string
+		wantErr error
+		current string
+	}{
+		{name: "prod", current: "prod"},
+		{name: "missing", wantErr: ErrProfileNotFound, current: "prod"},
+		{name: "lab", current: "lab"},
+	}
+
+	for _, tt := range tests {
+		err := c.Switch(tt.name)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Switch(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if c.Current != tt.current {
+			t.Errorf("Switch(%q) current = %q, want %q", tt.name, c.Current, tt.current)
+		}
+		p, err := c.Profile("")
+		if err != nil || p.Host != tt.current {
+			t.Errorf("Switch(%q) profile = %+v, %v, want %s", tt.name, p, err, tt.current)
+		}
+	}
+
+	if _, err := c.Profile("missing"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("Profile(\"missing\") error = %v, want %v", err, ErrProfileNotFound)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device42", "config.yaml")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Profile{
+		Host:             "lab.example.com",
+		Username:         "admin",
+		IgnoreSSL:        true,
+		Timeout:          30,
+		Format:           "json",
+		Credentials:      "helper",
+		CredentialHelper: "docker-credential-pass",
+	}
+	p := want
+	if err := c.SetProfile("lab", &p); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Save permissions = %04o, want 0600", perm)
+	}
+
+	l, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.Auth = AuthBasic
+	if l.Current != "lab" || !reflect.DeepEqual(*l.Profiles["lab"], want) || l.Path() != path {
+		t.Errorf("Load = %+v, want profile %+v", l, want)
+	}
+}
@@ -0,0 +1,316 @@
+// Package credentials resolves device42 credentials from the system keyring,
+// an external credential helper or a private file
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// source kinds
+const (
+	SourceKeyring = "keyring"
+	SourceHelper  = "helper"
+	SourceFile    = "file"
+)
+
+// keyringService is the service attribute credentials are stored under
+const keyringService = "device42"
+
+// ErrNotFound is returned when no credentials are stored for a host
+var ErrNotFound = errors.New("credentials not found")
+
+// Credential type
+type Credential struct {
+	Host     string `json:"host"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Source type
+// a place credentials are stored, keyed by appliance host
+type Source interface {
+	Get(host string) (*Credential, error)
+	Store(c *Credential) error
+	Erase(host string) error
+}
+
+// New will create a source by kind. helper is the credential helper
+// executable and file the credentials file, which defaults to DefaultFile
+func New(kind, helper, file string) (Source, error) {
+	switch kind {
+	case SourceKeyring:
+		return &Keyring{Command: "secret-tool"}, nil
+	case SourceHelper:
+		if helper == "" {
+			return nil, errors.New("a credential helper executable is required")
+		}
+		return &Helper{Command: helper}, nil
+	case SourceFile, "":
+		if file == "" {
+			file = DefaultFile()
+		}
+		return &File{Path: file}, nil
+	default:
+		return nil, errors.New("unknown credential source " + kind)
+	}
+}
+
+// DefaultFile returns the default location of the credentials file
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "device42", "credentials.json")
+}
+
+// Keyring type
+// stores credentials in the secret service (gnome keyring, kwallet) through
+// libsecret's secret-tool
+type Keyring struct {
+	Command string
+}
+
+// Get implements Source
+func (k *Keyring) Get(host string) (*Credential, error) {
+	out, err := k.run(nil, "lookup", "service", keyringService, "host", host)
+	if err != nil {
+		// secret-tool exits 1 without output when nothing matches
+		if _, ok := err.(*exec.ExitError); ok && len(out) == 0 {
+			return nil, fmt.Errorf("%w for %s", ErrNotFound, host)
+		}
+		return nil, err
+	}
+
+	c := Credential{}
+	err = json.Unmarshal(out, &c)
+	if err != nil {
+		return nil, errors.New("unable to parse keyring secret for " + host)
+	}
+	c.Host = host
+	return &c, nil
+}
+
+// Store implements Source. the username and password are stored together as
+// the secret
+func (k *Keyring) Store(c *Credential) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.run(b, "store", "--label", "device42 "+c.Host, "service", keyringService, "host", c.Host)
+	return err
+}
+
+// Erase implements Source
+func (k *Keyring) Erase(host string) error {
+	_, err := k.run(nil, "clear", "service", keyringService, "host", host)
+	return err
+}
+
+func (k *Keyring) run(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(k.Command, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, errors.New(k.Command + " is required for the keyring credential source (install libsecret-tools)")
+	}
+	if err != nil && stderr.Len() > 0 {
+		return out, errors.New(k.Command + ": " + strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
+
+// Helper type
+// an external executable speaking the docker credential helper protocol.
+// it is called with get, store or erase as its only argument; get and erase
+// read the host on stdin, store reads {"ServerURL","Username","Secret"} and
+// get writes the same
+type Helper struct {
+	Command string
+}
+
+type helperCredential struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// helperNotFound is the message helpers print when nothing is stored
+const helperNotFound = "credentials not found in native keychain"
+
+// Get implements Source
+func (h *Helper) Get(host string) (*Credential, error) {
+	out, err := h.run("get", []byte(host))
+	if err != nil {
+		if strings.Contains(string(out), helperNotFound) || strings.Contains(err.Error(), helperNotFound) {
+			return nil, fmt.Errorf("%w for %s", ErrNotFound, host)
+		}
+		return nil, err
+	}
+
+	c := helperCredential{}
+	err = json.Unmarshal(out, &c)
+	if err != nil {
+		return nil, errors.New("unable to parse " + h.Command + " output")
+	}
+	return &Credential{Host: host, Username: c.Username, Password: c.Secret}, nil
+}
+
+// Store implements Source
+func (h *Helper) Store(c *Credential) error {
+	b, err := json.Marshal(helperCredential{ServerURL: c.Host, Username: c.Username, Secret: c.Password})
+	if err != nil {
+		return err
+	}
+
+	_, err = h.run("store", b)
+	return err
+}
+
+// Erase implements Source
+func (h *Helper) Erase(host string) error {
+	_, err := h.run("erase", []byte(host))
+	return err
+}
+
+func (h *Helper) run(action string, stdin []byte) ([]byte, error) {
+	cmd := exec.Command(h.Command, action)
+	cmd.Stdin = bytes.NewReader(stdin)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(string(out))
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return out, errors.New(h.Command + " " + action + ": " + msg)
+	}
+	return out, nil
+}
+
+// File type
+// a json file of credentials keyed by host, which must only be accessible
+// by its owner
+type File struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Get implements Source
+func (f *File) Get(host string) (*Credential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	creds, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := creds[host]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNotFound, host)
+	}
+	c.Host = host
+	return &c, nil
+}
+
+// Store implements Source
+func (f *File) Store(c *Credential) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	creds, err := f.read()
+	if err != nil {
+		return err
+	}
+	creds[c.Host] = *c
+
+	return f.write(creds)
+}
+
+// Erase implements Source
+func (f *File) Erase(host string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	creds, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := creds[host]; !ok {
+		return fmt.Errorf("%w for %s", ErrNotFound, host)
+	}
+	delete(creds, host)
+
+	return f.write(creds)
+}
+
+// read will read the credentials file, refusing files others can access
+func (f *File) read() (map[string]Credential, error) {
+	creds := map[string]Credential{}
+
+	info, err := os.Stat(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s has permissions %04o, it must only be accessible by its owner (0600)", f.Path, info.Mode().Perm())
+	}
+
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &creds)
+	if err != nil {
+		return nil, errors.New("unable to parse " + f.Path + ": " + err.Error())
+	}
+
+	return creds, nil
+}
+
+func (f *File) write(creds map[string]Credential) error {
+	b, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(f.Path), 0700)
+	if err != nil {
+		return err
+	}
+
+	// a stale temporary file would keep its own permissions
+	tmp := f.Path + ".tmp"
+	_ = os.Remove(tmp)
+	err = os.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.Path)
+}
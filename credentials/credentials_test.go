@@ -0,0 +1,128 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		kind, helper, file string
+		want               Source
+		wantErr            bool
+	}{
+		{kind: SourceKeyring, want: &Keyring{Command: "secret-tool"}},
+		{kind: SourceHelper, helper: "docker-credential-pass", want: &Helper{Command: "docker-credential-pass"}},
+		{kind: SourceHelper, wantErr: true},
+		{kind: SourceFile, file: "/tmp/creds.json", want: &File{Path: "/tmp/creds.json"}},
+		{kind: "", want: &File{Path: DefaultFile()}},
+		{kind: "vault", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := New(tt.kind, tt.helper, tt.file)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("New(%q) error = %v, wantErr %v", tt.kind, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("New(%q) = %#v, want %#v", tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	f := &File{Path: filepath.Join(t.TempDir(), "device42", "credentials.json")}
+
+	if _, err := f.Get("lab"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get on a missing file error = %v, want %v", err, ErrNotFound)
+	}
+
+	for _, c := range []Credential{
+		{Host: "lab", Username: "admin", Password: "a"},
+		{Host: "prod", Username: "svc", Password: "b"},
+		{Host: "lab", Username: "admin", Password: "c"},
+	} {
+		c := c
+		if err := f.Store(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Store permissions = %04o, want 0600", perm)
+	}
+
+	tests := []struct {
+		host    string
+		want    *Credential
+		wantErr error
+	}{
+		{host: "lab", want: &Credential{Host: "lab", Username: "admin", Password: "c"}},
+		{host: "prod", want: &Credential{Host: "prod", Username: "svc", Password: "b"}},
+		{host: "missing", wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		got, err := f.Get(tt.host)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Get(%q) error = %v, want %v", tt.host, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%q) = %+v, want %+v", tt.host, got, tt.want)
+		}
+	}
+
+	if err := f.Erase("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Erase("prod"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Erase of an erased host error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := f.Get("lab"); err != nil {
+		t.Errorf("Erase removed other hosts: %v", err)
+	}
+}
+
+func TestFileInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		body    string
+		perm    os.FileMode
+		wantErr string
+	}{
+		{name: "shared", body: `{}`, perm: 0644, wantErr: "must only be accessible by its owner"},
+		{name: "invalid", body: `{`, perm: 0600, wantErr: "unable to parse"},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".json")
+		if err := os.WriteFile(path, []byte(tt.body), tt.perm); err != nil {
+			t.Fatal(err)
+		}
+		// the umask may have dropped bits
+		if err := os.Chmod(path, tt.perm); err != nil {
+			t.Fatal(err)
+		}
+
+		f := &File{Path: path}
+		_, err := f.Get("lab")
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Get error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+		if err := f.Store(&Credential{Host: "lab"}); err == nil {
+			t.Errorf("%s: Store succeeded", tt.name)
+		}
+	}
+}
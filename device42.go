@@ -11,6 +11,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/chopnico/output"
@@ -74,6 +76,18 @@ func (api *API) Proxy(v string) *API {
 	return api
 }
 
+// DryRun will log mutating requests instead of sending them
+func (api *API) DryRun() *API {
+	api.option("dry-run", true)
+	return api
+}
+
+// IsDryRun checks if mutating requests are only logged
+func (api *API) IsDryRun() bool {
+	d, _ := api.options["dry-run"].(bool)
+	return d
+}
+
 // InfoLogger sets a custom InfoLogger
 func (api *API) InfoLogger(v *log.Logger) *API {
 	api.option("logger-info", v)
@@ -183,9 +197,55 @@ func NewAPIBasicAuth(username string, password string, host string) (*API, error
 }
 
 // Do is a wrapper function for the httpClient Do function
+// changes are recorded when a journal is set
+func (api *API) Do(method, path string, body io.Reader) ([]byte, error) {
+	if api.IsDryRun() {
+		if isReserving(method, path) {
+			// still suggest an ip, just don't reserve it
+			api.WriteToInfoLog("dry run : " + method + " " + path)
+			path = strings.Replace(path, "reserve_ip=yes", "reserve_ip=no", 1)
+		} else if method != "GET" {
+			return api.doDryRun(method, path, body)
+		}
+	}
+
+	if j := api.journal(); j != nil && (method != "GET" || isReserving(method, path)) {
+		return api.doJournaled(j, method, path, body)
+	}
+
+	return api.do(method, path, body)
+}
+
+// doDryRun logs a mutating request and returns a synthetic success response
+func (api *API) doDryRun(method, path string, body io.Reader) ([]byte, error) {
+	msg := "dry run : " + method + " " + path
+	if body != nil {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		params, err := url.ParseQuery(string(b))
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(params))
+		for k := range params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			msg += " " + k + "=" + strings.Join(params[k], ",")
+		}
+	}
+	api.WriteToInfoLog(msg)
+
+	return []byte(`{"code": 0, "msg": ["dry run", 0, ""]}`), nil
+}
+
+// do performs the actual request
 // REVIEW : refactor
 // NOTES: it's pretty ugly
-func (api *API) Do(method, path string, body io.Reader) ([]byte, error) {
+func (api *API) do(method, path string, body io.Reader) ([]byte, error) {
 	req, err := http.NewRequest(method, api.options["url"].(string)+path, body)
 	if api.IsLoggingDebug() {
 		api.WriteToDebugLog("request url : " + req.URL.Host)
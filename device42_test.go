@@ -0,0 +1,64 @@
+package device42
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDoDryRun(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		method, path, body string
+		sent               string
+		logged             string
+	}{
+		{method: "GET", path: "/ips/", sent: "GET /ips/"},
+		{method: "GET", path: "/suggest_ip/?subnet_id=1&reserve_ip=yes", sent: "GET /suggest_ip/?subnet_id=1&reserve_ip=no", logged: "dry run : GET /suggest_ip/?subnet_id=1&reserve_ip=yes\n"},
+		{method: "POST", path: "/ips/", body: "label=web&ipaddress=10.0.0.1", logged: "dry run : POST /ips/ ipaddress=10.0.0.1 label=web\n"},
+		{method: "PUT", path: "/vlans/5/", body: "name=web", logged: "dry run : PUT /vlans/5/ name=web\n"},
+		{method: "DELETE", path: "/subnets/3/", logged: "dry run : DELETE /subnets/3/\n"},
+	}
+
+	for _, tt := range tests {
+		api, err := NewAPIBasicAuth("u", "p", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := bytes.Buffer{}
+		api.url(server.URL)
+		api.InfoLogger(log.New(&info, "", 0)).DryRun()
+		requests = requests[:0]
+
+		var body io.Reader
+		if tt.body != "" {
+			body = strings.NewReader(tt.body)
+		}
+		b, err := api.Do(tt.method, tt.path, body)
+		if err != nil {
+			t.Errorf("%s %s: Do error = %v", tt.method, tt.path, err)
+			continue
+		}
+
+		sent := strings.Join(requests, ", ")
+		if sent != tt.sent {
+			t.Errorf("%s %s: sent %q, want %q", tt.method, tt.path, sent, tt.sent)
+		}
+		if info.String() != tt.logged {
+			t.Errorf("%s %s: logged %q, want %q", tt.method, tt.path, info.String(), tt.logged)
+		}
+		if tt.sent == "" && newAPIResponse(b).Code != 0 {
+			t.Errorf("%s %s: response = %s, want a success", tt.method, tt.path, b)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Package dhcp generates kea and isc dhcpd configuration from device42
+// subnets and ips
+package dhcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/internal/utilities"
+	"github.com/chopnico/device42-go/ipmath"
+)
+
+// Options type
+type Options struct {
+	// Options maps dhcp option names to values. values are go templates
+	// executed against TemplateData, e.g. "routers": "{{.Gateway}}"
+	Options map[string]string
+	// ValidLifetime is the lease time in seconds (0 leaves it unset)
+	ValidLifetime int
+	// Interfaces kea should listen on
+	Interfaces []string
+	// Family keeps only ipv4 (4) or ipv6 (6) subnets, 0 keeps both
+	Family int
+}
+
+// TemplateData type
+// the data available to option templates
+type TemplateData struct {
+	Subnet     device42.Subnet
+	Network    string
+	Netmask    string
+	Prefix     string
+	Gateway    string
+	RangeBegin string
+	RangeEnd   string
+}
+
+// Host type
+// a static reservation of an address to a mac address
+type Host struct {
+	Name       string
+	MacAddress string
+	Address    netip.Addr
+}
+
+// Option type
+type Option struct {
+	Name string
+	Data string
+}
+
+// Subnet type
+type Subnet struct {
+	ID         int
+	Name       string
+	Prefix     netip.Prefix
+	RangeBegin netip.Addr
+	RangeEnd   netip.Addr
+	Options    []Option
+	Hosts      []Host
+}
+
+// Config type
+type Config struct {
+	Subnets       []Subnet
+	ValidLifetime int
+	Interfaces    []string
+}
+
+var hostnameReplacer = regexp.MustCompile(`[^A-Za-z0-9-]+`)
+
+// Build will create a dhcp configuration from subnets and the ips within
+// them. ips without a mac address are left out, as are subnets containing
+// other subnets since dhcp servers reject overlapping subnets
+func Build(subnets []device42.Subnet, ips []device42.IP, opts Options) (*Config, error) {
+	if opts.Family != 0 && opts.Family != 4 && opts.Family != 6 {
+		return nil, fmt.Errorf("invalid family %d, expected 4 or 6", opts.Family)
+	}
+
+	c := Config{
+		ValidLifetime: opts.ValidLifetime,
+		Interfaces:    opts.Interfaces,
+	}
+
+	templates := map[string]*template.Template{}
+	optionNames := []string{}
+	for name, o := range opts.Options {
+		t, err := template.New(name).Option("missingkey=error").Parse(o)
+		if err != nil {
+			return nil, fmt.Errorf("option %s: %w", name, err)
+		}
+		templates[name] = t
+		optionNames = append(optionNames, name)
+	}
+	sort.Strings(optionNames)
+
+	names := utilities.UniqueNames{}
+
+	hosts := map[int][]device42.IP{}
+	for _, i := range ips {
+		if i.MacAddress != "" {
+			hosts[i.SubnetID] = append(hosts[i.SubnetID], i)
+		}
+	}
+
+	prefixes := make([]netip.Prefix, len(subnets))
+	for i, s := range subnets {
+		p, err := s.Prefix()
+		if err != nil {
+			return nil, fmt.Errorf("subnet %d: %w", s.SubnetID, err)
+		}
+		prefixes[i] = p
+	}
+
+	for i, s := range subnets {
+		p := prefixes[i]
+		if (opts.Family == 4 && !p.Addr().Is4()) || (opts.Family == 6 && !p.Addr().Is6()) {
+			continue
+		}
+		if isContainer(p, prefixes) {
+			continue
+		}
+
+		var err error
+		sub := Subnet{
+			ID:     s.SubnetID,
+			Name:   s.Name,
+			Prefix: p,
+		}
+		if s.RangeBegin != "" && s.RangeEnd != "" {
+			sub.RangeBegin, sub.RangeEnd, err = s.Range()
+			if err != nil {
+				return nil, fmt.Errorf("subnet %d: %w", s.SubnetID, err)
+			}
+		}
+
+		data := TemplateData{
+			Subnet:     s,
+			Network:    p.Addr().String(),
+			Prefix:     p.String(),
+			RangeBegin: device42.NormalizeAddress(s.RangeBegin),
+			RangeEnd:   device42.NormalizeAddress(s.RangeEnd),
+		}
+		if p.Addr().Is4() {
+			data.Netmask = net.IP(net.CIDRMask(p.Bits(), 32)).String()
+		}
+		if g, err := s.GatewayAddr(); err == nil {
+			data.Gateway = g.String()
+		}
+
+		if data.Gateway != "" && p.Addr().Is4() && opts.Options["routers"] == "" {
+			sub.Options = append(sub.Options, Option{Name: "routers", Data: data.Gateway})
+		}
+		for _, name := range optionNames {
+			b := bytes.Buffer{}
+			if err := templates[name].Execute(&b, data); err != nil {
+				return nil, fmt.Errorf("option %s for subnet %d: %w", name, s.SubnetID, err)
+			}
+			if b.Len() > 0 {
+				sub.Options = append(sub.Options, Option{Name: name, Data: b.String()})
+			}
+		}
+
+		for _, i := range hosts[s.SubnetID] {
+			a, err := i.Addr()
+			if err != nil {
+				return nil, fmt.Errorf("ip %d: %w", i.ID, err)
+			}
+			sub.Hosts = append(sub.Hosts, Host{
+				Name:       names.Name(hostName(i)),
+				MacAddress: strings.ToLower(i.MacAddress),
+				Address:    a,
+			})
+		}
+		sort.Slice(sub.Hosts, func(i, j int) bool { return sub.Hosts[i].Address.Less(sub.Hosts[j].Address) })
+
+		c.Subnets = append(c.Subnets, sub)
+	}
+
+	sort.SliceStable(c.Subnets, func(i, j int) bool {
+		return c.Subnets[i].Prefix.Addr().Less(c.Subnets[j].Prefix.Addr())
+	})
+
+	return &c, nil
+}
+
+// isContainer checks if a prefix holds any smaller prefix
+func isContainer(p netip.Prefix, prefixes []netip.Prefix) bool {
+	for _, i := range prefixes {
+		if i.Bits() > p.Bits() && ipmath.Contains(p, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostName will build a dhcp safe host name for an ip
+func hostName(ip device42.IP) string {
+	n := ip.Label
+	if n == "" {
+		n = ip.Device
+	}
+	n = strings.Trim(hostnameReplacer.ReplaceAllString(n, "-"), "-")
+	if n == "" {
+		n = "ip-" + strconv.Itoa(ip.ID)
+	}
+	return n
+}
+
+// Kea will render the configuration as kea Dhcp4 and Dhcp6 json
+func (c *Config) Kea() ([]byte, error) {
+	type option struct {
+		Name string `json:"name"`
+		Data string `json:"data"`
+	}
+	type pool struct {
+		Pool string `json:"pool"`
+	}
+	type reservation struct {
+		HWAddress   string   `json:"hw-address"`
+		IPAddress   string   `json:"ip-address,omitempty"`
+		IPAddresses []string `json:"ip-addresses,omitempty"`
+		Hostname    string   `json:"hostname"`
+	}
+	type subnet struct {
+		ID           int           `json:"id"`
+		Subnet       string        `json:"subnet"`
+		Comment      string        `json:"comment,omitempty"`
+		Pools        []pool        `json:"pools,omitempty"`
+		OptionData   []option      `json:"option-data,omitempty"`
+		Reservations []reservation `json:"reservations,omitempty"`
+	}
+
+	v4, v6 := []subnet{}, []subnet{}
+	for _, s := range c.Subnets {
+		k := subnet{
+			ID:      s.ID,
+			Subnet:  s.Prefix.String(),
+			Comment: s.Name,
+		}
+		if s.RangeBegin.IsValid() {
+			k.Pools = []pool{{Pool: s.RangeBegin.String() + " - " + s.RangeEnd.String()}}
+		}
+		for _, o := range s.Options {
+			k.OptionData = append(k.OptionData, option{Name: o.Name, Data: o.Data})
+		}
+		for _, h := range s.Hosts {
+			r := reservation{HWAddress: h.MacAddress, Hostname: h.Name}
+			if h.Address.Is6() {
+				r.IPAddresses = []string{h.Address.String()}
+			} else {
+				r.IPAddress = h.Address.String()
+			}
+			k.Reservations = append(k.Reservations, r)
+		}
+
+		if s.Prefix.Addr().Is6() {
+			v6 = append(v6, k)
+		} else {
+			v4 = append(v4, k)
+		}
+	}
+
+	cfg := map[string]interface{}{}
+	for name, subnets := range map[string][]subnet{"Dhcp4": v4, "Dhcp6": v6} {
+		if len(subnets) == 0 {
+			continue
+		}
+		d := map[string]interface{}{}
+		if name == "Dhcp4" {
+			d["subnet4"] = subnets
+		} else {
+			d["subnet6"] = subnets
+		}
+		if c.ValidLifetime > 0 {
+			d["valid-lifetime"] = c.ValidLifetime
+		}
+		if len(c.Interfaces) > 0 {
+			d["interfaces-config"] = map[string]interface{}{"interfaces": c.Interfaces}
+		}
+		cfg[name] = d
+	}
+
+	return json.MarshalIndent(cfg, "", "  ")
+}
+
+// ISC will render the configuration as isc dhcpd.conf subnet and host blocks.
+// dhcpd serves either ipv4 or ipv6, so the subnets must be of one family
+func (c *Config) ISC() (string, error) {
+	for _, s := range c.Subnets {
+		if s.Prefix.Addr().Is6() != c.Subnets[0].Prefix.Addr().Is6() {
+			return "", errors.New("isc dhcpd serves one address family at a time, build with a family of 4 or 6")
+		}
+	}
+
+	b := strings.Builder{}
+
+	if c.ValidLifetime > 0 {
+		b.WriteString(fmt.Sprintf("default-lease-time %d;\n\n", c.ValidLifetime))
+	}
+
+	for _, s := range c.Subnets {
+		v6 := s.Prefix.Addr().Is6()
+
+		if s.Name != "" {
+			b.WriteString("# " + s.Name + "\n")
+		}
+		if v6 {
+			b.WriteString("subnet6 " + s.Prefix.String() + " {\n")
+		} else {
+			mask := net.IP(net.CIDRMask(s.Prefix.Bits(), 32)).String()
+			b.WriteString("subnet " + s.Prefix.Addr().String() + " netmask " + mask + " {\n")
+		}
+		if s.RangeBegin.IsValid() {
+			if v6 {
+				b.WriteString("  range6 " + s.RangeBegin.String() + " " + s.RangeEnd.String() + ";\n")
+			} else {
+				b.WriteString("  range " + s.RangeBegin.String() + " " + s.RangeEnd.String() + ";\n")
+			}
+		}
+		for _, o := range s.Options {
+			b.WriteString("  option " + o.Name + " " + o.Data + ";\n")
+		}
+		b.WriteString("}\n\n")
+
+		for _, h := range s.Hosts {
+			b.WriteString("host " + h.Name + " {\n")
+			b.WriteString("  hardware ethernet " + h.MacAddress + ";\n")
+			if v6 {
+				b.WriteString("  fixed-address6 " + h.Address.String() + ";\n")
+			} else {
+				b.WriteString("  fixed-address " + h.Address.String() + ";\n")
+			}
+			b.WriteString("}\n\n")
+		}
+	}
+
+	return b.String(), nil
+}
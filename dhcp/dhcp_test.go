@@ -0,0 +1,253 @@
+package dhcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+var testSubnets = []device42.Subnet{
+	{SubnetID: 1, Name: "site", Network: "10.0.0.0", MaskBits: 16},
+	{SubnetID: 2, Name: "web", Network: "10.0.1.0", MaskBits: 24, ParentSubnetID: 1, Gateway: "10.0.1.1", RangeBegin: "10.0.1.100", RangeEnd: "10.0.1.200"},
+	{SubnetID: 3, Name: "db", Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 1},
+	{SubnetID: 4, Name: "v6", Network: "2001:db8::", MaskBits: 64, Gateway: "2001:db8::1"},
+}
+
+var testIPs = []device42.IP{
+	{ID: 1, Address: "10.0.1.20", Label: "web 2", MacAddress: "AA:BB:CC:00:00:02", SubnetID: 2},
+	{ID: 2, Address: "10.0.1.10", Label: "web", MacAddress: "aa:bb:cc:00:00:01", SubnetID: 2},
+	{ID: 3, Address: "10.0.1.30", Label: "web", MacAddress: "aa:bb:cc:00:00:03", SubnetID: 2},
+	{ID: 4, Address: "10.0.1.40", Label: "no mac", SubnetID: 2},
+	{ID: 5, Address: "2001:db8::10", Device: "_", MacAddress: "aa:bb:cc:00:00:05", SubnetID: 4},
+}
+
+func TestBuild(t *testing.T) {
+	type subnet struct {
+		prefix  string
+		options []Option
+		hosts   []string
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []subnet
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: []subnet{
+				{prefix: "10.0.0.0/24"},
+				{
+					prefix:  "10.0.1.0/24",
+					options: []Option{{Name: "routers", Data: "10.0.1.1"}},
+					hosts:   []string{"web 10.0.1.10", "web-2 10.0.1.20", "web-3 10.0.1.30"},
+				},
+				{prefix: "2001:db8::/64", hosts: []string{"ip-5 2001:db8::10"}},
+			},
+		},
+		{
+			name: "ipv4 only with options",
+			opts: Options{
+				Family: 4,
+				Options: map[string]string{
+					"subnet-mask":         "{{.Netmask}}",
+					"domain-name-servers": "{{.Gateway}}",
+				},
+			},
+			want: []subnet{
+				{prefix: "10.0.0.0/24", options: []Option{{Name: "subnet-mask", Data: "255.255.255.0"}}},
+				{
+					prefix: "10.0.1.0/24",
+					options: []Option{
+						{Name: "routers", Data: "10.0.1.1"},
+						{Name: "domain-name-servers", Data: "10.0.1.1"},
+						{Name: "subnet-mask", Data: "255.255.255.0"},
+					},
+					hosts: []string{"web 10.0.1.10", "web-2 10.0.1.20", "web-3 10.0.1.30"},
+				},
+			},
+		},
+		{
+			name: "routers option overrides the gateway",
+			opts: Options{Family: 4, Options: map[string]string{"routers": "{{.RangeBegin}}"}},
+			want: []subnet{
+				{prefix: "10.0.0.0/24"},
+				{
+					prefix:  "10.0.1.0/24",
+					options: []Option{{Name: "routers", Data: "10.0.1.100"}},
+					hosts:   []string{"web 10.0.1.10", "web-2 10.0.1.20", "web-3 10.0.1.30"},
+				},
+			},
+		},
+		{
+			name: "ipv6 only",
+			opts: Options{Family: 6},
+			want: []subnet{
+				{prefix: "2001:db8::/64", hosts: []string{"ip-5 2001:db8::10"}},
+			},
+		},
+		{name: "invalid family", opts: Options{Family: 5}, wantErr: true},
+		{name: "invalid template", opts: Options{Options: map[string]string{"x": "{{"}}, wantErr: true},
+		{name: "unknown template field", opts: Options{Options: map[string]string{"x": "{{.Bogus}}"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		c, err := Build(testSubnets, testIPs, tt.opts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Build error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		got := []subnet{}
+		for _, s := range c.Subnets {
+			g := subnet{prefix: s.Prefix.String(), options: s.Options}
+			for _, h := range s.Hosts {
+				g.hosts = append(g.hosts, h.Name+" "+h.Address.String())
+				if h.MacAddress != strings.ToLower(h.MacAddress) {
+					t.Errorf("%s: mac address %s is not lower case", tt.name, h.MacAddress)
+				}
+			}
+			got = append(got, g)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Build = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildInvalidSubnet(t *testing.T) {
+	_, err := Build([]device42.Subnet{{SubnetID: 1, Network: "bogus", MaskBits: 24}}, nil, Options{})
+	if err == nil {
+		t.Error("Build with an invalid subnet succeeded")
+	}
+}
+
+func TestKea(t *testing.T) {
+	c, err := Build(testSubnets, testIPs, Options{ValidLifetime: 3600, Interfaces: []string{"eth0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.Kea()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := map[string]struct {
+		ValidLifetime    int `json:"valid-lifetime"`
+		InterfacesConfig struct {
+			Interfaces []string `json:"interfaces"`
+		} `json:"interfaces-config"`
+		Subnet4 []struct {
+			ID           int    `json:"id"`
+			Subnet       string `json:"subnet"`
+			Pools        []struct{ Pool string }
+			Reservations []struct {
+				HWAddress   string   `json:"hw-address"`
+				IPAddress   string   `json:"ip-address"`
+				IPAddresses []string `json:"ip-addresses"`
+			}
+		} `json:"subnet4"`
+		Subnet6 []struct {
+			Subnet       string `json:"subnet"`
+			Reservations []struct {
+				IPAddress   string   `json:"ip-address"`
+				IPAddresses []string `json:"ip-addresses"`
+			}
+		} `json:"subnet6"`
+	}{}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	v4, v6 := cfg["Dhcp4"], cfg["Dhcp6"]
+	if v4.ValidLifetime != 3600 || !reflect.DeepEqual(v4.InterfacesConfig.Interfaces, []string{"eth0"}) {
+		t.Errorf("Dhcp4 settings = %+v", v4)
+	}
+	if len(v4.Subnet4) != 2 || v4.Subnet4[1].Subnet != "10.0.1.0/24" || v4.Subnet4[1].ID != 2 {
+		t.Fatalf("Dhcp4 subnets = %+v", v4.Subnet4)
+	}
+	if p := v4.Subnet4[1].Pools; len(p) != 1 || p[0].Pool != "10.0.1.100 - 10.0.1.200" {
+		t.Errorf("Dhcp4 pools = %+v", p)
+	}
+	if r := v4.Subnet4[1].Reservations; len(r) != 3 || r[0].IPAddress != "10.0.1.10" || r[0].IPAddresses != nil {
+		t.Errorf("Dhcp4 reservations = %+v", r)
+	}
+	if len(v6.Subnet6) != 1 || v6.Subnet6[0].Subnet != "2001:db8::/64" {
+		t.Fatalf("Dhcp6 subnets = %+v", v6.Subnet6)
+	}
+	if r := v6.Subnet6[0].Reservations; len(r) != 1 || r[0].IPAddress != "" || !reflect.DeepEqual(r[0].IPAddresses, []string{"2001:db8::10"}) {
+		t.Errorf("Dhcp6 reservations = %+v", r)
+	}
+}
+
+func TestISC(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		contains []string
+		wantErr  bool
+	}{
+		{
+			name: "ipv4",
+			opts: Options{Family: 4, ValidLifetime: 600},
+			contains: []string{
+				"default-lease-time 600;",
+				"# web\nsubnet 10.0.1.0 netmask 255.255.255.0 {\n  range 10.0.1.100 10.0.1.200;\n  option routers 10.0.1.1;\n}",
+				"host web {\n  hardware ethernet aa:bb:cc:00:00:01;\n  fixed-address 10.0.1.10;\n}",
+			},
+		},
+		{
+			name: "ipv6",
+			opts: Options{Family: 6},
+			contains: []string{
+				"subnet6 2001:db8::/64 {",
+				"host ip-5 {\n  hardware ethernet aa:bb:cc:00:00:05;\n  fixed-address6 2001:db8::10;\n}",
+			},
+		},
+		{name: "mixed families", opts: Options{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		c, err := Build(testSubnets, testIPs, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.ISC()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ISC error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(got, s) {
+				t.Errorf("%s: ISC output does not contain %q:\n%s", tt.name, s, got)
+			}
+		}
+	}
+}
+
+func TestHostName(t *testing.T) {
+	tests := []struct {
+		ip   device42.IP
+		want string
+	}{
+		{ip: device42.IP{ID: 1, Label: "web01"}, want: "web01"},
+		{ip: device42.IP{ID: 1, Label: "web 01.example.com"}, want: "web-01-example-com"},
+		{ip: device42.IP{ID: 1, Device: "db01"}, want: "db01"},
+		{ip: device42.IP{ID: 1, Label: "--web--"}, want: "web"},
+		{ip: device42.IP{ID: 7, Label: "!!!"}, want: "ip-7"},
+		{ip: device42.IP{ID: 8}, want: "ip-8"},
+	}
+
+	for _, tt := range tests {
+		if got := hostName(tt.ip); got != tt.want {
+			t.Errorf("hostName(%+v) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
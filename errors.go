@@ -1,5 +1,7 @@
 package device42
 
+import "errors"
+
 const (
 	// ErrorEmptyCredentials returns credential error
 	ErrorEmptyCredentials = "invalid credentials: username & password must be specified"
@@ -10,3 +12,6 @@ const (
 	// ErrorEmptyHost returns empty host error
 	ErrorEmptyHost = "invalid host: you must supply the device42 host"
 )
+
+// ErrSubnetNotFound is returned when a subnet lookup yields no subnets
+var ErrSubnetNotFound = errors.New("unable to find subnet")
@@ -0,0 +1,339 @@
+// Package exporter serves device42 ipam capacity and inventory as prometheus
+// metrics
+package exporter
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace       = "device42"
+	defaultInterval = 5 * time.Minute
+)
+
+var (
+	subnetLabels   = []string{"subnet_id", "name", "network", "vrf_group", "tags"}
+	vrfGroupLabels = []string{"vrf_group"}
+
+	subnetCapacity = prometheus.NewDesc(namespace+"_subnet_capacity_ips",
+		"Number of usable addresses in the subnet.", subnetLabels, nil)
+	subnetUsed = prometheus.NewDesc(namespace+"_subnet_used_ips",
+		"Number of used addresses in the subnet, including child subnets.", subnetLabels, nil)
+	subnetFree = prometheus.NewDesc(namespace+"_subnet_free_ips",
+		"Number of free addresses in the subnet.", subnetLabels, nil)
+	subnetUtilization = prometheus.NewDesc(namespace+"_subnet_utilization_ratio",
+		"Used addresses over the capacity of the subnet.", subnetLabels, nil)
+
+	vrfGroupCapacity = prometheus.NewDesc(namespace+"_vrf_group_capacity_ips",
+		"Number of usable addresses in the vrf group.", vrfGroupLabels, nil)
+	vrfGroupUsed = prometheus.NewDesc(namespace+"_vrf_group_used_ips",
+		"Number of used addresses in the vrf group.", vrfGroupLabels, nil)
+	vrfGroupFree = prometheus.NewDesc(namespace+"_vrf_group_free_ips",
+		"Number of free addresses in the vrf group.", vrfGroupLabels, nil)
+	vrfGroupSubnets = prometheus.NewDesc(namespace+"_vrf_group_subnets",
+		"Number of subnets in the vrf group.", vrfGroupLabels, nil)
+
+	ipsTotal = prometheus.NewDesc(namespace+"_ips",
+		"Number of ips.", vrfGroupLabels, nil)
+	vlansTotal = prometheus.NewDesc(namespace+"_vlans",
+		"Number of vlans.", nil, nil)
+	vrfGroupsTotal = prometheus.NewDesc(namespace+"_vrf_groups",
+		"Number of vrf groups.", nil, nil)
+	buildingInfo = prometheus.NewDesc(namespace+"_building_info",
+		"Device42 buildings.", []string{"building_id", "name", "address"}, nil)
+
+	collectSuccess = prometheus.NewDesc(namespace+"_exporter_last_collect_success",
+		"Whether the last collection succeeded.", nil, nil)
+	collectTimestamp = prometheus.NewDesc(namespace+"_exporter_last_collect_timestamp_seconds",
+		"Unix time of the last successful collection.", nil, nil)
+)
+
+// snapshot is the data collected from device42
+type snapshot struct {
+	report    *device42.UtilizationReport
+	ips       []device42.IP
+	vlans     int
+	vrfGroups []device42.VRFGroup
+	buildings []device42.Building
+	time      time.Time
+}
+
+// Exporter type
+// a prometheus.Collector serving cached device42 data
+type Exporter struct {
+	api      *device42.API
+	interval time.Duration
+
+	refresh     sync.Mutex
+	mu          sync.RWMutex
+	last        *snapshot
+	lastErr     error
+	lastTime    time.Time
+	lastAttempt time.Time
+
+	requestDuration *prometheus.HistogramVec
+	requestErrors   *prometheus.CounterVec
+	collectDuration prometheus.Histogram
+}
+
+// New creates a new exporter
+func New(api *device42.API) *Exporter {
+	return &Exporter{
+		api:      api,
+		interval: defaultInterval,
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "exporter",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of device42 api calls.",
+			Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"call"}),
+		requestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "exporter",
+			Name:      "request_errors_total",
+			Help:      "Number of failed device42 api calls.",
+		}, []string{"call"}),
+		collectDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "exporter",
+			Name:      "collect_duration_seconds",
+			Help:      "Duration of a full collection from device42.",
+			Buckets:   []float64{1, 5, 10, 30, 60, 120, 300},
+		}),
+	}
+}
+
+// Interval sets how often data is collected from device42. scrapes within
+// the interval are served from the cache
+func (e *Exporter) Interval(v time.Duration) *Exporter {
+	e.interval = v
+	return e
+}
+
+// Run will collect data every interval until the context is done
+func (e *Exporter) Run(ctx context.Context) {
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+
+	for {
+		if err := e.Refresh(); err != nil {
+			e.api.WriteToInfoLog("exporter collection failed : " + err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Refresh will collect data from device42 and replace the cache
+func (e *Exporter) Refresh() error {
+	e.refresh.Lock()
+	defer e.refresh.Unlock()
+
+	start := time.Now()
+	s, err := e.collect()
+	e.collectDuration.Observe(time.Since(start).Seconds())
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.lastErr = err
+	e.lastAttempt = start
+	if err == nil {
+		e.last = s
+		e.lastTime = s.time
+	}
+	return err
+}
+
+// refreshIfStale will refresh the cache when the last attempt is older than
+// the interval, waiting on any refresh already running
+func (e *Exporter) refreshIfStale() {
+	e.refresh.Lock()
+	e.mu.RLock()
+	stale := time.Since(e.lastAttempt) > e.interval
+	e.mu.RUnlock()
+	e.refresh.Unlock()
+
+	if stale {
+		_ = e.Refresh()
+	}
+}
+
+func (e *Exporter) collect() (*snapshot, error) {
+	s := snapshot{time: time.Now()}
+
+	var subnets *[]device42.Subnet
+	err := e.timed("GetSubnets", func() (err error) {
+		subnets, err = e.api.GetSubnets()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ips *[]device42.IP
+	err = e.timed("GetIPs", func() (err error) {
+		ips, err = e.api.GetIPs()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.ips = *ips
+
+	err = e.timed("GetVLANs", func() error {
+		vlans, err := e.api.GetVLANs()
+		if err == nil {
+			s.vlans = len(*vlans)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.timed("GetVRFGroups", func() error {
+		vrfGroups, err := e.api.GetVRFGroups()
+		if err == nil {
+			s.vrfGroups = *vrfGroups
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.timed("GetBuildings", func() error {
+		buildings, err := e.api.GetBuildings()
+		if err == nil {
+			s.buildings = *buildings
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.report = device42.CalculateUtilization(*subnets, s.ips)
+
+	return &s, nil
+}
+
+// timed will record the duration and failure of an api call
+func (e *Exporter) timed(call string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	e.requestDuration.WithLabelValues(call).Observe(time.Since(start).Seconds())
+	if err != nil {
+		e.requestErrors.WithLabelValues(call).Inc()
+	}
+	return err
+}
+
+// Describe implements prometheus.Collector
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		subnetCapacity, subnetUsed, subnetFree, subnetUtilization,
+		vrfGroupCapacity, vrfGroupUsed, vrfGroupFree, vrfGroupSubnets,
+		ipsTotal, vlansTotal, vrfGroupsTotal, buildingInfo,
+		collectSuccess, collectTimestamp,
+	} {
+		ch <- d
+	}
+	e.requestDuration.Describe(ch)
+	e.requestErrors.Describe(ch)
+	e.collectDuration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector. the cache is refreshed first when
+// the last attempt is older than the interval
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.refreshIfStale()
+
+	e.mu.RLock()
+	s, lastErr, lastTime := e.last, e.lastErr, e.lastTime
+	e.mu.RUnlock()
+
+	success := 1.0
+	if lastErr != nil {
+		success = 0
+	}
+	ch <- prometheus.MustNewConstMetric(collectSuccess, prometheus.GaugeValue, success)
+	if !lastTime.IsZero() {
+		ch <- prometheus.MustNewConstMetric(collectTimestamp, prometheus.GaugeValue, float64(lastTime.Unix()))
+	}
+
+	e.requestDuration.Collect(ch)
+	e.requestErrors.Collect(ch)
+	e.collectDuration.Collect(ch)
+
+	if s == nil {
+		return
+	}
+
+	for _, u := range s.report.Subnets {
+		l := []string{
+			strconv.Itoa(u.SubnetID),
+			u.Name,
+			u.Network + "/" + strconv.Itoa(u.MaskBits),
+			u.VrfGroupName,
+			strings.Join(u.Tags, ","),
+		}
+		ch <- prometheus.MustNewConstMetric(subnetCapacity, prometheus.GaugeValue, bigFloat(u.Capacity), l...)
+		ch <- prometheus.MustNewConstMetric(subnetUsed, prometheus.GaugeValue, float64(u.TotalUsed), l...)
+		ch <- prometheus.MustNewConstMetric(subnetFree, prometheus.GaugeValue, bigFloat(u.Free), l...)
+		ch <- prometheus.MustNewConstMetric(subnetUtilization, prometheus.GaugeValue, u.Percent/100, l...)
+	}
+
+	for _, g := range s.report.VRFGroups {
+		ch <- prometheus.MustNewConstMetric(vrfGroupCapacity, prometheus.GaugeValue, bigFloat(g.Capacity), g.VrfGroupName)
+		ch <- prometheus.MustNewConstMetric(vrfGroupUsed, prometheus.GaugeValue, float64(g.Used), g.VrfGroupName)
+		ch <- prometheus.MustNewConstMetric(vrfGroupFree, prometheus.GaugeValue, bigFloat(g.Free), g.VrfGroupName)
+		ch <- prometheus.MustNewConstMetric(vrfGroupSubnets, prometheus.GaugeValue, float64(g.Subnets), g.VrfGroupName)
+	}
+
+	ips := map[string]int{}
+	for _, i := range s.ips {
+		ips[i.VRFGroup]++
+	}
+	groups := make([]string, 0, len(ips))
+	for g := range ips {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		ch <- prometheus.MustNewConstMetric(ipsTotal, prometheus.GaugeValue, float64(ips[g]), g)
+	}
+
+	ch <- prometheus.MustNewConstMetric(vlansTotal, prometheus.GaugeValue, float64(s.vlans))
+	ch <- prometheus.MustNewConstMetric(vrfGroupsTotal, prometheus.GaugeValue, float64(len(s.vrfGroups)))
+
+	for _, b := range s.buildings {
+		ch <- prometheus.MustNewConstMetric(buildingInfo, prometheus.GaugeValue, 1,
+			strconv.Itoa(b.BuildingID), b.Name, b.Address)
+	}
+}
+
+// bigFloat converts a big.Int, which may be larger than an int64, to a float64
+func bigFloat(i *big.Int) float64 {
+	if i == nil {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f
+}
@@ -0,0 +1,173 @@
+package exporter
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBigFloat(t *testing.T) {
+	tests := []struct {
+		in   *big.Int
+		want float64
+	}{
+		{in: nil, want: 0},
+		{in: big.NewInt(0), want: 0},
+		{in: big.NewInt(254), want: 254},
+		{in: new(big.Int).Lsh(big.NewInt(1), 64), want: math.Pow(2, 64)},
+		{in: new(big.Int).Lsh(big.NewInt(1), 128), want: math.Pow(2, 128)},
+	}
+
+	for _, tt := range tests {
+		if got := bigFloat(tt.in); got != tt.want {
+			t.Errorf("bigFloat(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// stubServer answers the device42 calls made by a collection
+type stubServer struct {
+	mu       sync.Mutex
+	vlans    int
+	down     bool
+	requests int
+}
+
+func (s *stubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var v interface{}
+	switch strings.TrimPrefix(r.URL.Path, "/api/1.0") {
+	case "/subnets/":
+		v = device42.Subnets{List: []device42.Subnet{
+			{SubnetID: 1, Name: "web", Network: "10.0.0.0", MaskBits: 24, VrfGroupName: "prod"},
+		}}
+	case "/ips/":
+		v = device42.IPs{List: []device42.IP{
+			{ID: 1, Address: "10.0.0.1", SubnetID: 1, VRFGroup: "prod"},
+			{ID: 2, Address: "10.0.0.2", SubnetID: 1, VRFGroup: "prod"},
+		}}
+	case "/vlans/":
+		v = device42.VLANs{List: make([]device42.VLAN, s.vlans)}
+	case "/vrfgroup/":
+		v = device42.VRFGroups{List: []device42.VRFGroup{{ID: 1, Name: "prod"}}}
+	case "/buildings/":
+		v = device42.Buildings{List: []device42.Building{{BuildingID: 3, Name: "hq", Address: "1 main st"}}}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *stubServer) set(vlans int, down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vlans, s.down = vlans, down
+}
+
+func (s *stubServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func TestExporterCollect(t *testing.T) {
+	stub := &stubServer{vlans: 2}
+	s := httptest.NewTLSServer(stub)
+	defer s.Close()
+
+	api, err := device42.NewAPIBasicAuth("u", "p", strings.TrimPrefix(s.URL, "https://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.IgnoreSSLErrors()
+	api.InfoLogger(log.New(io.Discard, "", 0))
+
+	e := New(api).Interval(time.Hour)
+
+	// a pedantic registry fails a gather when collect and describe disagree
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(e); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{
+		"device42_subnet_used_ips",
+		"device42_ips",
+		"device42_vlans",
+		"device42_vrf_groups",
+		"device42_building_info",
+		"device42_exporter_last_collect_success",
+	}
+	metrics := func(vlans string, success string) string {
+		return `
+# HELP device42_building_info Device42 buildings.
+# TYPE device42_building_info gauge
+device42_building_info{address="1 main st",building_id="3",name="hq"} 1
+# HELP device42_exporter_last_collect_success Whether the last collection succeeded.
+# TYPE device42_exporter_last_collect_success gauge
+device42_exporter_last_collect_success ` + success + `
+# HELP device42_ips Number of ips.
+# TYPE device42_ips gauge
+device42_ips{vrf_group="prod"} 2
+# HELP device42_subnet_used_ips Number of used addresses in the subnet, including child subnets.
+# TYPE device42_subnet_used_ips gauge
+device42_subnet_used_ips{name="web",network="10.0.0.0/24",subnet_id="1",tags="",vrf_group="prod"} 2
+# HELP device42_vlans Number of vlans.
+# TYPE device42_vlans gauge
+device42_vlans ` + vlans + `
+# HELP device42_vrf_groups Number of vrf groups.
+# TYPE device42_vrf_groups gauge
+device42_vrf_groups 1
+`
+	}
+
+	tests := []struct {
+		name     string
+		vlans    int
+		down     bool
+		interval time.Duration
+		want     string
+		fetched  bool
+	}{
+		{name: "first scrape collects", vlans: 2, interval: time.Hour, want: metrics("2", "1"), fetched: true},
+		{name: "scrape within the interval is cached", vlans: 3, interval: time.Hour, want: metrics("2", "1")},
+		{name: "expired cache is collected again", vlans: 3, interval: time.Nanosecond, want: metrics("3", "1"), fetched: true},
+		{name: "failed collection serves the last data", vlans: 4, down: true, interval: time.Nanosecond, want: metrics("3", "0"), fetched: true},
+	}
+
+	for _, tt := range tests {
+		stub.set(tt.vlans, tt.down)
+		e.Interval(tt.interval)
+		before := stub.count()
+
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.want), names...); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if fetched := stub.count() > before; fetched != tt.fetched {
+			t.Errorf("%s: fetched = %v, want %v", tt.name, fetched, tt.fetched)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package device42
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryObjectTypes maps object types to device42's history content types
+var HistoryObjectTypes = map[string]string{
+	"ip":       "ip address",
+	"subnet":   "subnet",
+	"vlan":     "vlan",
+	"vrfgroup": "vrf group",
+	"building": "building",
+	"device":   "device",
+}
+
+// historyTimeLayouts are the timestamp layouts device42 uses in history entries
+var historyTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999",
+	"2006-01-02 15:04:05.999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// HistoryChange type
+// a single field changed by a history entry
+type HistoryChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// HistoryEntry type
+type HistoryEntry struct {
+	ID          int             `json:"id"`
+	Action      string          `json:"action"`
+	ActionTime  string          `json:"action_time"`
+	ContentType string          `json:"content_type"`
+	ObjectID    int             `json:"object_id"`
+	ObjectRepr  string          `json:"object_repr"`
+	User        string          `json:"user"`
+	Changes     []HistoryChange `json:"changes"`
+}
+
+// HistoryFilter type
+// empty fields are not filtered on
+type HistoryFilter struct {
+	// ObjectType is a key of HistoryObjectTypes or a device42 content type
+	ObjectType string
+	ObjectID   int
+	User       string
+	Since      time.Time
+}
+
+// Time will parse the entry's action time
+func (h *HistoryEntry) Time() (time.Time, error) {
+	for _, l := range historyTimeLayouts {
+		if t, err := time.Parse(l, h.ActionTime); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unable to parse history time " + h.ActionTime)
+}
+
+// UnmarshalJSON will decode a history entry. device42 has returned changes as
+// a list of changes, a map of field to old and new values and as free text
+// depending on the version
+func (h *HistoryEntry) UnmarshalJSON(b []byte) error {
+	type entry HistoryEntry
+	raw := struct {
+		entry
+		Changes       json.RawMessage `json:"changes"`
+		ChangeMessage string          `json:"change_message"`
+		ObjectID      json.RawMessage `json:"object_id"`
+	}{}
+
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	*h = HistoryEntry(raw.entry)
+	h.ObjectID = historyObjectID(raw.ObjectID)
+	h.Changes = parseHistoryChanges(raw.Changes)
+	if h.Changes == nil && raw.ChangeMessage != "" {
+		h.Changes = parseHistoryChanges(json.RawMessage(strconv.Quote(raw.ChangeMessage)))
+	}
+
+	return nil
+}
+
+// Summary will describe the changes of an entry on a single line
+func (h *HistoryEntry) Summary() string {
+	s := []string{}
+	for _, c := range h.Changes {
+		switch {
+		case c.Old == "" && c.New == "":
+			s = append(s, c.Field)
+		case c.Field == "":
+			s = append(s, c.New)
+		default:
+			s = append(s, c.Field+": "+c.Old+" -> "+c.New)
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+// GetHistory will return history entries matching a filter, oldest first
+func (api *API) GetHistory(f HistoryFilter) (*[]HistoryEntry, error) {
+	contentType := f.ObjectType
+	if t, ok := HistoryObjectTypes[strings.ToLower(f.ObjectType)]; ok {
+		contentType = t
+	}
+
+	q := url.Values{}
+	if contentType != "" {
+		q.Set("content_type", contentType)
+	}
+	if f.ObjectID != 0 {
+		q.Set("object_id", strconv.Itoa(f.ObjectID))
+	}
+	if f.User != "" {
+		q.Set("user", f.User)
+	}
+
+	path := "/history/"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	b, err := api.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := decodeHistory(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if api.IsLoggingDebug() {
+		api.WriteToDebugLog(fmt.Sprintf("history entries : %d", len(entries)))
+	}
+
+	// older appliances ignore some of the query parameters
+	history := []HistoryEntry{}
+	for _, e := range entries {
+		if contentType != "" && !strings.EqualFold(e.ContentType, contentType) {
+			continue
+		}
+		if f.ObjectID != 0 && e.ObjectID != f.ObjectID {
+			continue
+		}
+		if f.User != "" && !strings.EqualFold(e.User, f.User) {
+			continue
+		}
+		if !f.Since.IsZero() {
+			t, err := e.Time()
+			if err != nil || t.Before(f.Since) {
+				continue
+			}
+		}
+		history = append(history, e)
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		a, _ := history[i].Time()
+		b, _ := history[j].Time()
+		return a.Before(b)
+	})
+
+	return &history, nil
+}
+
+// GetObjectHistory will return the history of a single object
+func (api *API) GetObjectHistory(objectType string, id int) (*[]HistoryEntry, error) {
+	return api.GetHistory(HistoryFilter{ObjectType: objectType, ObjectID: id})
+}
+
+// decodeHistory will decode a history response, which is either a list of
+// entries or an object wrapping them
+func decodeHistory(b []byte) ([]HistoryEntry, error) {
+	entries := []HistoryEntry{}
+	if err := json.Unmarshal(b, &entries); err == nil {
+		return entries, nil
+	}
+
+	wrapped := struct {
+		History []HistoryEntry `json:"history"`
+		Logs    []HistoryEntry `json:"audit_logs"`
+	}{}
+	err := json.Unmarshal(b, &wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(wrapped.History, wrapped.Logs...), nil
+}
+
+// historyObjectID will decode an object id sent as a number or a string
+func historyObjectID(b json.RawMessage) int {
+	var n int
+	if json.Unmarshal(b, &n) == nil {
+		return n
+	}
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		n, _ = strconv.Atoi(s)
+	}
+	return n
+}
+
+// parseHistoryChanges will decode the changes of a history entry
+func parseHistoryChanges(b json.RawMessage) []HistoryChange {
+	if len(b) == 0 || string(b) == "null" {
+		return nil
+	}
+
+	list := []HistoryChange{}
+	if json.Unmarshal(b, &list) == nil {
+		return list
+	}
+
+	// {"field": ["old", "new"]} or {"field": {"old": ..., "new": ...}}
+	m := map[string]json.RawMessage{}
+	if json.Unmarshal(b, &m) == nil {
+		fields := make([]string, 0, len(m))
+		for k := range m {
+			fields = append(fields, k)
+		}
+		sort.Strings(fields)
+
+		for _, k := range fields {
+			c := HistoryChange{Field: k}
+			pair := []interface{}{}
+			diff := map[string]interface{}{}
+			if json.Unmarshal(m[k], &pair) == nil && len(pair) == 2 {
+				c.Old, c.New = historyValue(pair[0]), historyValue(pair[1])
+			} else if json.Unmarshal(m[k], &diff) == nil {
+				c.Old, c.New = historyValue(diff["old"]), historyValue(diff["new"])
+			} else {
+				var v interface{}
+				_ = json.Unmarshal(m[k], &v)
+				c.New = historyValue(v)
+			}
+			list = append(list, c)
+		}
+		return list
+	}
+
+	// free text, usually "changed name from 'a' to 'b'." or "changed name, notes."
+	var s string
+	if json.Unmarshal(b, &s) != nil || strings.TrimSpace(s) == "" {
+		return nil
+	}
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			list = append(list, HistoryChange{New: l})
+		}
+	}
+	return list
+}
+
+// historyValue will format a decoded json value
+func historyValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
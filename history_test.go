@@ -0,0 +1,140 @@
+package device42
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []int
+		wantErr bool
+	}{
+		{name: "list", body: `[{"id": 1}, {"id": 2}]`, want: []int{1, 2}},
+		{name: "empty list", body: `[]`, want: []int{}},
+		{name: "history", body: `{"history": [{"id": 3}]}`, want: []int{3}},
+		{name: "audit logs", body: `{"audit_logs": [{"id": 4}, {"id": 5}]}`, want: []int{4, 5}},
+		{name: "invalid", body: `nope`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		entries, err := decodeHistory([]byte(tt.body))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: decodeHistory error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		got := []int{}
+		for _, e := range entries {
+			got = append(got, e.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: decodeHistory = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryEntryUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		objectID int
+		changes  []HistoryChange
+		summary  string
+	}{
+		{
+			name:     "list of changes",
+			body:     `{"object_id": 7, "changes": [{"field": "name", "old": "a", "new": "b"}]}`,
+			objectID: 7,
+			changes:  []HistoryChange{{Field: "name", Old: "a", New: "b"}},
+			summary:  "name: a -> b",
+		},
+		{
+			name:     "map of pairs",
+			body:     `{"object_id": "8", "changes": {"notes": ["", "x"], "mask_bits": [24, 25]}}`,
+			objectID: 8,
+			changes:  []HistoryChange{{Field: "mask_bits", Old: "24", New: "25"}, {Field: "notes", Old: "", New: "x"}},
+			summary:  "mask_bits: 24 -> 25, notes:  -> x",
+		},
+		{
+			name:    "map of old and new",
+			body:    `{"changes": {"label": {"old": "web", "new": null}}}`,
+			changes: []HistoryChange{{Field: "label", Old: "web", New: ""}},
+			summary: "label: web -> ",
+		},
+		{
+			name:    "map of values",
+			body:    `{"changes": {"tags": "a,b"}}`,
+			changes: []HistoryChange{{Field: "tags", New: "a,b"}},
+			summary: "tags:  -> a,b",
+		},
+		{
+			name:    "free text",
+			body:    `{"changes": "changed name.\nchanged notes."}`,
+			changes: []HistoryChange{{New: "changed name."}, {New: "changed notes."}},
+			summary: "changed name., changed notes.",
+		},
+		{
+			name:    "change message",
+			body:    `{"change_message": "added."}`,
+			changes: []HistoryChange{{New: "added."}},
+			summary: "added.",
+		},
+		{
+			name:     "no changes",
+			body:     `{"object_id": "bogus", "changes": null}`,
+			objectID: 0,
+			changes:  nil,
+			summary:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		h := HistoryEntry{}
+		if err := h.UnmarshalJSON([]byte(tt.body)); err != nil {
+			t.Errorf("%s: UnmarshalJSON error = %v", tt.name, err)
+			continue
+		}
+		if h.ObjectID != tt.objectID {
+			t.Errorf("%s: object id = %d, want %d", tt.name, h.ObjectID, tt.objectID)
+		}
+		if !reflect.DeepEqual(h.Changes, tt.changes) {
+			t.Errorf("%s: changes = %+v, want %+v", tt.name, h.Changes, tt.changes)
+		}
+		if s := h.Summary(); s != tt.summary {
+			t.Errorf("%s: Summary = %q, want %q", tt.name, s, tt.summary)
+		}
+	}
+}
+
+func TestHistoryEntryTime(t *testing.T) {
+	want := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{in: "2021-03-04T05:06:07Z"},
+		{in: "2021-03-04T05:06:07"},
+		{in: "2021-03-04 05:06:07"},
+		{in: "2021-03-04 05:06:07.000000"},
+		{in: "04/03/2021", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		h := HistoryEntry{ActionTime: tt.in}
+		got, err := h.Time()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Time(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !got.Equal(want) {
+			t.Errorf("Time(%q) = %s, want %s", tt.in, got, want)
+		}
+	}
+}
@@ -4,11 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 
 	device42 "github.com/chopnico/device42-go"
 
-	"github.com/chopnico/output"
 	"github.com/urfave/cli/v2"
 )
 
@@ -26,7 +24,7 @@ func buildingCommands(app *cli.App) *cli.Command {
 }
 
 func buildingList(app *cli.App) *cli.Command {
-	flags := addQuietFlag(addDisplayFlags(nil))
+	flags := addQuietFlag(addListFlags(addDisplayFlags(nil)))
 
 	return &cli.Command{
 		Name:  "list",
@@ -39,34 +37,25 @@ func buildingList(app *cli.App) *cli.Command {
 				return err
 			}
 
+			if err := selectItems(c, buildings); err != nil {
+				return err
+			}
+
 			if c.Bool("quiet") {
 				for _, i := range *buildings {
 					fmt.Println(i.BuildingID)
 				}
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Print(output.FormatItemsAsJson(buildings))
-				case "list":
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemsAsList(buildings, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemsAsList(buildings, p))
-					}
-				default:
-					data := [][]string{}
-					for _, i := range *buildings {
-						data = append(data,
-							[]string{strconv.Itoa(i.BuildingID), i.Name, i.Address},
-						)
-					}
-					headers := []string{"ID", "Name", "Address"}
-					fmt.Print(output.FormatTable(data, headers))
-				}
+				return nil
 			}
 
-			return nil
+			data := [][]string{}
+			for _, i := range *buildings {
+				data = append(data,
+					[]string{strconv.Itoa(i.BuildingID), i.Name, i.Address},
+				)
+			}
+			headers := []string{"ID", "Name", "Address"}
+			return printTable(c, buildings, headers, data)
 		},
 	}
 }
@@ -114,33 +103,20 @@ func buildingGet(app *cli.App) *cli.Command {
 
 			if c.Bool("quiet") {
 				fmt.Println(building.BuildingID)
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Print(output.FormatItemAsJson(building))
-				case "list":
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemAsList(building, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemAsList(building, p))
-					}
-				default:
-					data := [][]string{
-						[]string{strconv.Itoa(building.BuildingID), building.Name, building.Address},
-					}
-					headers := []string{"ID", "Name", "Address"}
-					fmt.Print(output.FormatTable(data, headers))
-				}
+				return nil
 			}
 
-			return nil
+			data := [][]string{
+				[]string{strconv.Itoa(building.BuildingID), building.Name, building.Address},
+			}
+			headers := []string{"ID", "Name", "Address"}
+			return printTable(c, building, headers, data)
 		},
 	}
 }
 
 func buildingSet(app *cli.App) *cli.Command {
-	flags := []cli.Flag{
+	flags := addDisplayFlags([]cli.Flag{
 		&cli.StringFlag{
 			Name:     "name",
 			Usage:    "the `NAME` of the building",
@@ -161,7 +137,7 @@ func buildingSet(app *cli.App) *cli.Command {
 			Usage:    "some `NOTES` about the building",
 			Required: false,
 		},
-	}
+	})
 
 	return &cli.Command{
 		Name:  "set",
@@ -182,18 +158,7 @@ func buildingSet(app *cli.App) *cli.Command {
 				return err
 			}
 
-			switch c.String("format") {
-			case "json":
-				fmt.Print(output.FormatItemAsJson(b))
-			default:
-				if c.String("properties") == "" {
-					fmt.Print(output.FormatItemAsList(b, nil))
-				} else {
-					p := strings.Split(c.String("properties"), ",")
-					fmt.Print(output.FormatItemAsList(b, p))
-				}
-			}
-			return nil
+			return printItem(c, b, nil)
 		},
 	}
 }
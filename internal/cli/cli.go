@@ -9,6 +9,19 @@ func NewCommands(app *cli.App) {
 	app.Commands = append(app.Commands,
 		ipamCommands(app),
 		buildingCommands(app),
+		exportCommands(app),
+		importCommands(app),
+		journalCommands(app),
+		undoCommand(app),
+		historyCommand(app),
+		watchCommands(app),
+		webhookCommands(app),
+		exporterCommand(app),
+		inventoryCommand(app),
+		configCommands(app),
+		loginCommand(app),
+		logoutCommand(app),
+		tuiCommand(app),
 	)
 }
 
@@ -24,11 +37,44 @@ func addQuietFlag(flags []cli.Flag) []cli.Flag {
 	return flags
 }
 
+func addListFlags(flags []cli.Flag) []cli.Flag {
+	flags = append(flags,
+		&cli.StringSliceFlag{
+			Name:     "filter",
+			Usage:    "only print items matching `FILTER`, e.g. 'Label~=web' (operators: = != ~= !~ < <= > >=), repeat to match all",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "sort",
+			Usage:    "comma separated `PROPERTIES` to sort by, addresses and networks in ip order. prefix with - to reverse",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "limit",
+			Usage:    "print at most `N` items",
+			Required: false,
+		},
+	)
+
+	return flags
+}
+
 func addDisplayFlags(flags []cli.Flag) []cli.Flag {
 	flags = append(flags,
 		&cli.StringFlag{
 			Name:     "properties",
-			Usage:    "`PROPERTIES` to print (only relevant to list format)",
+			Aliases:  []string{"columns"},
+			Usage:    "comma separated `PROPERTIES` to print, table headers or field names",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "template",
+			Usage:    "go `TEMPLATE` printed for every item, e.g. '{{.Address}} {{.Label}}'",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "no-headers",
+			Usage:    "do not print headers (table, csv and tsv formats)",
 			Required: false,
 		},
 	)
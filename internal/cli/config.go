@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/chopnico/device42-go/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+func configCommands(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "configuration profiles",
+		Subcommands: []*cli.Command{
+			configAdd(app),
+			configList(app),
+			configSwitch(app),
+		},
+	}
+}
+
+func configAdd(app *cli.App) *cli.Command {
+	// the global host, username, credentials, proxy and timeout flags would be
+	// filled in from the current profile, so the profile has its own
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "name",
+			Usage:    "profile `NAME`",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "host",
+			Usage:    "device42 appliance `HOST`",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "auth",
+			Usage:    "authentication `METHOD`",
+			Value:    config.AuthBasic,
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "username",
+			Usage:    "account `USERNAME`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "credentials",
+			Usage:    "`SOURCE` to look up the password in (keyring, helper, file)",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "credential-helper",
+			Usage:    "credential helper `EXECUTABLE` for the helper source",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "credentials-file",
+			Usage:    "credentials `FILE` for the file source",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "ignore-ssl",
+			Usage:    "ignore ssl errors",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "proxy",
+			Usage:    "http `PROXY`",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "timeout",
+			Usage:    "http timeout in `SECONDS`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "default-format",
+			Usage:    "default printing `FORMAT` (table, list, json, jsonl, yaml, csv, tsv)",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "switch",
+			Usage:    "make the profile the current profile",
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "add",
+		Usage: "add or replace a profile",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			name := c.String("name")
+
+			cfg, err := config.Load(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			err = cfg.SetProfile(name, &config.Profile{
+				Host:      c.String("host"),
+				Auth:      c.String("auth"),
+				Username:  c.String("username"),
+				IgnoreSSL: c.Bool("ignore-ssl"),
+				Proxy:     c.String("proxy"),
+				Timeout:   c.Int("timeout"),
+				Format:    c.String("default-format"),
+
+				Credentials:      c.String("credentials"),
+				CredentialHelper: c.String("credential-helper"),
+				CredentialsFile:  c.String("credentials-file"),
+			})
+			if err != nil {
+				return err
+			}
+			if c.Bool("switch") {
+				err = cfg.Switch(name)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = cfg.Save()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("successfully saved profile " + name + " to " + cfg.Path())
+			return nil
+		},
+	}
+}
+
+func configList(app *cli.App) *cli.Command {
+	flags := addQuietFlag(addDisplayFlags(nil))
+
+	return &cli.Command{
+		Name:  "list",
+		Usage: "list all profiles",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			cfg, err := config.Load(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			if c.Bool("quiet") {
+				for _, n := range cfg.Names() {
+					fmt.Println(n)
+				}
+				return nil
+			}
+
+			data := [][]string{}
+			for _, n := range cfg.Names() {
+				p := cfg.Profiles[n]
+				current := ""
+				if n == cfg.Current {
+					current = "*"
+				}
+				timeout := ""
+				if p.Timeout != 0 {
+					timeout = strconv.Itoa(p.Timeout)
+				}
+				data = append(data, []string{
+					current, n, p.Host, p.Auth, p.Username, strconv.FormatBool(p.IgnoreSSL), p.Proxy, timeout, p.Format,
+				})
+			}
+			headers := []string{"Current", "Name", "Host", "Auth", "Username", "Ignore SSL", "Proxy", "Timeout", "Format"}
+			return printTable(c, cfg, headers, data)
+		},
+	}
+}
+
+func configSwitch(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:      "switch",
+		Usage:     "change the current profile",
+		ArgsUsage: "NAME",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				_ = cli.ShowCommandHelp(c, "switch")
+				return errors.New("you must supply a profile name")
+			}
+
+			cfg, err := config.Load(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			err = cfg.Switch(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			err = cfg.Save()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("switched to profile " + c.Args().First())
+			return nil
+		},
+	}
+}
@@ -0,0 +1,282 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/dhcp"
+	"github.com/chopnico/device42-go/netbox"
+	"github.com/chopnico/device42-go/terraform"
+
+	"github.com/urfave/cli/v2"
+)
+
+func exportCommands(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "export device42 data to other systems",
+		Subcommands: []*cli.Command{
+			exportDHCP(app),
+			exportTerraform(app),
+			exportNetBox(app),
+		},
+	}
+}
+
+func exportDHCP(app *cli.App) *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "vrf-group",
+			Usage:    "`VRF-GROUP` name to export subnets from",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "server",
+			Usage:    "dhcp `SERVER` to write configuration for (kea, isc)",
+			Value:    "kea",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "family",
+			Usage:    "only export subnets of address `FAMILY` (4, 6), isc dhcpd needs one when both are present",
+			Required: false,
+		},
+		&cli.StringSliceFlag{
+			Name:     "option",
+			Usage:    "dhcp `OPTION` as name=template, e.g. domain-name-servers={{.Gateway}}",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "lease-time",
+			Usage:    "lease time in `SECONDS`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "interfaces",
+			Usage:    "`INTERFACES` kea should listen on",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "write the configuration to `FILE` instead of stdout",
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "dhcp",
+		Usage: "export subnets and ip reservations as dhcp server configuration",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			opts := dhcp.Options{
+				Options:       map[string]string{},
+				ValidLifetime: c.Int("lease-time"),
+				Family:        c.Int("family"),
+			}
+			for _, o := range c.StringSlice("option") {
+				kv := strings.SplitN(o, "=", 2)
+				if len(kv) != 2 || kv[0] == "" {
+					return errors.New("invalid option " + o + ", expected name=template")
+				}
+				opts.Options[kv[0]] = kv[1]
+			}
+			if c.String("interfaces") != "" {
+				opts.Interfaces = strings.Split(c.String("interfaces"), ",")
+			}
+
+			vrfGroup, err := api.GetVRFGroupByName(c.String("vrf-group"))
+			if err != nil {
+				return err
+			}
+			subnets, err := api.GetSubnetsByVRFGroupID(vrfGroup.ID)
+			if err != nil {
+				return err
+			}
+			ips, err := api.GetIPs()
+			if err != nil {
+				return err
+			}
+
+			config, err := dhcp.Build(*subnets, *ips, opts)
+			if err != nil {
+				return err
+			}
+
+			var out string
+			switch c.String("server") {
+			case "kea":
+				b, err := config.Kea()
+				if err != nil {
+					return err
+				}
+				out = string(b) + "\n"
+			case "isc":
+				out, err = config.ISC()
+				if err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown dhcp server %s", c.String("server"))
+			}
+
+			if c.String("output") != "" {
+				return os.WriteFile(c.String("output"), []byte(out), 0644)
+			}
+			fmt.Print(out)
+
+			return nil
+		},
+	}
+}
+
+func exportTerraform(app *cli.App) *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "vrf-group",
+			Usage:    "only export subnets from `VRF-GROUP`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "write the configuration to `FILE` instead of stdout, e.g. device42.tf.json",
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "terraform",
+		Usage: "export buildings, vrf groups, vlans and subnets as terraform json with import blocks",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			buildings, err := api.GetBuildings()
+			if err != nil {
+				return err
+			}
+			vrfGroups, err := api.GetVRFGroups()
+			if err != nil {
+				return err
+			}
+			vlans, err := api.GetVLANs()
+			if err != nil {
+				return err
+			}
+
+			var subnets *[]device42.Subnet
+			if c.String("vrf-group") != "" {
+				vrfGroup, err := api.GetVRFGroupByName(c.String("vrf-group"))
+				if err != nil {
+					return err
+				}
+				subnets, err = api.GetSubnetsByVRFGroupID(vrfGroup.ID)
+				if err != nil {
+					return err
+				}
+			} else {
+				subnets, err = api.GetSubnets()
+				if err != nil {
+					return err
+				}
+			}
+
+			b, err := terraform.Build(*buildings, *vrfGroups, *vlans, *subnets).JSON()
+			if err != nil {
+				return err
+			}
+			b = append(b, '\n')
+
+			if c.String("output") != "" {
+				return os.WriteFile(c.String("output"), b, 0644)
+			}
+			fmt.Print(string(b))
+
+			return nil
+		},
+	}
+}
+
+func exportNetBox(app *cli.App) *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "vrf-group",
+			Usage:    "only export subnets and ips from `VRF-GROUP`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "write the export to `FILE` instead of stdout",
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "netbox",
+		Usage: "export buildings, vrf groups, vlans, subnets and ips as netbox json",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			buildings, err := api.GetBuildings()
+			if err != nil {
+				return err
+			}
+			vrfGroups, err := api.GetVRFGroups()
+			if err != nil {
+				return err
+			}
+			vlans, err := api.GetVLANs()
+			if err != nil {
+				return err
+			}
+			ips, err := api.GetIPs()
+			if err != nil {
+				return err
+			}
+
+			var subnets *[]device42.Subnet
+			if c.String("vrf-group") != "" {
+				vrfGroup, err := api.GetVRFGroupByName(c.String("vrf-group"))
+				if err != nil {
+					return err
+				}
+				subnets, err = api.GetSubnetsByVRFGroupID(vrfGroup.ID)
+				if err != nil {
+					return err
+				}
+
+				filtered := []device42.IP{}
+				for _, i := range *ips {
+					if i.VRFGroupID == vrfGroup.ID || i.VRFGroup == vrfGroup.Name {
+						filtered = append(filtered, i)
+					}
+				}
+				ips = &filtered
+			} else {
+				subnets, err = api.GetSubnets()
+				if err != nil {
+					return err
+				}
+			}
+
+			b, err := json.MarshalIndent(netbox.Export(*buildings, *vrfGroups, *vlans, *subnets, *ips), "", "  ")
+			if err != nil {
+				return err
+			}
+			b = append(b, '\n')
+
+			if c.String("output") != "" {
+				return os.WriteFile(c.String("output"), b, 0644)
+			}
+			fmt.Print(string(b))
+
+			return nil
+		},
+	}
+}
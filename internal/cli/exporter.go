@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/exporter"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/urfave/cli/v2"
+)
+
+func exporterCommand(app *cli.App) *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "listen",
+			Usage:    "`ADDRESS` to listen on",
+			Value:    ":9442",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "path",
+			Usage:    "url `PATH` metrics are served on",
+			Value:    "/metrics",
+			Required: false,
+		},
+		&cli.DurationFlag{
+			Name:     "interval",
+			Usage:    "`INTERVAL` between collections from device42",
+			Value:    5 * time.Minute,
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "exporter",
+		Usage: "serve ipam capacity and inventory as prometheus metrics",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			e := exporter.New(api).Interval(c.Duration("interval"))
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(
+				e,
+				collectors.NewGoCollector(),
+				collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			)
+
+			mux := http.NewServeMux()
+			mux.Handle(c.String("path"), promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+			srv := &http.Server{
+				Addr:              c.String("listen"),
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+			go e.Run(ctx)
+			go func() {
+				<-ctx.Done()
+				shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdown)
+			}()
+
+			fmt.Fprintln(os.Stderr, "serving metrics on "+srv.Addr+c.String("path"))
+
+			err := srv.ListenAndServe()
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"errors"
+	"math"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/urfave/cli/v2"
+)
+
+// filter operators, longest first so "!=" is not read as "!" and "="
+var filterOperators = []string{"!=", "~=", "!~", "<=", ">=", "=", "<", ">"}
+
+// filter type
+// a --filter, a property compared to a value
+type filter struct {
+	property string
+	operator string
+	value    string
+	re       *regexp.Regexp
+}
+
+// parseFilter will parse PROPERTY OPERATOR VALUE, e.g. Label~=web
+func parseFilter(s string) (filter, error) {
+	i := strings.IndexAny(s, "=!~<>")
+	if i <= 0 {
+		return filter{}, errors.New("invalid filter " + s + ", expected PROPERTY=VALUE (operators: " + strings.Join(filterOperators, " ") + ")")
+	}
+
+	f := filter{property: strings.TrimSpace(s[:i])}
+	for _, o := range filterOperators {
+		if strings.HasPrefix(s[i:], o) {
+			f.operator = o
+			f.value = s[i+len(o):]
+			break
+		}
+	}
+	if f.operator == "" {
+		return filter{}, errors.New("invalid filter " + s + ", unknown operator (" + strings.Join(filterOperators, " ") + ")")
+	}
+
+	if f.operator == "~=" || f.operator == "!~" {
+		re, err := regexp.Compile(f.value)
+		if err != nil {
+			return filter{}, errors.New("invalid filter " + s + ": " + err.Error())
+		}
+		f.re = re
+	}
+
+	return f, nil
+}
+
+// match checks if a printed value passes the filter
+func (f filter) match(v string) bool {
+	switch f.operator {
+	case "~=":
+		return f.re.MatchString(v)
+	case "!~":
+		return !f.re.MatchString(v)
+	case "!=":
+		return compareValues(v, f.value) != 0
+	case "<":
+		return compareValues(v, f.value) < 0
+	case "<=":
+		return compareValues(v, f.value) <= 0
+	case ">":
+		return compareValues(v, f.value) > 0
+	case ">=":
+		return compareValues(v, f.value) >= 0
+	default:
+		return compareValues(v, f.value) == 0
+	}
+}
+
+// sortKey type
+// a --sort property
+type sortKey struct {
+	property string
+	reverse  bool
+}
+
+func parseSortKeys(s string) []sortKey {
+	keys := []sortKey{}
+	for _, p := range splitProperties(s) {
+		if strings.HasPrefix(p, "-") {
+			keys = append(keys, sortKey{property: p[1:], reverse: true})
+		} else {
+			keys = append(keys, sortKey{property: p})
+		}
+	}
+	return keys
+}
+
+// selectItems will filter, sort and limit a pointer to a slice of items in
+// place, using --filter, --sort and --limit. properties are the items' field
+// names or json names
+func selectItems(c *cli.Context, v interface{}) error {
+	if len(c.StringSlice("filter")) == 0 && c.String("sort") == "" && c.Int("limit") <= 0 {
+		return nil
+	}
+
+	s := reflect.ValueOf(v)
+	if s.Kind() != reflect.Ptr || s.Elem().Kind() != reflect.Slice {
+		return errors.New("only lists can be filtered, sorted and limited")
+	}
+	s = s.Elem()
+
+	filters := []filter{}
+	for _, i := range c.StringSlice("filter") {
+		f, err := parseFilter(i)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+	}
+	keys := parseSortKeys(c.String("sort"))
+
+	// the printed value of every property used, by item
+	values := make([]map[string]string, s.Len())
+	value := func(i int, property string) (string, error) {
+		if values[i] == nil {
+			values[i] = map[string]string{}
+		}
+		if v, ok := values[i][property]; ok {
+			return v, nil
+		}
+		f, ok := field(s.Index(i).Interface(), property)
+		if !ok {
+			return "", errors.New("unknown property " + property)
+		}
+		values[i][property] = formatValue(f)
+		return values[i][property], nil
+	}
+
+	selected := []int{}
+	for i := 0; i < s.Len(); i++ {
+		matched := true
+		for _, f := range filters {
+			v, err := value(i, f.property)
+			if err != nil {
+				return err
+			}
+			if !f.match(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			selected = append(selected, i)
+		}
+	}
+
+	// a key is compared as one kind across all values, so the order is
+	// the same whichever two items are compared
+	kinds := make([]valueKind, len(keys))
+	for j, k := range keys {
+		column := make([]string, len(selected))
+		for n, i := range selected {
+			v, err := value(i, k.property)
+			if err != nil {
+				return err
+			}
+			column[n] = v
+		}
+		kinds[j] = valueKindOf(column...)
+	}
+	sort.SliceStable(selected, func(a, b int) bool {
+		for j, k := range keys {
+			r := compareAs(kinds[j], values[selected[a]][k.property], values[selected[b]][k.property])
+			if k.reverse {
+				r = -r
+			}
+			if r != 0 {
+				return r < 0
+			}
+		}
+		return false
+	})
+
+	if l := c.Int("limit"); l > 0 && len(selected) > l {
+		selected = selected[:l]
+	}
+
+	r := reflect.MakeSlice(s.Type(), 0, len(selected))
+	for _, i := range selected {
+		r = reflect.Append(r, s.Index(i))
+	}
+	s.Set(r)
+
+	return nil
+}
+
+// value kinds
+type valueKind int
+
+const (
+	kindText valueKind = iota
+	kindAddress
+	kindPrefix
+	kindNumber
+)
+
+// valueKindOf returns the kind every value is, addresses, networks or
+// numbers, otherwise text. empty values are left out
+func valueKindOf(values ...string) valueKind {
+	for _, k := range []valueKind{kindAddress, kindPrefix, kindNumber} {
+		all := true
+		for _, v := range values {
+			if v != "" && !isKind(v, k) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return k
+		}
+	}
+	return kindText
+}
+
+func isKind(v string, k valueKind) bool {
+	switch k {
+	case kindAddress:
+		_, err := device42.ParseAddress(v)
+		return err == nil
+	case kindPrefix:
+		_, err := netip.ParsePrefix(strings.TrimSpace(v))
+		return err == nil
+	case kindNumber:
+		f, err := strconv.ParseFloat(v, 64)
+		return err == nil && !math.IsNaN(f)
+	}
+	return true
+}
+
+// compareValues will compare two printed values as addresses, networks or
+// numbers when both are one, otherwise as text ignoring case
+func compareValues(a, b string) int {
+	return compareAs(valueKindOf(a, b), a, b)
+}
+
+// compareAs will compare two printed values of a kind. empty values come
+// first
+func compareAs(k valueKind, a, b string) int {
+	if a == "" || b == "" {
+		return strings.Compare(a, b)
+	}
+
+	switch k {
+	case kindAddress:
+		x, _ := device42.ParseAddress(a)
+		y, _ := device42.ParseAddress(b)
+		return x.Compare(y)
+	case kindPrefix:
+		x, _ := netip.ParsePrefix(strings.TrimSpace(a))
+		y, _ := netip.ParsePrefix(strings.TrimSpace(b))
+		if r := x.Addr().Unmap().Compare(y.Addr().Unmap()); r != 0 {
+			return r
+		}
+		return compareInts(x.Bits(), y.Bits())
+	case kindNumber:
+		x, _ := strconv.ParseFloat(a, 64)
+		y, _ := strconv.ParseFloat(b, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"flag"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    filter
+		wantErr bool
+	}{
+		{in: "Label=web", want: filter{property: "Label", operator: "=", value: "web"}},
+		{in: "Label!=web", want: filter{property: "Label", operator: "!=", value: "web"}},
+		{in: " MaskBits >=24", want: filter{property: "MaskBits", operator: ">=", value: "24"}},
+		{in: "Label=", want: filter{property: "Label", operator: "=", value: ""}},
+		{in: "Notes=a=b", want: filter{property: "Notes", operator: "=", value: "a=b"}},
+		{in: "Label~=^web", want: filter{property: "Label", operator: "~=", value: "^web"}},
+		{in: "Label!~[", wantErr: true},
+		{in: "=web", wantErr: true},
+		{in: "Label", wantErr: true},
+		{in: "Label!web", wantErr: true},
+		{in: "Label~web", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFilter(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFilter(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		got.re = nil
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFilter(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		filter string
+		value  string
+		want   bool
+	}{
+		{filter: "Label=web", value: "WEB", want: true},
+		{filter: "Label=web", value: "web01", want: false},
+		{filter: "Label!=web", value: "db", want: true},
+		{filter: "Label~=^web[0-9]+$", value: "web01", want: true},
+		{filter: "Label~=^web[0-9]+$", value: "webserver", want: false},
+		{filter: "Label!~^web", value: "db01", want: true},
+		{filter: "MaskBits<24", value: "8", want: true},
+		{filter: "MaskBits<=24", value: "24", want: true},
+		{filter: "MaskBits>24", value: "8", want: false},
+		{filter: "Address>=10.0.0.10", value: "10.0.0.9", want: false},
+		{filter: "Address>10.0.0.10", value: "10.0.0.100", want: true},
+		{filter: "Address=2001:db8::1", value: "2001:DB8:0::1", want: true},
+		{filter: "Network<10.0.0.0/16", value: "10.0.0.0/8", want: true},
+		{filter: "Notes=", value: "", want: true},
+		{filter: "Notes>", value: "x", want: true},
+	}
+
+	for _, tt := range tests {
+		f, err := parseFilter(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.match(tt.value); got != tt.want {
+			t.Errorf("%s match(%q) = %v, want %v", tt.filter, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseSortKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []sortKey
+	}{
+		{in: "", want: []sortKey{}},
+		{in: "Network", want: []sortKey{{property: "Network"}}},
+		{in: "-MaskBits, Name", want: []sortKey{{property: "MaskBits", reverse: true}, {property: "Name"}}},
+	}
+
+	for _, tt := range tests {
+		if got := parseSortKeys(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSortKeys(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValueKindOf(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   valueKind
+	}{
+		{values: nil, want: kindAddress},
+		{values: []string{"10.0.0.1", "2001:db8::1", ""}, want: kindAddress},
+		{values: []string{"10.0.0.0/8", "2001:db8::/32"}, want: kindPrefix},
+		{values: []string{"1", "-2.5", "1e3"}, want: kindNumber},
+		{values: []string{"1", "NaN"}, want: kindText},
+		{values: []string{"10.0.0.1", "10.0.0.0/8"}, want: kindText},
+		{values: []string{"10", "web"}, want: kindText},
+	}
+
+	for _, tt := range tests {
+		if got := valueKindOf(tt.values...); got != tt.want {
+			t.Errorf("valueKindOf(%q) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "10.0.0.9", b: "10.0.0.10", want: -1},
+		{a: "10.0.0.1", b: "2001:db8::1", want: -1},
+		{a: "10.0.0.0/8", b: "10.0.0.0/16", want: -1},
+		{a: "10.1.0.0/16", b: "10.0.0.0/8", want: 1},
+		{a: "9", b: "10", want: -1},
+		{a: "1.0", b: "1", want: 0},
+		{a: "Web", b: "web", want: 0},
+		{a: "9", b: "web", want: -1},
+		{a: "", b: "0", want: -1},
+		{a: "", b: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := compareValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareValues(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestCompareAsTransitive sorts a column mixing numbers and text, which
+// compared two by two as numbers or text would not be ordered consistently
+func TestCompareAsTransitive(t *testing.T) {
+	column := []string{"10", "9", "1a", "", "100"}
+	k := valueKindOf(column...)
+
+	sort.SliceStable(column, func(i, j int) bool { return compareAs(k, column[i], column[j]) < 0 })
+	if want := []string{"", "10", "100", "1a", "9"}; !reflect.DeepEqual(column, want) {
+		t.Errorf("sorted = %q, want %q", column, want)
+	}
+
+	for _, a := range column {
+		for _, b := range column {
+			for _, c := range column {
+				if compareAs(k, a, b) < 0 && compareAs(k, b, c) < 0 && compareAs(k, a, c) >= 0 {
+					t.Errorf("%q < %q < %q but not %q < %q", a, b, c, a, c)
+				}
+			}
+		}
+	}
+}
+
+func TestSelectItems(t *testing.T) {
+	items := []testItem{
+		{ID: 1, Name: "web", VrfGroup: "prod"},
+		{ID: 2, Name: "db", VrfGroup: "prod"},
+		{ID: 10, Name: "web2", VrfGroup: "lab"},
+		{ID: 3, Name: "cache"},
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []int
+		wantErr bool
+	}{
+		{name: "nothing", want: []int{1, 2, 10, 3}},
+		{name: "filter", args: []string{"--filter", "Name~=^web"}, want: []int{1, 10}},
+		{name: "filters", args: []string{"--filter", "VRF Group=prod", "--filter", "id>1"}, want: []int{2}},
+		{name: "sort numbers", args: []string{"--sort", "id"}, want: []int{1, 2, 3, 10}},
+		{name: "sort keys", args: []string{"--sort", "vrf_group,-name"}, want: []int{3, 10, 1, 2}},
+		{name: "limit", args: []string{"--sort", "-id", "--limit", "2"}, want: []int{10, 3}},
+		{name: "unknown filter property", args: []string{"--filter", "bogus=1"}, wantErr: true},
+		{name: "unknown sort property", args: []string{"--sort", "bogus"}, wantErr: true},
+		{name: "invalid filter", args: []string{"--filter", "Name"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		set := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+		for _, f := range addListFlags(nil) {
+			if err := f.Apply(set); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := set.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+
+		got := append([]testItem{}, items...)
+		err := selectItems(cli.NewContext(cli.NewApp(), set, nil), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: selectItems error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		ids := []int{}
+		for _, i := range got {
+			ids = append(ids, i.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: selectItems = %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/urfave/cli/v2"
+)
+
+func historyCommand(app *cli.App) *cli.Command {
+	flags := addListFlags(addDisplayFlags([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "type",
+			Usage:    "object `TYPE` (ip, subnet, vlan, vrfgroup, building, device)",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "id",
+			Usage:    "object `ID`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "user",
+			Usage:    "only changes made by `USER`",
+			Required: false,
+		},
+		&cli.DurationFlag{
+			Name:     "since",
+			Usage:    "only changes made within `DURATION` (e.g. 24h)",
+			Required: false,
+		},
+	}))
+
+	return &cli.Command{
+		Name:  "history",
+		Usage: "show the change history of objects",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			if c.Int("id") != 0 && c.String("type") == "" {
+				_ = cli.ShowCommandHelp(c, "history")
+				return errors.New("you must supply a type with an id")
+			}
+
+			f := device42.HistoryFilter{
+				ObjectType: c.String("type"),
+				ObjectID:   c.Int("id"),
+				User:       c.String("user"),
+			}
+			if d := c.Duration("since"); d > 0 {
+				f.Since = time.Now().Add(-d)
+			}
+
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			history, err := api.GetHistory(f)
+			if err != nil {
+				return err
+			}
+
+			if err := selectItems(c, history); err != nil {
+				return err
+			}
+
+			data := [][]string{}
+			for _, i := range *history {
+				data = append(data, []string{
+					i.ActionTime, i.User, i.Action, i.ContentType, strconv.Itoa(i.ObjectID), i.ObjectRepr, i.Summary(),
+				})
+			}
+			headers := []string{"Time", "User", "Action", "Type", "ID", "Object", "Changes"}
+			return printTable(c, *history, headers, data)
+		},
+	}
+}
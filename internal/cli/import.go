@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/netbox"
+
+	"github.com/urfave/cli/v2"
+)
+
+func importCommands(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "import data from other systems into device42",
+		Subcommands: []*cli.Command{
+			importNetBox(app),
+		},
+	}
+}
+
+func importNetBox(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:      "netbox",
+		Usage:     "upsert sites, vrfs, vlans, prefixes and ip addresses from a netbox json export",
+		ArgsUsage: "FILE",
+		Flags:     addDisplayFlags(nil),
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				_ = cli.ShowCommandHelp(c, "netbox")
+				return errors.New("you must supply a netbox export file")
+			}
+
+			b, err := os.ReadFile(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			d := netbox.Document{}
+			err = json.Unmarshal(b, &d)
+			if err != nil {
+				return err
+			}
+
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			r, err := netbox.Import(api, &d)
+			if r != nil {
+				data := [][]string{
+					{"buildings", strconv.Itoa(len(r.Buildings)), strconv.Itoa(len(d.Sites))},
+					{"vrf groups", strconv.Itoa(len(r.VRFGroups)), strconv.Itoa(len(d.VRFs))},
+					{"vlans", strconv.Itoa(len(r.VLANs)), strconv.Itoa(len(d.VLANs))},
+					{"subnets", strconv.Itoa(len(r.Subnets)), strconv.Itoa(len(d.Prefixes))},
+					{"ips", strconv.Itoa(len(r.IPs)), strconv.Itoa(len(d.IPAddresses))},
+				}
+				headers := []string{"Type", "Imported", "Total"}
+				if err := printTable(c, r, headers, data); err != nil {
+					return err
+				}
+			}
+			return err
+		},
+	}
+}
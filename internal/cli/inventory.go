@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/ansible"
+
+	"github.com/urfave/cli/v2"
+)
+
+func inventoryCommand(app *cli.App) *cli.Command {
+	flags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:     "list",
+			Usage:    "print the whole inventory",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "host",
+			Usage:    "print the vars of `HOST`",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "vrf-group-id",
+			Usage:    "only include ips in vrf group `ID`",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "include-unlabeled",
+			Usage:    "include ips without a label or device, named by address",
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "inventory",
+		Usage: "ansible dynamic inventory",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			if c.Bool("list") == (c.String("host") != "") {
+				_ = cli.ShowCommandHelp(c, "inventory")
+				return errors.New("you must supply either --list or --host")
+			}
+
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			var (
+				subnets *[]device42.Subnet
+				err     error
+			)
+			if c.Int("vrf-group-id") != 0 {
+				subnets, err = api.GetSubnetsByVRFGroupID(c.Int("vrf-group-id"))
+			} else {
+				subnets, err = api.GetSubnets()
+			}
+			if err != nil {
+				return err
+			}
+
+			ips, err := api.GetIPs()
+			if err != nil {
+				return err
+			}
+			if c.Int("vrf-group-id") != 0 {
+				inGroup := map[int]bool{}
+				for _, s := range *subnets {
+					inGroup[s.SubnetID] = true
+				}
+				filtered := []device42.IP{}
+				for _, i := range *ips {
+					if inGroup[i.SubnetID] {
+						filtered = append(filtered, i)
+					}
+				}
+				ips = &filtered
+			}
+
+			vrfGroups, err := api.GetVRFGroups()
+			if err != nil {
+				return err
+			}
+
+			inv := ansible.Build(*subnets, *ips, *vrfGroups, ansible.Options{
+				IncludeUnlabeled: c.Bool("include-unlabeled"),
+			})
+
+			var b []byte
+			if c.Bool("list") {
+				b, err = inv.List()
+			} else {
+				b, err = inv.Host(c.String("host"))
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(string(b))
+			return nil
+		},
+	}
+}
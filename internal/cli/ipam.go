@@ -1,6 +1,8 @@
 package cli
 
 import (
+	device42 "github.com/chopnico/device42-go"
+
 	"github.com/urfave/cli/v2"
 )
 
@@ -29,6 +31,29 @@ func ipamCommands(app *cli.App) *cli.Command {
 				Usage:       "vlan management",
 				Subcommands: ipamVLANCommands(app),
 			},
+			{
+				Name:        "network",
+				Usage:       "network provisioning",
+				Subcommands: ipamNetworkCommands(app),
+			},
+			{
+				Name:        "report",
+				Usage:       "ipam reports",
+				Subcommands: ipamReportCommands(app),
+			},
+			ipamAudit(app),
 		},
 	}
 }
+
+// addressFamily returns the family of an address for table output
+func addressFamily(a string) string {
+	addr, err := device42.ParseAddress(a)
+	if err != nil {
+		return ""
+	}
+	if addr.Is6() {
+		return "ipv6"
+	}
+	return "ipv4"
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/urfave/cli/v2"
+)
+
+func ipamAudit(app *cli.App) *cli.Command {
+	flags := addListFlags(addDisplayFlags([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "rules",
+			Usage:    "only report findings for these `RULES`",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "fail",
+			Usage:    "exit with 1 when there are findings",
+			Required: false,
+		},
+	}))
+
+	return &cli.Command{
+		Name:  "audit",
+		Usage: "audit subnets, ips and vlans for overlaps and conflicts (formats: table, list, json, jsonl, yaml, csv, tsv, sarif)",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			findings, err := api.AuditIPAM()
+			if err != nil {
+				return err
+			}
+
+			if c.String("rules") != "" {
+				rules := map[string]bool{}
+				for _, r := range strings.Split(c.String("rules"), ",") {
+					if _, ok := device42.AuditRules[r]; !ok {
+						return fmt.Errorf("unknown audit rule %s", r)
+					}
+					rules[r] = true
+				}
+
+				filtered := []device42.AuditFinding{}
+				for _, i := range findings {
+					if rules[i.Rule] {
+						filtered = append(filtered, i)
+					}
+				}
+				findings = filtered
+			}
+
+			if err := selectItems(c, &findings); err != nil {
+				return err
+			}
+
+			if c.String("format") == "sarif" {
+				fmt.Printf("%s\n", formatAuditAsSARIF(findings))
+			} else {
+				data := [][]string{}
+				for _, i := range findings {
+					data = append(data, []string{
+						i.Severity, i.Rule, i.ObjectType, strconv.Itoa(i.ObjectID), strconv.Itoa(i.VrfGroupID), i.Message,
+					})
+				}
+				headers := []string{"Severity", "Rule", "Type", "ID", "VRF Group ID", "Message"}
+				if err := printTable(c, findings, headers, data); err != nil {
+					return err
+				}
+			}
+
+			if c.Bool("fail") && len(findings) > 0 {
+				return cli.Exit(strconv.Itoa(len(findings))+" audit findings", 1)
+			}
+
+			return nil
+		},
+	}
+}
+
+// formatAuditAsSARIF renders findings as a sarif log
+func formatAuditAsSARIF(findings []device42.AuditFinding) string {
+	type message struct {
+		Text string `json:"text"`
+	}
+	type logicalLocation struct {
+		Name               string `json:"name"`
+		Kind               string `json:"kind"`
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+	}
+	type location struct {
+		LogicalLocations []logicalLocation `json:"logicalLocations"`
+	}
+	type result struct {
+		RuleID     string                 `json:"ruleId"`
+		Level      string                 `json:"level"`
+		Message    message                `json:"message"`
+		Locations  []location             `json:"locations"`
+		Properties map[string]interface{} `json:"properties,omitempty"`
+	}
+	type rule struct {
+		ID               string  `json:"id"`
+		ShortDescription message `json:"shortDescription"`
+	}
+
+	rules := []rule{}
+	for id, d := range device42.AuditRules {
+		rules = append(rules, rule{ID: id, ShortDescription: message{Text: d}})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	results := []result{}
+	for _, i := range findings {
+		name := i.ObjectType + "/" + strconv.Itoa(i.ObjectID)
+		results = append(results, result{
+			RuleID:  i.Rule,
+			Level:   i.Severity,
+			Message: message{Text: i.Message},
+			Locations: []location{{
+				LogicalLocations: []logicalLocation{{
+					Name:               strconv.Itoa(i.ObjectID),
+					Kind:               i.ObjectType,
+					FullyQualifiedName: name,
+				}},
+			}},
+			Properties: map[string]interface{}{
+				"vrf_group_id": i.VrfGroupID,
+				"related_ids":  i.RelatedIDs,
+			},
+		})
+	}
+
+	log := map[string]interface{}{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":  "device42 ipam audit",
+						"rules": rules,
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+
+	b, _ := json.MarshalIndent(log, "", "  ")
+	return string(b)
+}
@@ -4,11 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 
 	device42 "github.com/chopnico/device42-go"
 
-	"github.com/chopnico/output"
 	"github.com/urfave/cli/v2"
 )
 
@@ -22,6 +20,9 @@ func ipamIPCommands(app *cli.App) []*cli.Command {
 		ipamIPDelete(app),
 		ipamIPClear(app),
 		ipamIPSuggest(app),
+		ipamIPAllocate(app),
+		ipamIPRelease(app),
+		ipamIPReconcile(app),
 	)
 
 	return commands
@@ -55,7 +56,7 @@ func ipamIPClear(app *cli.App) *cli.Command {
 }
 
 func ipamIPSuggest(app *cli.App) *cli.Command {
-	flags := []cli.Flag{
+	flags := addDisplayFlags([]cli.Flag{
 		&cli.IntFlag{
 			Name:     "subnet-id",
 			Usage:    "`SUBNET-ID` to chose an ip from",
@@ -70,7 +71,7 @@ func ipamIPSuggest(app *cli.App) *cli.Command {
 			Name:  "reserve",
 			Usage: "reserve IP address",
 		},
-	}
+	})
 
 	return &cli.Command{
 		Name:  "suggest",
@@ -90,12 +91,104 @@ func ipamIPSuggest(app *cli.App) *cli.Command {
 				}
 			}
 
-			switch c.String("format") {
-			case "json":
-				fmt.Printf("%s\n", output.FormatItemAsJson(ip))
-			default:
-				fmt.Print(output.FormatItemAsList(&ip, []string{"Address"}))
+			return printItem(c, ip, []string{"Address"})
+		},
+	}
+}
+
+func ipamIPAllocate(app *cli.App) *cli.Command {
+	flags := addQuietFlag(addDisplayFlags([]cli.Flag{
+		&cli.IntFlag{
+			Name:     "subnet-id",
+			Usage:    "`SUBNET-ID` to allocate an ip from",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:     "mask-bits",
+			Usage:    "`MASK-BITS` for ip",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "label",
+			Usage:    "`LABEL` of the ip",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "notes",
+			Usage:    "`NOTES` for the ip",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "owner",
+			Usage:    "`OWNER` token to claim the ip with (generated if empty)",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "retries",
+			Usage:    "number of `RETRIES` when another client claims the same ip",
+			Value:    5,
+			Required: false,
+		},
+	}))
+
+	return &cli.Command{
+		Name:  "allocate",
+		Usage: "reserve an ip address and claim it with an owner token",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			lease, err := api.AllocateIP(c.Context, c.Int("subnet-id"), device42.AllocateIPOptions{
+				MaskBits: c.Int("mask-bits"),
+				Label:    c.String("label"),
+				Notes:    c.String("notes"),
+				Owner:    c.String("owner"),
+				Retries:  c.Int("retries"),
+			})
+			if err != nil {
+				return err
+			}
+
+			if c.Bool("quiet") {
+				fmt.Println(lease.IP.ID)
+				return nil
 			}
+			// the owner is only in the notes of the ip, so the lease is
+			// printed whole
+			if isStructured(c) {
+				return printItem(c, lease, nil)
+			}
+			return printItem(c, lease.IP, []string{"ID", "Address", "Label", "Notes"})
+		},
+	}
+}
+
+func ipamIPRelease(app *cli.App) *cli.Command {
+	flags := []cli.Flag{
+		&cli.IntFlag{
+			Name:     "id",
+			Usage:    "`ID` of the ip to release",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "owner",
+			Usage:    "`OWNER` token the ip was allocated with",
+			Required: true,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "release",
+		Usage: "release an allocated ip address",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			err := api.ReleaseIP(c.Int("id"), c.String("owner"))
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("successfully released ip with id " + strconv.Itoa(c.Int("id")))
+
 			return nil
 		},
 	}
@@ -126,27 +219,13 @@ func ipamIPGet(app *cli.App) *cli.Command {
 				return err
 			}
 
-			if c.Bool("quiet") {
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Printf("%s\n", output.FormatItemAsJson(ip))
-				default:
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemAsList(&ip, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemAsList(&ip, p))
-					}
-				}
-			}
-			return nil
+			return printItem(c, ip, nil)
 		},
 	}
 }
 
 func ipamIPSet(app *cli.App) *cli.Command {
-	flags := addQuietFlag([]cli.Flag{
+	flags := addQuietFlag(addDisplayFlags([]cli.Flag{
 		&cli.StringFlag{
 			Name:     "address",
 			Usage:    "`ADDRESS` of the ip",
@@ -172,7 +251,7 @@ func ipamIPSet(app *cli.App) *cli.Command {
 			Usage:    "`VRF-GROUP` for the ip",
 			Required: false,
 		},
-	})
+	}))
 
 	return &cli.Command{
 		Name:  "set",
@@ -195,27 +274,15 @@ func ipamIPSet(app *cli.App) *cli.Command {
 
 			if c.Bool("quiet") {
 				fmt.Println(ip.ID)
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Printf("%s\n", output.FormatItemAsJson(ip))
-				default:
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemAsList(ip, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemAsList(ip, p))
-					}
-				}
+				return nil
 			}
-
-			return nil
+			return printItem(c, ip, nil)
 		},
 	}
 }
 
 func ipamIPList(app *cli.App) *cli.Command {
-	flags := addQuietFlag(addDisplayFlags(nil))
+	flags := addQuietFlag(addListFlags(addDisplayFlags(nil)))
 
 	return &cli.Command{
 		Name:  "list",
@@ -229,33 +296,25 @@ func ipamIPList(app *cli.App) *cli.Command {
 				return err
 			}
 
+			if err := selectItems(c, ips); err != nil {
+				return err
+			}
+
 			if c.Bool("quiet") {
 				for _, i := range *ips {
 					fmt.Println(i.ID)
 				}
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Print(output.FormatItemsAsJson(ips))
-				case "list":
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemsAsList(ips, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemsAsList(ips, p))
-					}
-				default:
-					data := [][]string{}
-					for _, i := range *ips {
-						data = append(data,
-							[]string{strconv.Itoa(i.ID), i.Address, i.Subnet, i.Label},
-						)
-					}
-					headers := []string{"ID", "Address", "Subnet", "Label"}
-					fmt.Print(output.FormatTable(data, headers))
-				}
+				return nil
 			}
-			return nil
+
+			data := [][]string{}
+			for _, i := range *ips {
+				data = append(data,
+					[]string{strconv.Itoa(i.ID), device42.NormalizeAddress(i.Address), addressFamily(i.Address), i.Subnet, i.Label},
+				)
+			}
+			headers := []string{"ID", "Address", "Family", "Subnet", "Label"}
+			return printTable(c, ips, headers, data)
 		},
 	}
 }
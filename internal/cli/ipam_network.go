@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/urfave/cli/v2"
+)
+
+func ipamNetworkCommands(app *cli.App) []*cli.Command {
+	var commands []*cli.Command
+
+	commands = append(commands,
+		ipamNetworkCreate(app),
+	)
+
+	return commands
+}
+
+func ipamNetworkCreate(app *cli.App) *cli.Command {
+	flags := addQuietFlag(addDisplayFlags([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "name",
+			Usage:    "`NAME` of the network",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:     "parent-id",
+			Usage:    "the parent `SUBNET-ID` to carve the network from",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:     "mask-bits",
+			Usage:    "`MASK-BITS` of the network",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "vlan-range",
+			Usage:    "vlan number `RANGE` to allocate from, e.g. 100-199",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "vlan-name",
+			Usage:    "`VLAN-NAME` (defaults to the network name)",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "vlan-scope-tags",
+			Usage:    "only consider vlans with any of these `TAGS` as used",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "tags",
+			Usage:    "`TAGS` of the subnet and vlan",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "skip-broadcast",
+			Usage:    "do not reserve the broadcast address",
+			Required: false,
+		},
+	}))
+
+	return &cli.Command{
+		Name:  "create",
+		Usage: "provision a vlan, subnet and gateway in one go",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			var start, end int
+			_, err := fmt.Sscanf(c.String("vlan-range"), "%d-%d", &start, &end)
+			if err != nil {
+				return errors.New("invalid vlan range " + c.String("vlan-range") + ", expected START-END")
+			}
+
+			spec := device42.NetworkSpec{
+				Name:           c.String("name"),
+				ParentSubnetID: c.Int("parent-id"),
+				MaskBits:       c.Int("mask-bits"),
+				VLANRangeStart: start,
+				VLANRangeEnd:   end,
+				VLANName:       c.String("vlan-name"),
+				SkipBroadcast:  c.Bool("skip-broadcast"),
+			}
+			if c.String("tags") != "" {
+				spec.Tags = strings.Split(c.String("tags"), ",")
+			}
+			if c.String("vlan-scope-tags") != "" {
+				spec.VLANScopeTags = strings.Split(c.String("vlan-scope-tags"), ",")
+			}
+
+			network, err := api.ProvisionNetwork(c.Context, spec)
+			if err != nil {
+				return err
+			}
+
+			if c.Bool("quiet") {
+				fmt.Println(network.Subnet.SubnetID)
+			} else {
+				data := [][]string{
+					{"vlan", strconv.Itoa(network.VLAN.VlanID), network.VLAN.Name, strconv.Itoa(network.VLAN.Number)},
+					{"subnet", strconv.Itoa(network.Subnet.SubnetID), network.Subnet.Name,
+						device42.NormalizeAddress(network.Subnet.Network) + "/" + strconv.Itoa(network.Subnet.MaskBits)},
+					{"gateway", strconv.Itoa(network.Gateway.ID), network.Gateway.Label, network.Gateway.Address},
+				}
+				if network.Broadcast != nil {
+					data = append(data,
+						[]string{"broadcast", strconv.Itoa(network.Broadcast.ID), network.Broadcast.Label, network.Broadcast.Address},
+					)
+				}
+				headers := []string{"Object", "ID", "Name", "Value"}
+				if err := printTable(c, network, headers, data); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/neighbor"
+
+	"github.com/urfave/cli/v2"
+)
+
+func ipamIPReconcile(app *cli.App) *cli.Command {
+	flags := addListFlags(addDisplayFlags([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "type",
+			Usage:    "neighbor table `TYPE` (auto, linux, cisco)",
+			Value:    neighbor.FormatAuto,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "vrf-group-id",
+			Usage:    "only match subnets in `VRF-GROUP-ID`",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "create",
+			Usage:    "create missing ips with their mac address",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "changes-only",
+			Usage:    "do not print matched ips",
+			Required: false,
+		},
+	}))
+
+	return &cli.Command{
+		Name:      "reconcile",
+		Usage:     "reconcile ips against arp or neighbor table dumps",
+		ArgsUsage: "FILE...",
+		Flags:     flags,
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				_ = cli.ShowCommandHelp(c, "reconcile")
+				return errors.New("you must supply at least one neighbor table file")
+			}
+
+			entries := []neighbor.Entry{}
+			for _, f := range c.Args().Slice() {
+				r, err := os.Open(f)
+				if err != nil {
+					return err
+				}
+				e, err := neighbor.Parse(r, c.String("type"))
+				r.Close()
+				if err != nil {
+					return fmt.Errorf("%s: %w", f, err)
+				}
+				entries = append(entries, e...)
+			}
+
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			var (
+				subnets *[]device42.Subnet
+				err     error
+			)
+			if c.Int("vrf-group-id") != 0 {
+				subnets, err = api.GetSubnetsByVRFGroupID(c.Int("vrf-group-id"))
+			} else {
+				subnets, err = api.GetSubnets()
+			}
+			if err != nil {
+				return err
+			}
+			ips, err := api.GetIPs()
+			if err != nil {
+				return err
+			}
+
+			results := neighbor.Reconcile(entries, *subnets, *ips)
+			if c.Bool("create") {
+				err = neighbor.CreateUnrecorded(api, results)
+				if err != nil {
+					return err
+				}
+			}
+
+			if c.Bool("changes-only") {
+				changes := []neighbor.Result{}
+				for _, i := range results {
+					if i.Status != neighbor.StatusMatched {
+						changes = append(changes, i)
+					}
+				}
+				results = changes
+			}
+
+			if err := selectItems(c, &results); err != nil {
+				return err
+			}
+
+			data := [][]string{}
+			for _, i := range results {
+				var mac, id, subnet, recordedMac string
+				if i.Entry != nil {
+					mac = i.Entry.MacAddress
+				}
+				if i.IP != nil {
+					id = strconv.Itoa(i.IP.ID)
+					recordedMac = i.IP.MacAddress
+				}
+				if i.Subnet != nil {
+					subnet = i.Subnet.Name
+				}
+				data = append(data, []string{i.Status, i.Address.String(), mac, recordedMac, id, subnet})
+			}
+			headers := []string{"Status", "Address", "Seen MAC", "Recorded MAC", "IP ID", "Subnet"}
+			return printTable(c, results, headers, data)
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/urfave/cli/v2"
+)
+
+func ipamReportCommands(app *cli.App) []*cli.Command {
+	var commands []*cli.Command
+
+	commands = append(commands,
+		ipamReportUtilization(app),
+	)
+
+	return commands
+}
+
+func ipamReportUtilization(app *cli.App) *cli.Command {
+	flags := addListFlags(addDisplayFlags([]cli.Flag{
+		&cli.IntFlag{
+			Name:     "vrf-group-id",
+			Usage:    "only report on subnets in `VRF-GROUP-ID`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "source",
+			Usage:    "where used counts come from (ips, assigned)",
+			Value:    "ips",
+			Required: false,
+		},
+		&cli.Float64Flag{
+			Name:     "warning",
+			Usage:    "exit with 1 when a subnet is over `PERCENT` utilized",
+			Required: false,
+		},
+		&cli.Float64Flag{
+			Name:     "critical",
+			Usage:    "exit with 2 when a subnet is over `PERCENT` utilized",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "vrf-groups",
+			Usage:    "report per vrf group instead of per subnet",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "exceeding",
+			Usage:    "only show subnets over the warning or critical threshold",
+			Required: false,
+		},
+	}))
+
+	return &cli.Command{
+		Name:  "utilization",
+		Usage: "report subnet utilization and capacity",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			var countIPs bool
+			switch c.String("source") {
+			case "ips":
+				countIPs = true
+			case "assigned":
+				countIPs = false
+			default:
+				return fmt.Errorf("unknown source %s", c.String("source"))
+			}
+
+			report, err := api.GetUtilization(c.Int("vrf-group-id"), countIPs)
+			if err != nil {
+				return err
+			}
+
+			warning, critical := c.Float64("warning"), c.Float64("critical")
+			status := func(pct float64) string {
+				switch {
+				case critical > 0 && pct > critical:
+					return "critical"
+				case warning > 0 && pct > warning:
+					return "warning"
+				default:
+					return "ok"
+				}
+			}
+
+			subnets := report.Subnets
+			if c.Bool("exceeding") {
+				// over the lowest threshold set is over either of them
+				limit := warning
+				if critical > 0 && (limit <= 0 || critical < limit) {
+					limit = critical
+				}
+				subnets = []device42.SubnetUtilization{}
+				if limit > 0 {
+					subnets = report.Exceeding(limit)
+				}
+			}
+
+			if c.Bool("vrf-groups") {
+				if err := selectItems(c, &report.VRFGroups); err != nil {
+					return err
+				}
+
+				data := [][]string{}
+				for _, i := range report.VRFGroups {
+					data = append(data, []string{
+						strconv.Itoa(i.VrfGroupID), i.VrfGroupName, strconv.Itoa(i.Subnets),
+						strconv.FormatInt(i.Used, 10), i.Free.String(), i.Capacity.String(),
+						fmt.Sprintf("%.1f", i.Percent),
+					})
+				}
+				headers := []string{"ID", "VRF Group", "Subnets", "Used", "Free", "Capacity", "Percent"}
+				err = printTable(c, report.VRFGroups, headers, data)
+			} else {
+				if err := selectItems(c, &subnets); err != nil {
+					return err
+				}
+
+				data := [][]string{}
+				for _, i := range subnets {
+					data = append(data, []string{
+						strconv.Itoa(i.SubnetID), i.Name, i.Network + "/" + strconv.Itoa(i.MaskBits), i.VrfGroupName,
+						strconv.FormatInt(i.TotalUsed, 10), i.Free.String(), i.Capacity.String(),
+						fmt.Sprintf("%.1f", i.Percent), status(i.Percent),
+					})
+				}
+				headers := []string{"ID", "Name", "Network", "VRF Group", "Used", "Free", "Capacity", "Percent", "Status"}
+				err = printTable(c, subnets, headers, data)
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case critical > 0 && len(report.Exceeding(critical)) > 0:
+				return cli.Exit("one or more subnets are over the critical threshold", 2)
+			case warning > 0 && len(report.Exceeding(warning)) > 0:
+				return cli.Exit("one or more subnets are over the warning threshold", 1)
+			}
+
+			return nil
+		},
+	}
+}
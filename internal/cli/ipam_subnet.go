@@ -3,12 +3,12 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
 	device42 "github.com/chopnico/device42-go"
 
-	"github.com/chopnico/output"
 	"github.com/urfave/cli/v2"
 )
 
@@ -21,6 +21,8 @@ func ipamSubnetCommands(app *cli.App) []*cli.Command {
 		ipamSubnetGet(app),
 		ipamSubnetSet(app),
 		ipamSubnetSuggest(app),
+		ipamSubnetPlan(app),
+		ipamSubnetTree(app),
 		ipamSubnetDelete(app),
 	)
 
@@ -52,27 +54,13 @@ func ipamSubnetGet(app *cli.App) *cli.Command {
 				return err
 			}
 
-			if c.Bool("quiet") {
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Printf("%s\n", output.FormatItemAsJson(subnet))
-				default:
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemAsList(&subnet, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemAsList(&subnet, p))
-					}
-				}
-			}
-			return nil
+			return printItem(c, subnet, nil)
 		},
 	}
 }
 
 func ipamSubnetSuggest(app *cli.App) *cli.Command {
-	flags := []cli.Flag{
+	flags := addDisplayFlags([]cli.Flag{
 		&cli.IntFlag{
 			Name:     "subnet-id",
 			Usage:    "the parent `SUBNET-ID` to suggest a subnet from",
@@ -94,7 +82,7 @@ func ipamSubnetSuggest(app *cli.App) *cli.Command {
 			Value:    false,
 			Required: false,
 		},
-	}
+	})
 
 	return &cli.Command{
 		Name:  "suggest",
@@ -112,20 +100,239 @@ func ipamSubnetSuggest(app *cli.App) *cli.Command {
 				return err
 			}
 
-			switch c.String("format") {
-			case "json":
-				fmt.Printf("%s\n", output.FormatItemAsJson(subnet))
-			default:
-				fmt.Print(output.FormatItemAsList(subnet, []string{"SubnetID", "Name", "Network", "MaskBits", "VrfGroupName"}))
+			return printItem(c, subnet, []string{"SubnetID", "Name", "Network", "MaskBits", "VrfGroupName"})
+		},
+	}
+}
+
+func ipamSubnetPlan(app *cli.App) *cli.Command {
+	flags := addQuietFlag(addDisplayFlags([]cli.Flag{
+		&cli.IntFlag{
+			Name:     "parent-id",
+			Usage:    "the parent `SUBNET-ID` to carve subnets from",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "sizes",
+			Usage:    "`SIZES` to carve as mask bits and counts, e.g. 24x4,27x8",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "name-prefix",
+			Usage:    "`NAME-PREFIX` for created subnets, suffixed with the network",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "create",
+			Usage:    "should we go ahead and `CREATE` the planned subnets",
+			Value:    false,
+			Required: false,
+		},
+	}))
+
+	return &cli.Command{
+		Name:  "plan",
+		Usage: "plan child subnets within a parent subnet",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			requests, err := device42.ParseSubnetPlanSizes(c.String("sizes"))
+			if err != nil {
+				return err
 			}
 
-			return nil
+			plan, err := api.PlanChildSubnets(c.Int("parent-id"), requests)
+			if err != nil {
+				return err
+			}
+
+			subnets := &plan.Planned
+			if c.String("name-prefix") != "" {
+				for i := range plan.Planned {
+					plan.Planned[i].Name = c.String("name-prefix") + "-" + plan.Planned[i].Network
+				}
+			}
+			if c.Bool("create") {
+				subnets, err = api.CreateSubnetPlan(plan)
+				if err != nil {
+					return err
+				}
+			}
+
+			if c.Bool("quiet") {
+				for _, i := range *subnets {
+					fmt.Println(i.Network + "/" + strconv.Itoa(i.MaskBits))
+				}
+				return nil
+			}
+
+			data := [][]string{}
+			for _, i := range *subnets {
+				data = append(data,
+					[]string{strconv.Itoa(i.SubnetID), i.Name, i.Network, strconv.Itoa(i.MaskBits), addressFamily(i.Network)},
+				)
+			}
+			headers := []string{"ID", "Name", "Network", "MaskBits", "Family"}
+			return printTable(c, subnets, headers, data)
 		},
 	}
 }
 
+func ipamSubnetTree(app *cli.App) *cli.Command {
+	flags := addDisplayFlags([]cli.Flag{
+		&cli.IntFlag{
+			Name:     "vrf-group-id",
+			Usage:    "`VRF-GROUP-ID` of the subnets (all subnets if not set)",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "match",
+			Usage:    "only show subnets whose name or network contains `TEXT`, with their parents",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "no-utilization",
+			Usage:    "do not fetch ips to calculate utilization",
+			Required: false,
+		},
+	})
+
+	return &cli.Command{
+		Name:  "tree",
+		Usage: "show the subnet hierarchy",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			var (
+				roots []*device42.SubnetNode
+				err   error
+			)
+
+			if c.Int("vrf-group-id") != 0 {
+				roots, err = api.GetSubnetTree(c.Int("vrf-group-id"))
+			} else {
+				var subnets *[]device42.Subnet
+				subnets, err = api.GetSubnets()
+				if err == nil {
+					roots = device42.BuildSubnetTree(*subnets)
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			if f := c.String("match"); f != "" {
+				roots = device42.FilterSubnetTree(roots, func(s *device42.Subnet) bool {
+					return strings.Contains(s.Name, f) || strings.Contains(s.Network, f)
+				})
+			}
+
+			var utilization map[int]device42.SubnetUtilization
+			if !c.Bool("no-utilization") {
+				ips, err := api.GetIPs()
+				if err != nil {
+					return err
+				}
+
+				subnets := []device42.Subnet{}
+				device42.WalkSubnetTree(roots, func(n *device42.SubnetNode, depth int) bool {
+					subnets = append(subnets, n.Subnet)
+					return true
+				})
+
+				utilization = make(map[int]device42.SubnetUtilization)
+				for _, i := range device42.CalculateUtilization(subnets, *ips).Subnets {
+					utilization[i.SubnetID] = i
+				}
+			}
+
+			if isStructured(c) {
+				return printItem(c, roots, nil)
+			}
+			if c.String("format") == formatTable && c.String("template") == "" && c.String("properties") == "" {
+				fmt.Print(formatSubnetTree(roots, utilization))
+				return nil
+			}
+
+			// the other formats print the tree flattened, depth first
+			subnets := []device42.Subnet{}
+			data := [][]string{}
+			device42.WalkSubnetTree(roots, func(n *device42.SubnetNode, depth int) bool {
+				s := n.Subnet
+				row := []string{strconv.Itoa(s.SubnetID), s.Name, device42.NormalizeAddress(s.Network), strconv.Itoa(s.MaskBits),
+					strconv.Itoa(s.ParentSubnetID), strconv.Itoa(depth), "", "", ""}
+				if u, ok := utilization[s.SubnetID]; ok {
+					row[6] = strconv.FormatInt(u.TotalUsed, 10)
+					row[7] = u.Capacity.String()
+					row[8] = strconv.FormatFloat(u.Percent, 'f', 1, 64)
+				}
+				subnets = append(subnets, s)
+				data = append(data, row)
+				return true
+			})
+			headers := []string{"ID", "Name", "Network", "MaskBits", "Parent ID", "Depth", "Used", "Capacity", "Percent"}
+			return printTable(c, subnets, headers, data)
+		},
+	}
+}
+
+// formatSubnetTree renders subnet trees as indented text
+func formatSubnetTree(roots []*device42.SubnetNode, utilization map[int]device42.SubnetUtilization) string {
+	b := strings.Builder{}
+
+	var render func(n *device42.SubnetNode, prefix string, last, root bool)
+	render = func(n *device42.SubnetNode, prefix string, last, root bool) {
+		branch, next := "", ""
+		if !root {
+			if last {
+				branch, next = "└── ", "    "
+			} else {
+				branch, next = "├── ", "│   "
+			}
+		}
+
+		s := n.Subnet
+		line := device42.NormalizeAddress(s.Network) + "/" + strconv.Itoa(s.MaskBits)
+		if s.Name != "" {
+			line += " " + s.Name
+		}
+		if s.ParentVlanID != 0 {
+			line += fmt.Sprintf(" [vlan %v %s]", s.ParentVlanNumber, s.ParentVlanName)
+		}
+		if u, ok := utilization[s.SubnetID]; ok {
+			line += " " + formatUtilization(&s, int(u.TotalUsed))
+		}
+		b.WriteString(prefix + branch + line + "\n")
+
+		for i, c := range n.Children {
+			render(c, prefix+next, i == len(n.Children)-1, false)
+		}
+	}
+
+	for _, r := range roots {
+		render(r, "", true, true)
+	}
+
+	return b.String()
+}
+
+// formatUtilization renders used over capacity of a subnet
+func formatUtilization(s *device42.Subnet, used int) string {
+	capacity, err := s.Capacity()
+	if err != nil || capacity.Sign() <= 0 {
+		return strconv.Itoa(used) + "/?"
+	}
+
+	pct, _ := new(big.Float).Quo(
+		new(big.Float).SetInt64(int64(used)*100),
+		new(big.Float).SetInt(capacity),
+	).Float64()
+
+	return fmt.Sprintf("%d/%s (%.1f%%)", used, capacity.String(), pct)
+}
+
 func ipamSubnetSet(app *cli.App) *cli.Command {
-	flags := addQuietFlag([]cli.Flag{
+	flags := addQuietFlag(addDisplayFlags([]cli.Flag{
 		&cli.StringFlag{
 			Name:     "network",
 			Usage:    "`NETWORK` address of the subnet",
@@ -146,7 +353,17 @@ func ipamSubnetSet(app *cli.App) *cli.Command {
 			Usage:    "`VRF-GROUP` of the subnet",
 			Required: false,
 		},
-	})
+		&cli.IntFlag{
+			Name:     "vlan-id",
+			Usage:    "`VLAN-ID` to attach the subnet to",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "detach-vlan",
+			Usage:    "detach the subnet from its vlan",
+			Required: false,
+		},
+	}))
 
 	return &cli.Command{
 		Name:  "set",
@@ -161,42 +378,49 @@ func ipamSubnetSet(app *cli.App) *cli.Command {
 				VrfGroup: c.String("vrf-group"),
 			}
 
+			if c.Int("vlan-id") != 0 && c.Bool("detach-vlan") {
+				return errors.New("--vlan-id and --detach-vlan can not be used together")
+			}
+
 			subnet, err := api.SetSubnet(subnet)
 			if err != nil {
 				return err
 			}
 
-			if c.Bool("quiet") {
-				fmt.Println(subnet.SubnetID)
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Printf("%s\n", output.FormatItemAsJson(subnet))
-				default:
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemAsList(subnet, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemAsList(subnet, p))
-					}
+			switch {
+			case api.IsDryRun() && subnet.SubnetID == 0:
+				// a dry run does not read the subnet back, so its id is unknown
+				if c.Int("vlan-id") != 0 || c.Bool("detach-vlan") {
+					api.WriteToInfoLog("dry run : the subnet's vlan is set once the subnet is saved")
 				}
+			case c.Int("vlan-id") != 0:
+				subnet, err = api.AttachSubnetToVLAN(subnet.SubnetID, c.Int("vlan-id"))
+			case c.Bool("detach-vlan"):
+				subnet, err = api.DetachSubnetFromVLAN(subnet.SubnetID)
+			}
+			if err != nil {
+				return err
 			}
 
-			return nil
+			if c.Bool("quiet") {
+				fmt.Println(subnet.SubnetID)
+				return nil
+			}
+			return printItem(c, subnet, nil)
 		},
 	}
 }
 
 func ipamSubnetList(app *cli.App) *cli.Command {
 	flags := addQuietFlag(
-		addDisplayFlags([]cli.Flag{
+		addListFlags(addDisplayFlags([]cli.Flag{
 			&cli.StringFlag{
 				Name:     "filter-by-tags",
 				Usage:    "allows for filtering of subnets by a list of `TAGS`",
 				Required: false,
 			},
 		},
-		))
+		)))
 
 	return &cli.Command{
 		Name:  "list",
@@ -216,33 +440,26 @@ func ipamSubnetList(app *cli.App) *cli.Command {
 			if err != nil {
 				return err
 			}
+
+			if err := selectItems(c, subnets); err != nil {
+				return err
+			}
+
 			if c.Bool("quiet") {
 				for _, i := range *subnets {
 					fmt.Println(i.SubnetID)
 				}
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Print(output.FormatItemsAsJson(subnets))
-				case "list":
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemsAsList(subnets, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemsAsList(subnets, p))
-					}
-				default:
-					data := [][]string{}
-					for _, i := range *subnets {
-						data = append(data,
-							[]string{strconv.Itoa(i.SubnetID), i.Name, i.Network, strconv.Itoa(i.MaskBits), strconv.Itoa(i.ParentVlanID), i.VrfGroupName},
-						)
-					}
-					headers := []string{"ID", "Name", "Network", "MaskBits", "VLAN ID", "VRF Group"}
-					fmt.Print(output.FormatTable(data, headers))
-				}
+				return nil
 			}
-			return nil
+
+			data := [][]string{}
+			for _, i := range *subnets {
+				data = append(data,
+					[]string{strconv.Itoa(i.SubnetID), i.Name, device42.NormalizeAddress(i.Network), strconv.Itoa(i.MaskBits), addressFamily(i.Network), strconv.Itoa(i.ParentVlanID), i.VrfGroupName},
+				)
+			}
+			headers := []string{"ID", "Name", "Network", "MaskBits", "Family", "VLAN ID", "VRF Group"}
+			return printTable(c, subnets, headers, data)
 		},
 	}
 }
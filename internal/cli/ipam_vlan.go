@@ -17,7 +17,9 @@ func ipamVLANCommands(app *cli.App) []*cli.Command {
 	commands = append(commands,
 		ipamVLANList(app),
 		ipamVLANGet(app),
+		ipamVLANShow(app),
 		ipamVLANSet(app),
+		ipamVLANAllocate(app),
 		ipamVLANDelete(app),
 	)
 
@@ -26,14 +28,14 @@ func ipamVLANCommands(app *cli.App) []*cli.Command {
 
 func ipamVLANList(app *cli.App) *cli.Command {
 	flags := addQuietFlag(
-		addDisplayFlags([]cli.Flag{
+		addListFlags(addDisplayFlags([]cli.Flag{
 			&cli.StringFlag{
 				Name:     "filter-by-tags",
 				Usage:    "allows for filtering of vlans by a list of `TAGS`",
 				Required: false,
 			},
 		},
-		))
+		)))
 
 	return &cli.Command{
 		Name:  "list",
@@ -53,33 +55,26 @@ func ipamVLANList(app *cli.App) *cli.Command {
 			if err != nil {
 				return err
 			}
+
+			if err := selectItems(c, vlans); err != nil {
+				return err
+			}
+
 			if c.Bool("quiet") {
 				for _, i := range *vlans {
 					fmt.Println(i.VlanID)
 				}
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Print(output.FormatItemsAsJson(vlans))
-				case "list":
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemsAsList(vlans, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemsAsList(vlans, p))
-					}
-				default:
-					data := [][]string{}
-					for _, i := range *vlans {
-						data = append(data,
-							[]string{strconv.Itoa(i.VlanID), i.Name, strconv.Itoa(i.Number)},
-						)
-					}
-					headers := []string{"ID", "Name", "Number"}
-					fmt.Print(output.FormatTable(data, headers))
-				}
+				return nil
 			}
-			return nil
+
+			data := [][]string{}
+			for _, i := range *vlans {
+				data = append(data,
+					[]string{strconv.Itoa(i.VlanID), i.Name, strconv.Itoa(i.Number)},
+				)
+			}
+			headers := []string{"ID", "Name", "Number"}
+			return printTable(c, vlans, headers, data)
 		},
 	}
 }
@@ -109,24 +104,87 @@ func ipamVLANGet(app *cli.App) *cli.Command {
 				return err
 			}
 
-			switch c.String("format") {
-			case "json":
-				fmt.Printf("%s\n", output.FormatItemAsJson(vlan))
-			default:
-				if c.String("properties") == "" {
-					fmt.Print(output.FormatItemAsList(&vlan, nil))
-				} else {
-					p := strings.Split(c.String("properties"), ",")
-					fmt.Print(output.FormatItemAsList(&vlan, p))
+			return printItem(c, vlan, nil)
+		},
+	}
+}
+
+func ipamVLANShow(app *cli.App) *cli.Command {
+	flags := addDisplayFlags(nil)
+
+	return &cli.Command{
+		Name:      "show",
+		Usage:     "show a vlan with its subnets and switches",
+		ArgsUsage: "ID",
+		Flags:     flags,
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				_ = cli.ShowCommandHelp(c, "show")
+				return errors.New("you must supply a vlan id")
+			}
+
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			var id int
+			_, err := fmt.Sscan(c.Args().First(), &id)
+			if err != nil {
+				return err
+			}
+			details, err := api.GetVLANDetails(id)
+			if err != nil {
+				return err
+			}
+
+			v := details.VLAN
+			if isStructured(c) {
+				return printItem(c, details, nil)
+			}
+			if c.String("format") != formatTable || c.String("template") != "" || c.String("properties") != "" || c.Bool("no-headers") {
+				// the other formats print the vlan, its subnets and its
+				// switches flattened, a row each
+				data := [][]string{{"vlan", strconv.Itoa(v.VlanID), v.Name, strconv.Itoa(v.Number), "", "", ""}}
+				for _, i := range details.Subnets {
+					data = append(data,
+						[]string{"subnet", strconv.Itoa(i.SubnetID), i.Name, "", device42.NormalizeAddress(i.Network), strconv.Itoa(i.MaskBits), i.VrfGroupName},
+					)
+				}
+				for _, i := range v.Switches {
+					data = append(data,
+						[]string{"switch", strconv.Itoa(i.DeviceID), i.Name, "", "", "", ""},
+					)
 				}
+				headers := []string{"Type", "ID", "Name", "Number", "Network", "MaskBits", "VRF Group"}
+				return printTable(c, details, headers, data)
+			}
+
+			// the table format shows the vlan, its subnets and its switches
+			fmt.Print(output.FormatItemAsList(&v, []string{"VlanID", "Number", "Name", "Description", "Tags"}))
+			fmt.Println()
+
+			data := [][]string{}
+			for _, i := range details.Subnets {
+				data = append(data,
+					[]string{strconv.Itoa(i.SubnetID), i.Name, device42.NormalizeAddress(i.Network), strconv.Itoa(i.MaskBits), i.VrfGroupName},
+				)
+			}
+			fmt.Println("Subnets")
+			fmt.Print(output.FormatTable(data, []string{"ID", "Name", "Network", "MaskBits", "VRF Group"}))
+			fmt.Println()
+
+			data = [][]string{}
+			for _, i := range v.Switches {
+				data = append(data,
+					[]string{strconv.Itoa(i.DeviceID), i.Name, i.SerialNo, i.AssetNo},
+				)
 			}
+			fmt.Println("Switches")
+			fmt.Print(output.FormatTable(data, []string{"Device ID", "Name", "Serial No", "Asset No"}))
 			return nil
 		},
 	}
 }
 
 func ipamVLANSet(app *cli.App) *cli.Command {
-	flags := addQuietFlag([]cli.Flag{
+	flags := addQuietFlag(addDisplayFlags([]cli.Flag{
 		&cli.IntFlag{
 			Name:     "number",
 			Usage:    "the vlan `NUMBER`",
@@ -152,7 +210,7 @@ func ipamVLANSet(app *cli.App) *cli.Command {
 			Usage:    "`TAGS` of the vlan",
 			Required: false,
 		},
-	})
+	}))
 
 	return &cli.Command{
 		Name:  "set",
@@ -177,21 +235,84 @@ func ipamVLANSet(app *cli.App) *cli.Command {
 
 			if c.Bool("quiet") {
 				fmt.Println(vlan.VlanID)
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Printf("%s\n", output.FormatItemAsJson(vlan))
-				default:
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemAsList(vlan, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemAsList(vlan, p))
-					}
-				}
+				return nil
 			}
+			return printItem(c, vlan, nil)
+		},
+	}
+}
 
-			return nil
+func ipamVLANAllocate(app *cli.App) *cli.Command {
+	flags := addQuietFlag(addDisplayFlags([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "range",
+			Usage:    "vlan number `RANGE` to allocate from, e.g. 100-199",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "name",
+			Usage:    "`NAME` of the vlan",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "description",
+			Usage:    "`DESCRIPTION` of the vlan",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "notes",
+			Usage:    "`NOTES` of the vlan",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "tags",
+			Usage:    "`TAGS` of the vlan",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "scope-tags",
+			Usage:    "only consider vlans with any of these `TAGS` as used",
+			Required: false,
+		},
+	}))
+
+	return &cli.Command{
+		Name:  "allocate",
+		Usage: "create a vlan with the next free number in a range",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			var start, end int
+			_, err := fmt.Sscanf(c.String("range"), "%d-%d", &start, &end)
+			if err != nil {
+				return errors.New("invalid range " + c.String("range") + ", expected START-END")
+			}
+
+			opts := device42.AllocateVLANOptions{
+				RangeStart:  start,
+				RangeEnd:    end,
+				Name:        c.String("name"),
+				Description: c.String("description"),
+				Notes:       c.String("notes"),
+			}
+			if c.String("tags") != "" {
+				opts.Tags = strings.Split(c.String("tags"), ",")
+			}
+			if c.String("scope-tags") != "" {
+				opts.ScopeTags = strings.Split(c.String("scope-tags"), ",")
+			}
+
+			vlan, err := api.AllocateVLANWithOptions(c.Context, opts)
+			if err != nil {
+				return err
+			}
+
+			if c.Bool("quiet") {
+				fmt.Println(vlan.VlanID)
+				return nil
+			}
+			return printItem(c, vlan, nil)
 		},
 	}
 }
@@ -8,7 +8,6 @@ import (
 
 	device42 "github.com/chopnico/device42-go"
 
-	"github.com/chopnico/output"
 	"github.com/urfave/cli/v2"
 )
 
@@ -56,7 +55,7 @@ func ipamVRFGroupDelete(app *cli.App) *cli.Command {
 }
 
 func ipamVRFGroupSet(app *cli.App) *cli.Command {
-	flags := []cli.Flag{
+	flags := addDisplayFlags([]cli.Flag{
 		&cli.StringFlag{
 			Name:     "name",
 			Usage:    "`NAME` of the vrf group",
@@ -72,7 +71,7 @@ func ipamVRFGroupSet(app *cli.App) *cli.Command {
 			Usage:    "`BUILDINGS` where this vrf group is configured",
 			Required: false,
 		},
-	}
+	})
 
 	return &cli.Command{
 		Name:  "set",
@@ -94,18 +93,7 @@ func ipamVRFGroupSet(app *cli.App) *cli.Command {
 				return err
 			}
 
-			switch c.String("format") {
-			case "json":
-				fmt.Print(output.FormatItemAsJson(vg))
-			default:
-				if c.String("properties") == "" {
-					fmt.Print(output.FormatItemAsList(vg, nil))
-				} else {
-					p := strings.Split(c.String("properties"), ",")
-					fmt.Print(output.FormatItemsAsList(vg, p))
-				}
-			}
-			return nil
+			return printItem(c, vg, nil)
 		},
 	}
 }
@@ -153,24 +141,13 @@ func ipamVRFGroupGet(app *cli.App) *cli.Command {
 				return errors.New("you must either specifiy an id or the name of a vrf group")
 			}
 
-			switch c.String("format") {
-			case "json":
-				fmt.Print(output.FormatItemAsJson(vrfGroup))
-			default:
-				if c.String("properties") == "" {
-					fmt.Print(output.FormatItemAsList(vrfGroup, nil))
-				} else {
-					p := strings.Split(c.String("properties"), ",")
-					fmt.Print(output.FormatItemsAsList(vrfGroup, p))
-				}
-			}
-			return nil
+			return printItem(c, vrfGroup, nil)
 		},
 	}
 }
 
 func ipamVRFGroupList(app *cli.App) *cli.Command {
-	flags := addQuietFlag(addDisplayFlags(nil))
+	flags := addQuietFlag(addListFlags(addDisplayFlags(nil)))
 
 	return &cli.Command{
 		Name:  "list",
@@ -183,33 +160,25 @@ func ipamVRFGroupList(app *cli.App) *cli.Command {
 				return err
 			}
 
+			if err := selectItems(c, vrfGroups); err != nil {
+				return err
+			}
+
 			if c.Bool("quiet") {
 				for _, i := range *vrfGroups {
 					fmt.Println(i.ID)
 				}
-			} else {
-				switch c.String("format") {
-				case "json":
-					fmt.Print(output.FormatItemsAsJson(vrfGroups))
-				case "list":
-					if c.String("properties") == "" {
-						fmt.Print(output.FormatItemsAsList(vrfGroups, nil))
-					} else {
-						p := strings.Split(c.String("properties"), ",")
-						fmt.Print(output.FormatItemsAsList(vrfGroups, p))
-					}
-				default:
-					data := [][]string{}
-					for _, i := range *vrfGroups {
-						data = append(data,
-							[]string{strconv.Itoa(i.ID), i.Name, strings.Join(i.Buildings, ",")},
-						)
-					}
-					headers := []string{"ID", "Name", "Buildings"}
-					fmt.Print(output.FormatTable(data, headers))
-				}
+				return nil
 			}
-			return nil
+
+			data := [][]string{}
+			for _, i := range *vrfGroups {
+				data = append(data,
+					[]string{strconv.Itoa(i.ID), i.Name, strings.Join(i.Buildings, ",")},
+				)
+			}
+			headers := []string{"ID", "Name", "Buildings"}
+			return printTable(c, vrfGroups, headers, data)
 		},
 	}
 }
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/urfave/cli/v2"
+)
+
+func journalCommands(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:  "journal",
+		Usage: "change journals",
+		Subcommands: []*cli.Command{
+			journalList(app),
+			journalShow(app),
+		},
+	}
+}
+
+func journalList(app *cli.App) *cli.Command {
+	flags := addQuietFlag(addListFlags(addDisplayFlags(nil)))
+
+	return &cli.Command{
+		Name:  "list",
+		Usage: "list all change journals",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			journals, err := device42.ListJournals(c.String("journal-dir"))
+			if err != nil {
+				return err
+			}
+
+			if err := selectItems(c, &journals); err != nil {
+				return err
+			}
+
+			if c.Bool("quiet") {
+				for _, i := range journals {
+					fmt.Println(i.ID)
+				}
+				return nil
+			}
+
+			data := [][]string{}
+			for _, i := range journals {
+				undone := ""
+				if i.Undone != nil {
+					undone = i.Undone.Format("2006-01-02 15:04:05")
+				}
+				data = append(data, []string{
+					i.ID, i.Created.Format("2006-01-02 15:04:05"), strconv.Itoa(len(i.Entries)), undone, i.Description,
+				})
+			}
+			headers := []string{"ID", "Created", "Changes", "Undone", "Description"}
+			return printTable(c, journals, headers, data)
+		},
+	}
+}
+
+func journalShow(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:      "show",
+		Usage:     "show the changes recorded in a journal",
+		ArgsUsage: "JOURNAL-ID",
+		Flags:     addDisplayFlags(nil),
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				_ = cli.ShowCommandHelp(c, "show")
+				return errors.New("you must supply a journal id")
+			}
+
+			j, err := device42.OpenJournal(c.String("journal-dir"), c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			data := [][]string{}
+			for _, i := range j.Entries {
+				data = append(data, []string{
+					i.Time.Format("2006-01-02 15:04:05"), i.Action, i.Resource, strconv.Itoa(i.ObjectID), i.Method + " " + i.Path,
+				})
+			}
+			headers := []string{"Time", "Action", "Resource", "ID", "Request"}
+			// the journal is printed whole, its entries are the table
+			if isStructured(c) {
+				return printItem(c, j, nil)
+			}
+			return printTable(c, j.Entries, headers, data)
+		},
+	}
+}
+
+func undoCommand(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:      "undo",
+		Usage:     "revert the changes recorded in a journal",
+		ArgsUsage: "JOURNAL-ID",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				_ = cli.ShowCommandHelp(c, "undo")
+				return errors.New("you must supply a journal id")
+			}
+
+			j, err := device42.OpenJournal(c.String("journal-dir"), c.Args().First())
+			if err != nil {
+				return err
+			}
+			n := len(j.Entries)
+
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+			err = api.Undo(j)
+			if err != nil {
+				return err
+			}
+
+			if api.IsDryRun() {
+				fmt.Println("would revert " + strconv.Itoa(n) + " changes from journal " + j.ID)
+				return nil
+			}
+			fmt.Println("successfully reverted " + strconv.Itoa(n) + " changes from journal " + j.ID)
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/credentials"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+func loginCommand(app *cli.App) *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "username",
+			Usage:    "account `USERNAME`",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "password-stdin",
+			Usage:    "read the password from stdin",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "no-verify",
+			Usage:    "store the credentials without checking them against device42",
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "login",
+		Usage: "store credentials for the device42 host in the credential source",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			host := c.String("host")
+			if host == "" {
+				return errors.New(device42.ErrorEmptyHost)
+			}
+
+			username := c.String("username")
+			if username == "" {
+				username = globalString(c, "username")
+			}
+			if username == "" {
+				_ = cli.ShowCommandHelp(c, "login")
+				return errors.New(device42.ErrorEmptyUsername)
+			}
+
+			password, err := readPassword(c.Bool("password-stdin"))
+			if err != nil {
+				return err
+			}
+			if password == "" {
+				return errors.New(device42.ErrorEmptyPassword)
+			}
+
+			if !c.Bool("no-verify") {
+				api, err := device42.NewAPIBasicAuth(username, password, host)
+				if err != nil {
+					return err
+				}
+				api.Timeout(c.Int("timeout")).
+					LoggingLevel(c.String("logging")).
+					Proxy(c.String("proxy"))
+				if c.Bool("ignore-ssl") {
+					api.IgnoreSSLErrors()
+				}
+
+				_, err = api.GetBuildings()
+				if err != nil {
+					return errors.New("unable to log in to " + host + ": " + err.Error())
+				}
+			}
+
+			src, err := credentialSource(c)
+			if err != nil {
+				return err
+			}
+			err = src.Store(&credentials.Credential{Host: host, Username: username, Password: password})
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("successfully stored credentials for " + username + "@" + host + " in " + c.String("credentials"))
+			return nil
+		},
+	}
+}
+
+func logoutCommand(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:  "logout",
+		Usage: "remove the credentials for the device42 host from the credential source",
+		Action: func(c *cli.Context) error {
+			host := c.String("host")
+			if host == "" {
+				return errors.New(device42.ErrorEmptyHost)
+			}
+
+			src, err := credentialSource(c)
+			if err != nil {
+				return err
+			}
+			err = src.Erase(host)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("successfully removed credentials for " + host + " from " + c.String("credentials"))
+			return nil
+		},
+	}
+}
+
+// credentialSource will create the credential source chosen by the global flags
+func credentialSource(c *cli.Context) (credentials.Source, error) {
+	return credentials.New(c.String("credentials"), c.String("credential-helper"), c.String("credentials-file"))
+}
+
+// globalString will return a global flag shadowed by a command flag
+func globalString(c *cli.Context, name string) string {
+	l := c.Lineage()
+	return l[len(l)-1].String(name)
+}
+
+// readPassword will prompt for a password without echoing it, or read it
+// from stdin when it is not a terminal or fromStdin is set
+func readPassword(fromStdin bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !fromStdin && term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+
+	l, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(l, "\r\n"), nil
+}
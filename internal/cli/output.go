@@ -0,0 +1,526 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/chopnico/output"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// printing formats
+const (
+	formatTable = "table"
+	formatList  = "list"
+	formatJSON  = "json"
+	formatJSONL = "jsonl"
+	formatYAML  = "yaml"
+	formatCSV   = "csv"
+	formatTSV   = "tsv"
+)
+
+// formats every command supports
+var formats = []string{formatTable, formatList, formatJSON, formatJSONL, formatYAML, formatCSV, formatTSV}
+
+// templateFuncs are the functions available to --template
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// printItem will print a single item in the chosen format. the table format
+// prints it as a list. fields are the fields printed by the list, table,
+// csv and tsv formats unless --properties is set, nil prints all of them
+func printItem(c *cli.Context, v interface{}, fields []string) error {
+	properties := splitProperties(c.String("properties"))
+	if properties == nil && !isStructured(c) {
+		properties = fields
+	}
+	return render(c, v, nil, nil, properties)
+}
+
+// printTable will print v, a slice of items or a single item, in the chosen
+// format. headers and rows are v's table, used by the table, csv and tsv
+// formats. when v is a slice rows[i] describes v[i], so --properties may
+// also name fields of the items
+func printTable(c *cli.Context, v interface{}, headers []string, rows [][]string) error {
+	return render(c, v, headers, rows, splitProperties(c.String("properties")))
+}
+
+// isStructured checks whether the chosen format prints whole objects
+func isStructured(c *cli.Context) bool {
+	switch c.String("format") {
+	case formatJSON, formatJSONL, formatYAML:
+		return c.String("template") == ""
+	}
+	return false
+}
+
+func render(c *cli.Context, v interface{}, headers []string, rows [][]string, properties []string) error {
+	items, isList := itemsOf(v)
+
+	if c.String("template") != "" {
+		return printTemplate(c.String("template"), items)
+	}
+
+	format := c.String("format")
+	if format == formatTable && headers == nil && !isList {
+		format = formatList
+	}
+
+	switch format {
+	case formatJSON:
+		if properties != nil {
+			v = projectAll(items, properties, isList)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if isList && len(items) == 0 {
+			b = []byte("[]")
+		}
+		fmt.Printf("%s\n", b)
+	case formatJSONL:
+		for _, i := range items {
+			if properties != nil {
+				i = project(i, properties)
+			}
+			b, err := json.Marshal(i)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s\n", b)
+		}
+	case formatYAML:
+		if properties != nil {
+			v = projectAll(items, properties, isList)
+		}
+		b, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+	case formatList:
+		// a single item with a table, a summary, is listed row by row
+		if headers != nil && !isList {
+			h, r, err := selectColumns(items, isList, headers, rows, properties)
+			if err != nil {
+				return err
+			}
+			fmt.Print(listRows(h, r))
+			return nil
+		}
+
+		if properties != nil {
+			p, err := fieldsOf(items, properties)
+			if err != nil {
+				return err
+			}
+			properties = p
+		}
+		if isList {
+			fmt.Print(output.FormatItemsAsList(v, properties))
+		} else {
+			fmt.Print(output.FormatItemAsList(v, properties))
+		}
+	case formatTable, formatCSV, formatTSV:
+		h, r, err := selectColumns(items, isList, headers, rows, properties)
+		if err != nil {
+			return err
+		}
+		if c.Bool("no-headers") {
+			h = nil
+		}
+
+		switch format {
+		case formatCSV:
+			w := csv.NewWriter(os.Stdout)
+			if h != nil {
+				_ = w.Write(h)
+			}
+			_ = w.WriteAll(r)
+			return w.Error()
+		case formatTSV:
+			if h != nil {
+				fmt.Println(tsvRow(h))
+			}
+			for _, i := range r {
+				fmt.Println(tsvRow(i))
+			}
+		default:
+			fmt.Print(output.FormatTable(r, h))
+		}
+	default:
+		return errors.New("unsupported format " + format + " (" + strings.Join(formats, ", ") + ")")
+	}
+
+	return nil
+}
+
+// itemsOf returns the items of a slice, or the value itself
+func itemsOf(v interface{}) ([]interface{}, bool) {
+	r := reflect.ValueOf(v)
+	for r.Kind() == reflect.Ptr && !r.IsNil() && r.Elem().Kind() == reflect.Slice {
+		r = r.Elem()
+	}
+	if r.Kind() != reflect.Slice {
+		return []interface{}{v}, false
+	}
+
+	items := make([]interface{}, 0, r.Len())
+	for i := 0; i < r.Len(); i++ {
+		items = append(items, r.Index(i).Interface())
+	}
+	return items, true
+}
+
+func splitProperties(s string) []string {
+	if s == "" {
+		return nil
+	}
+	p := []string{}
+	for _, i := range strings.Split(s, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			p = append(p, i)
+		}
+	}
+	return p
+}
+
+// printTemplate will execute a go template for every item
+func printTemplate(text string, items []interface{}) error {
+	t, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range items {
+		b := bytes.Buffer{}
+		err = t.Execute(&b, i)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+			b.WriteByte('\n')
+		}
+		_, _ = os.Stdout.Write(b.Bytes())
+	}
+	return nil
+}
+
+// selectColumns will pick the columns named by properties. a property names
+// a header, or a field of the items when rows describe them one to one
+func selectColumns(items []interface{}, isList bool, headers []string, rows [][]string, properties []string) ([]string, [][]string, error) {
+	perItem := isList && len(items) == len(rows)
+
+	if headers == nil {
+		headers = properties
+		if headers == nil && len(items) > 0 {
+			headers = fieldNames(items[0])
+		}
+		rows = make([][]string, len(items))
+		for i, item := range items {
+			rows[i] = make([]string, len(headers))
+			for j, h := range headers {
+				f, ok := field(item, h)
+				if !ok {
+					return nil, nil, errors.New("unknown property " + h)
+				}
+				rows[i][j] = formatValue(f)
+			}
+		}
+		return headers, rows, nil
+	}
+	if properties == nil {
+		return headers, rows, nil
+	}
+
+	index := map[string]int{}
+	for i, h := range headers {
+		index[normalizeProperty(h)] = i
+	}
+
+	selected := make([][]string, len(rows))
+	for _, p := range properties {
+		i, isHeader := index[normalizeProperty(p)]
+		if !isHeader && !(perItem && hasField(items, p)) {
+			return nil, nil, errors.New("unknown property " + p + " (" + strings.Join(headers, ", ") + ")")
+		}
+
+		for r := range rows {
+			switch {
+			case isHeader && i < len(rows[r]):
+				selected[r] = append(selected[r], rows[r][i])
+			case isHeader:
+				selected[r] = append(selected[r], "")
+			default:
+				f, _ := field(items[r], p)
+				selected[r] = append(selected[r], formatValue(f))
+			}
+		}
+	}
+
+	return properties, selected, nil
+}
+
+// hasField checks whether the items have a field. there is nothing to check
+// without items
+func hasField(items []interface{}, name string) bool {
+	if len(items) == 0 {
+		return true
+	}
+	_, ok := field(items[0], name)
+	return ok
+}
+
+// normalizeProperty lets "VRF Group", "vrf-group" and "VrfGroup" match
+func normalizeProperty(s string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(s))
+}
+
+// structValue returns the struct behind v
+func structValue(v interface{}) (reflect.Value, bool) {
+	r := reflect.ValueOf(v)
+	for r.Kind() == reflect.Ptr || r.Kind() == reflect.Interface {
+		if r.IsNil() {
+			return r, false
+		}
+		r = r.Elem()
+	}
+	return r, r.Kind() == reflect.Struct
+}
+
+// fieldNames returns the exported fields of a struct
+func fieldNames(v interface{}) []string {
+	r, ok := structValue(v)
+	if !ok {
+		return nil
+	}
+
+	n := []string{}
+	for i := 0; i < r.NumField(); i++ {
+		if r.Type().Field(i).IsExported() {
+			n = append(n, r.Type().Field(i).Name)
+		}
+	}
+	return n
+}
+
+// field returns a struct field by its name or json name, ignoring case
+func field(v interface{}, name string) (interface{}, bool) {
+	f, _, ok := lookupField(v, name)
+	return f, ok
+}
+
+// fieldsOf returns the struct field names of properties, which the list
+// format needs spelled exactly
+func fieldsOf(items []interface{}, properties []string) ([]string, error) {
+	if len(items) == 0 {
+		return properties, nil
+	}
+
+	r, ok := structValue(items[0])
+	if !ok {
+		return properties, nil
+	}
+
+	n := []string{}
+	for _, p := range properties {
+		_, key, ok := lookupField(items[0], p)
+		if !ok {
+			return nil, errors.New("unknown property " + p)
+		}
+		for i := 0; i < r.NumField(); i++ {
+			t := r.Type().Field(i)
+			if t.Name == key || strings.Split(t.Tag.Get("json"), ",")[0] == key {
+				n = append(n, t.Name)
+				break
+			}
+		}
+	}
+	return n, nil
+}
+
+func lookupField(v interface{}, name string) (interface{}, string, bool) {
+	r, ok := structValue(v)
+	if !ok {
+		return nil, "", false
+	}
+
+	name = normalizeProperty(name)
+	for i := 0; i < r.NumField(); i++ {
+		t := r.Type().Field(i)
+		if !t.IsExported() {
+			continue
+		}
+		key := strings.Split(t.Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			key = t.Name
+		}
+		if normalizeProperty(t.Name) == name || normalizeProperty(key) == name {
+			return r.Field(i).Interface(), key, true
+		}
+	}
+	return nil, "", false
+}
+
+// formatValue will print a field for a table
+func formatValue(v interface{}) string {
+	switch i := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return i
+	case time.Time:
+		if i.IsZero() {
+			return ""
+		}
+		return i.Format(time.RFC3339)
+	case []string:
+		return strings.Join(i, ",")
+	case fmt.Stringer:
+		return i.String()
+	}
+
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if r.IsNil() {
+			return ""
+		}
+		return formatValue(r.Elem().Interface())
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// ordered type
+// the selected fields of an item, marshalled in the order they were selected
+type ordered struct {
+	keys   []string
+	values []interface{}
+}
+
+// MarshalJSON implements json.Marshaler
+func (o ordered) MarshalJSON() ([]byte, error) {
+	b := bytes.Buffer{}
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// project will keep the named fields of an item, under their json names
+func project(v interface{}, properties []string) interface{} {
+	o := ordered{}
+	for _, p := range properties {
+		f, key, ok := lookupField(v, p)
+		if !ok {
+			continue
+		}
+		o.keys = append(o.keys, key)
+		o.values = append(o.values, f)
+	}
+	return o
+}
+
+func projectAll(items []interface{}, properties []string, isList bool) interface{} {
+	p := make([]interface{}, 0, len(items))
+	for _, i := range items {
+		p = append(p, project(i, properties))
+	}
+	if !isList {
+		return p[0]
+	}
+	return p
+}
+
+// toYAML will marshal v as yaml using its json field names and order. json
+// is yaml, so it is decoded into a node and written out again in block style
+func toYAML(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	n := yaml.Node{}
+	err = yaml.Unmarshal(b, &n)
+	if err != nil {
+		return nil, err
+	}
+	blockStyle(&n)
+
+	return yaml.Marshal(&n)
+}
+
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// listRows will print table rows the way the list format prints items
+func listRows(headers []string, rows [][]string) string {
+	width := 0
+	for _, h := range headers {
+		if len(h) > width {
+			width = len(h)
+		}
+	}
+
+	b := strings.Builder{}
+	for i, r := range rows {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for j, h := range headers {
+			value := ""
+			if j < len(r) {
+				value = r[j]
+			}
+			b.WriteString(fmt.Sprintf("%-*s : %s\n", width, h, value))
+		}
+	}
+	return b.String()
+}
+
+// tsvRow will join a row with tabs, which cells may not contain
+func tsvRow(row []string) string {
+	cells := make([]string, len(row))
+	r := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	for i, c := range row {
+		cells[i] = r.Replace(c)
+	}
+	return strings.Join(cells, "\t")
+}
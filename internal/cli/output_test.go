@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	VrfGroup string   `json:"vrf_group"`
+	Tags     []string `json:"tags"`
+	hidden   string
+}
+
+func TestSplitProperties(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "ID", want: []string{"ID"}},
+		{in: " ID, Name ,,VRF Group ", want: []string{"ID", "Name", "VRF Group"}},
+		{in: ",", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := splitProperties(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitProperties(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeProperty(t *testing.T) {
+	for _, in := range []string{"VRF Group", "vrf-group", "VrfGroup", "vrf_group"} {
+		if got := normalizeProperty(in); got != "vrfgroup" {
+			t.Errorf("normalizeProperty(%q) = %q, want %q", in, got, "vrfgroup")
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	name := "web"
+	var none *string
+
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{in: nil, want: ""},
+		{in: "web", want: "web"},
+		{in: 42, want: "42"},
+		{in: true, want: "true"},
+		{in: []string{"a", "b"}, want: "a,b"},
+		{in: time.Time{}, want: ""},
+		{in: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), want: "2021-03-04T05:06:07Z"},
+		{in: &name, want: "web"},
+		{in: none, want: ""},
+		{in: map[string]int{"a": 1}, want: `{"a":1}`},
+		{in: []int{1, 2}, want: "[1,2]"},
+	}
+
+	for _, tt := range tests {
+		if got := formatValue(tt.in); got != tt.want {
+			t.Errorf("formatValue(%#v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestField(t *testing.T) {
+	item := testItem{ID: 1, Name: "web", VrfGroup: "prod", hidden: "x"}
+
+	tests := []struct {
+		name string
+		want interface{}
+		ok   bool
+	}{
+		{name: "ID", want: 1, ok: true},
+		{name: "name", want: "web", ok: true},
+		{name: "VRF Group", want: "prod", ok: true},
+		{name: "vrf_group", want: "prod", ok: true},
+		{name: "hidden", ok: false},
+		{name: "missing", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := field(&item, tt.name)
+		if ok != tt.ok || (ok && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("field(%q) = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if got := fieldNames(item); !reflect.DeepEqual(got, []string{"ID", "Name", "VrfGroup", "Tags"}) {
+		t.Errorf("fieldNames = %v", got)
+	}
+	if got, err := fieldsOf([]interface{}{item}, []string{"vrf_group", "id"}); err != nil || !reflect.DeepEqual(got, []string{"VrfGroup", "ID"}) {
+		t.Errorf("fieldsOf = %v, %v", got, err)
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	items := []interface{}{
+		testItem{ID: 1, Name: "web", VrfGroup: "prod", Tags: []string{"a"}},
+		testItem{ID: 2, Name: "db"},
+	}
+	headers := []string{"ID", "Name", "VRF Group"}
+	rows := [][]string{{"1", "web", "prod"}, {"2", "db"}}
+
+	tests := []struct {
+		name       string
+		isList     bool
+		headers    []string
+		rows       [][]string
+		properties []string
+		wantH      []string
+		wantR      [][]string
+		wantErr    bool
+	}{
+		{
+			name:    "all columns",
+			isList:  true,
+			headers: headers,
+			rows:    rows,
+			wantH:   headers,
+			wantR:   rows,
+		},
+		{
+			name:       "headers, short rows and fields",
+			isList:     true,
+			headers:    headers,
+			rows:       rows,
+			properties: []string{"vrf-group", "tags", "ID"},
+			wantH:      []string{"vrf-group", "tags", "ID"},
+			wantR:      [][]string{{"prod", "a", "1"}, {"", "", "2"}},
+		},
+		{
+			name:       "fields need a row per item",
+			isList:     false,
+			headers:    headers,
+			rows:       rows[:1],
+			properties: []string{"tags"},
+			wantErr:    true,
+		},
+		{
+			name:       "unknown",
+			isList:     true,
+			headers:    headers,
+			rows:       rows,
+			properties: []string{"bogus"},
+			wantErr:    true,
+		},
+		{
+			name:       "no table",
+			isList:     true,
+			properties: []string{"name", "id"},
+			wantH:      []string{"name", "id"},
+			wantR:      [][]string{{"web", "1"}, {"db", "2"}},
+		},
+		{
+			name:   "no table or properties",
+			isList: true,
+			wantH:  []string{"ID", "Name", "VrfGroup", "Tags"},
+			wantR:  [][]string{{"1", "web", "prod", "a"}, {"2", "db", "", ""}},
+		},
+		{
+			name:       "no table, unknown",
+			isList:     true,
+			properties: []string{"bogus"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		h, r, err := selectColumns(items, tt.isList, tt.headers, tt.rows, tt.properties)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: selectColumns error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(h, tt.wantH) || !reflect.DeepEqual(r, tt.wantR) {
+			t.Errorf("%s: selectColumns = %v, %v, want %v, %v", tt.name, h, r, tt.wantH, tt.wantR)
+		}
+	}
+}
+
+func TestListRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		rows    [][]string
+		want    string
+	}{
+		{name: "nothing", headers: []string{"ID"}, want: ""},
+		{
+			name:    "padded",
+			headers: []string{"ID", "Network"},
+			rows:    [][]string{{"1", "10.0.0.0/24"}, {"2"}},
+			want:    "ID      : 1\nNetwork : 10.0.0.0/24\n\nID      : 2\nNetwork : \n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := listRows(tt.headers, tt.rows); got != tt.want {
+			t.Errorf("%s: listRows = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTSVRow(t *testing.T) {
+	tests := []struct {
+		row  []string
+		want string
+	}{
+		{row: nil, want: ""},
+		{row: []string{"a", "b"}, want: "a\tb"},
+		{row: []string{"a\tb", "c\r\nd", ""}, want: "a b\tc  d\t"},
+	}
+
+	for _, tt := range tests {
+		if got := tsvRow(tt.row); got != tt.want {
+			t.Errorf("tsvRow(%q) = %q, want %q", tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	item := testItem{ID: 1, Name: "web", VrfGroup: "prod"}
+
+	b, err := project(item, []string{"VRF Group", "bogus", "id"}).(ordered).MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"vrf_group":"prod","id":1}`; string(b) != want {
+		t.Errorf("project = %s, want %s", b, want)
+	}
+}
+
+func TestItemsOf(t *testing.T) {
+	list := []testItem{{ID: 1}, {ID: 2}}
+
+	tests := []struct {
+		name   string
+		in     interface{}
+		len    int
+		isList bool
+	}{
+		{name: "slice", in: list, len: 2, isList: true},
+		{name: "pointer to slice", in: &list, len: 2, isList: true},
+		{name: "item", in: list[0], len: 1, isList: false},
+		{name: "pointer to item", in: &list[0], len: 1, isList: false},
+	}
+
+	for _, tt := range tests {
+		items, isList := itemsOf(tt.in)
+		if len(items) != tt.len || isList != tt.isList {
+			t.Errorf("%s: itemsOf = %d items, %v, want %d, %v", tt.name, len(items), isList, tt.len, tt.isList)
+		}
+	}
+}
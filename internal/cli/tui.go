@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"log"
+
+	device42 "github.com/chopnico/device42-go"
+	"github.com/chopnico/device42-go/tui"
+
+	"github.com/urfave/cli/v2"
+)
+
+func tuiCommand(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:  "tui",
+		Usage: "browse and edit ipam interactively",
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			t := tui.New(api)
+			// logging to the terminal would draw over the interface
+			api.InfoLogger(log.New(t, "", 0)).
+				DebugLogger(log.New(t, "", 0))
+
+			return t.Run()
+		},
+	}
+}
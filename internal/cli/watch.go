@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"os/signal"
+	"time"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/urfave/cli/v2"
+)
+
+func watchCommands(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:  "watch",
+		Usage: "stream changes as json lines",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "ipam",
+				Usage: "stream ipam changes",
+				Subcommands: []*cli.Command{
+					watchResource(app, "ip", device42.WatchResourceIPs),
+					watchResource(app, "subnet", device42.WatchResourceSubnets),
+				},
+			},
+		},
+	}
+}
+
+func watchResource(app *cli.App, name, resource string) *cli.Command {
+	flags := []cli.Flag{
+		&cli.DurationFlag{
+			Name:     "interval",
+			Usage:    "`INTERVAL` between polls",
+			Value:    30 * time.Second,
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "subnet-id",
+			Usage:    "only watch subnet `ID`",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     "vrf-group-id",
+			Usage:    "only watch vrf group `ID`",
+			Required: false,
+		},
+		&cli.BoolFlag{
+			Name:     "initial",
+			Usage:    "print an added event for every existing " + name,
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  name,
+		Usage: "stream " + name + " changes",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			api := c.Context.Value(device42.APIContextKey("api")).(*device42.API)
+
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			events, err := api.Watch(ctx, resource, device42.WatchFilter{
+				SubnetID:   c.Int("subnet-id"),
+				VrfGroupID: c.Int("vrf-group-id"),
+				Interval:   c.Duration("interval"),
+				Initial:    c.Bool("initial"),
+			})
+			if err != nil {
+				return err
+			}
+
+			e := json.NewEncoder(os.Stdout)
+			for i := range events {
+				err = e.Encode(i)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
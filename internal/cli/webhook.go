@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chopnico/device42-go/webhook"
+
+	"github.com/urfave/cli/v2"
+)
+
+func webhookCommands(app *cli.App) *cli.Command {
+	return &cli.Command{
+		Name:  "webhook",
+		Usage: "device42 webhook receiver",
+		Subcommands: []*cli.Command{
+			webhookServe(app),
+		},
+	}
+}
+
+func webhookServe(app *cli.App) *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "listen",
+			Usage:    "`ADDRESS` to listen on",
+			Value:    ":8042",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "path",
+			Usage:    "url `PATH` to receive webhooks on",
+			Value:    "/",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "token",
+			Usage:    "require `TOKEN` in the " + webhook.TokenHeader + " header",
+			EnvVars:  []string{"DEVICE42_WEBHOOK_TOKEN"},
+			Required: false,
+		},
+		&cli.StringSliceFlag{
+			Name:     "event",
+			Usage:    "only handle event `TYPE` (e.g. ip.created, subnet.*)",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "forward",
+			Usage:    "post events as json to `URL`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "tls-cert",
+			Usage:    "tls certificate `FILE`",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     "tls-key",
+			Usage:    "tls key `FILE`",
+			Required: false,
+		},
+	}
+
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "receive webhooks and print or forward their events",
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			if (c.String("tls-cert") == "") != (c.String("tls-key") == "") {
+				_ = cli.ShowCommandHelp(c, "serve")
+				return errors.New("you must supply both a tls certificate and key")
+			}
+
+			var mu sync.Mutex
+			enc := json.NewEncoder(os.Stdout)
+			forward := c.String("forward")
+			client := &http.Client{Timeout: 30 * time.Second}
+
+			handle := func(ctx context.Context, e webhook.Event) error {
+				mu.Lock()
+				err := enc.Encode(e)
+				mu.Unlock()
+				if err != nil || forward == "" {
+					return err
+				}
+				return forwardEvent(ctx, client, forward, e)
+			}
+
+			// one handler for all patterns, so overlapping patterns do not
+			// handle an event twice
+			events := c.StringSlice("event")
+			d := webhook.NewDispatcher().On("*", func(ctx context.Context, e webhook.Event) error {
+				if len(events) == 0 {
+					return handle(ctx, e)
+				}
+				for _, i := range events {
+					if webhook.Match(i, e) {
+						return handle(ctx, e)
+					}
+				}
+				return nil
+			})
+
+			mux := http.NewServeMux()
+			mux.Handle(c.String("path"), webhook.NewHandler(d).Token(c.String("token")))
+			srv := &http.Server{
+				Addr:              c.String("listen"),
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdown)
+			}()
+
+			fmt.Fprintln(os.Stderr, "listening for webhooks on "+srv.Addr)
+
+			var err error
+			if c.String("tls-cert") != "" {
+				err = srv.ListenAndServeTLS(c.String("tls-cert"), c.String("tls-key"))
+			} else {
+				err = srv.ListenAndServe()
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+	}
+}
+
+// forwardEvent will post an event as json to a url
+func forwardEvent(ctx context.Context, client *http.Client, url string, e webhook.Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return errors.New("forwarding event to " + url + " failed with status " + strconv.Itoa(resp.StatusCode))
+	}
+
+	return nil
+}
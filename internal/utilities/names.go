@@ -0,0 +1,18 @@
+package utilities
+
+import "strconv"
+
+// UniqueNames hands out names which have not been handed out before. a taken
+// name is numbered, e.g. web-2, and a numbered name may itself be taken, e.g.
+// by a label of "web-2"
+type UniqueNames map[string]bool
+
+// Name returns n, or the first free numbered n when n is taken
+func (u UniqueNames) Name(n string) string {
+	name := n
+	for i := 2; u[name]; i++ {
+		name = n + "-" + strconv.Itoa(i)
+	}
+	u[name] = true
+	return name
+}
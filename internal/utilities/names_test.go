@@ -0,0 +1,29 @@
+package utilities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUniqueNames(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{in: []string{"web", "db"}, want: []string{"web", "db"}},
+		{in: []string{"web", "web", "web"}, want: []string{"web", "web-2", "web-3"}},
+		{in: []string{"web", "web-2", "web"}, want: []string{"web", "web-2", "web-3"}},
+		{in: []string{"web-2", "web", "web"}, want: []string{"web-2", "web", "web-3"}},
+	}
+
+	for _, tt := range tests {
+		u := UniqueNames{}
+		got := []string{}
+		for _, n := range tt.in {
+			got = append(got, u.Name(n))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Name(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -29,10 +29,9 @@ func PostParameters(i interface{}) url.Values {
 				if !f.IsZero() {
 					switch f.Kind() {
 					case reflect.Slice:
-						if _, ok := f.Value().([]string); ok {
-							for _, i := range f.Value().([]string) {
-								d.Set(jtags[0], fmt.Sprintf("%v", i))
-							}
+						// device42 takes lists, like tags, comma separated
+						if v, ok := f.Value().([]string); ok {
+							d.Set(jtags[0], strings.Join(v, ","))
 						}
 					default:
 						d.Set(jtags[0], fmt.Sprintf("%v", f.Value()))
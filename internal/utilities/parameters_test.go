@@ -0,0 +1,41 @@
+package utilities
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPostParameters(t *testing.T) {
+	type item struct {
+		Name     string   `json:"name" methods:"post"`
+		Number   int      `json:"number,omitempty" methods:"post"`
+		Tags     []string `json:"tags" methods:"post"`
+		Hidden   string   `json:"hidden"`
+		Computed string   `json:"computed" methods:"get"`
+	}
+
+	tests := []struct {
+		name string
+		in   item
+		want url.Values
+	}{
+		{name: "empty", in: item{}, want: url.Values{}},
+		{
+			name: "values",
+			in:   item{Name: "web", Number: 100, Hidden: "x", Computed: "y"},
+			want: url.Values{"name": {"web"}, "number": {"100"}},
+		},
+		{
+			name: "tags are comma separated",
+			in:   item{Name: "web", Tags: []string{"a", "b"}},
+			want: url.Values{"name": {"web"}, "tags": {"a,b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := PostParameters(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: PostParameters = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package device42
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ParseAddress will parse an ipv4 or ipv6 address in either compressed or
+// expanded form. ipv4-mapped ipv6 addresses are returned as ipv4
+func ParseAddress(s string) (netip.Addr, error) {
+	a, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	return a.Unmap(), nil
+}
+
+// ParsePrefix will parse a network address and mask bits into a prefix,
+// making sure the mask bits are valid for the address family
+func ParsePrefix(network string, maskBits int) (netip.Prefix, error) {
+	a, err := ParseAddress(network)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	if maskBits < 0 || maskBits > a.BitLen() {
+		return netip.Prefix{}, errors.New("invalid mask bits " + strconv.Itoa(maskBits) + " for network " + network)
+	}
+
+	return netip.PrefixFrom(a, maskBits).Masked(), nil
+}
+
+// NormalizeAddress will return the canonical (compressed) form of an address.
+// the input is returned untouched if it is not a valid address
+func NormalizeAddress(s string) string {
+	a, err := ParseAddress(s)
+	if err != nil {
+		return s
+	}
+
+	return a.String()
+}
+
+// ExpandAddress will return the expanded form of an address.
+// the input is returned untouched if it is not a valid address
+func ExpandAddress(s string) string {
+	a, err := ParseAddress(s)
+	if err != nil {
+		return s
+	}
+
+	return a.StringExpanded()
+}
+
+// EqualAddresses will compare two addresses regardless of their notation
+func EqualAddresses(a, b string) bool {
+	x, err := ParseAddress(a)
+	if err != nil {
+		return a == b
+	}
+	y, err := ParseAddress(b)
+	if err != nil {
+		return false
+	}
+
+	return x == y
+}
@@ -0,0 +1,191 @@
+package device42
+
+import (
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "10.0.0.1", want: "10.0.0.1"},
+		{in: " 10.0.0.1 ", want: "10.0.0.1"},
+		{in: "::ffff:10.0.0.1", want: "10.0.0.1"},
+		{in: "2001:db8::1", want: "2001:db8::1"},
+		{in: "2001:0db8:0000:0000:0000:0000:0000:0001", want: "2001:db8::1"},
+		{in: "", wantErr: true},
+		{in: "10.0.0.256", wantErr: true},
+		{in: "10.0.0.0/24", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAddress(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAddress(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("ParseAddress(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		network  string
+		maskBits int
+		want     string
+		wantErr  bool
+	}{
+		{network: "10.0.0.0", maskBits: 24, want: "10.0.0.0/24"},
+		{network: "10.0.0.7", maskBits: 24, want: "10.0.0.0/24"},
+		{network: "10.0.0.0", maskBits: 0, want: "0.0.0.0/0"},
+		{network: "10.0.0.1", maskBits: 32, want: "10.0.0.1/32"},
+		{network: "2001:db8::", maskBits: 64, want: "2001:db8::/64"},
+		{network: "2001:db8::1", maskBits: 128, want: "2001:db8::1/128"},
+		{network: "10.0.0.0", maskBits: 33, wantErr: true},
+		{network: "10.0.0.0", maskBits: -1, wantErr: true},
+		{network: "2001:db8::", maskBits: 129, wantErr: true},
+		{network: "bogus", maskBits: 24, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePrefix(tt.network, tt.maskBits)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePrefix(%q, %d) error = %v, wantErr %v", tt.network, tt.maskBits, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("ParsePrefix(%q, %d) = %s, want %s", tt.network, tt.maskBits, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "2001:0db8:0000:0000:0000:0000:0000:0001", want: "2001:db8::1"},
+		{in: "10.0.0.1", want: "10.0.0.1"},
+		{in: "not an address", want: "not an address"},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAddress(tt.in); got != tt.want {
+			t.Errorf("NormalizeAddress(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "2001:db8::1", want: "2001:0db8:0000:0000:0000:0000:0000:0001"},
+		{in: "10.0.0.1", want: "10.0.0.1"},
+		{in: "not an address", want: "not an address"},
+	}
+
+	for _, tt := range tests {
+		if got := ExpandAddress(tt.in); got != tt.want {
+			t.Errorf("ExpandAddress(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEqualAddresses(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "2001:db8::1", b: "2001:0db8:0000:0000:0000:0000:0000:0001", want: true},
+		{a: "10.0.0.1", b: "::ffff:10.0.0.1", want: true},
+		{a: "10.0.0.1", b: "10.0.0.2", want: false},
+		{a: "10.0.0.1", b: "bogus", want: false},
+		{a: "bogus", b: "bogus", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := EqualAddresses(tt.a, tt.b); got != tt.want {
+			t.Errorf("EqualAddresses(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestIPAddr(t *testing.T) {
+	tests := []struct {
+		name    string
+		ip      IP
+		want    string
+		wantErr bool
+	}{
+		{name: "address", ip: IP{Address: "10.0.0.1"}, want: "10.0.0.1"},
+		{name: "posted address wins", ip: IP{Address: "10.0.0.1", IPAddress: "10.0.0.2"}, want: "10.0.0.2"},
+		{name: "ipv6", ip: IP{IPAddress: "2001:db8::0001"}, want: "2001:db8::1"},
+		{name: "empty", ip: IP{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.ip.Addr()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Addr() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("%s: Addr() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubnetRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		subnet    Subnet
+		wantBegin string
+		wantEnd   string
+		wantErr   bool
+	}{
+		{
+			name:      "network bounds",
+			subnet:    Subnet{Network: "10.0.0.0", MaskBits: 24},
+			wantBegin: "10.0.0.0",
+			wantEnd:   "10.0.0.255",
+		},
+		{
+			name:      "range",
+			subnet:    Subnet{Network: "10.0.0.0", MaskBits: 24, RangeBegin: "10.0.0.10", RangeEnd: "10.0.0.20"},
+			wantBegin: "10.0.0.10",
+			wantEnd:   "10.0.0.20",
+		},
+		{
+			name:      "ipv6",
+			subnet:    Subnet{Network: "2001:db8::", MaskBits: 126},
+			wantBegin: "2001:db8::",
+			wantEnd:   "2001:db8::3",
+		},
+		{
+			name:    "invalid range",
+			subnet:  Subnet{Network: "10.0.0.0", MaskBits: 24, RangeBegin: "bogus"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		begin, end, err := tt.subnet.Range()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Range() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if begin.String() != tt.wantBegin || end.String() != tt.wantEnd {
+			t.Errorf("%s: Range() = %s - %s, want %s - %s", tt.name, begin, end, tt.wantBegin, tt.wantEnd)
+		}
+	}
+}
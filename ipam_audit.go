@@ -0,0 +1,284 @@
+package device42
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"github.com/chopnico/device42-go/ipmath"
+)
+
+// audit rules
+const (
+	AuditRuleOverlappingSubnets = "overlapping-subnets"
+	AuditRuleIPOutsideRange     = "ip-outside-range"
+	AuditRuleDuplicateAddress   = "duplicate-address"
+	AuditRuleOrphanedSubnet     = "orphaned-subnet"
+	AuditRuleMissingVLAN        = "missing-vlan"
+	AuditRuleInvalidAddress     = "invalid-address"
+)
+
+// audit severities
+const (
+	AuditSeverityError   = "error"
+	AuditSeverityWarning = "warning"
+)
+
+// AuditRules describes every audit rule
+var AuditRules = map[string]string{
+	AuditRuleOverlappingSubnets: "subnets within the same vrf group overlap without being parent and child",
+	AuditRuleIPOutsideRange:     "ip is recorded outside of its subnet's range",
+	AuditRuleDuplicateAddress:   "address is recorded more than once within the same vrf group",
+	AuditRuleOrphanedSubnet:     "subnet references a parent subnet that no longer exists",
+	AuditRuleMissingVLAN:        "subnet references a vlan that no longer exists",
+	AuditRuleInvalidAddress:     "subnet or ip has an address that cannot be parsed",
+}
+
+// AuditFinding type
+type AuditFinding struct {
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	ObjectType string `json:"object_type"`
+	ObjectID   int    `json:"object_id"`
+	RelatedIDs []int  `json:"related_ids,omitempty"`
+	VrfGroupID int    `json:"vrf_group_id"`
+}
+
+// Audit will check subnets, ips and vlans for overlapping subnets, ips outside
+// of their subnet's range, duplicate addresses, orphaned child subnets and
+// references to missing vlans. vlan checks are skipped when vlans is nil
+func Audit(subnets []Subnet, ips []IP, vlans []VLAN) []AuditFinding {
+	findings := []AuditFinding{}
+
+	findings = append(findings, auditSubnets(subnets, vlans)...)
+	findings = append(findings, auditIPs(subnets, ips)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Rule != findings[j].Rule {
+			return findings[i].Rule < findings[j].Rule
+		}
+		return findings[i].ObjectID < findings[j].ObjectID
+	})
+
+	return findings
+}
+
+// AuditIPAM will fetch all subnets, ips and vlans and audit them
+func (api *API) AuditIPAM() ([]AuditFinding, error) {
+	subnets, err := api.GetSubnets()
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := api.GetIPs()
+	if err != nil {
+		return nil, err
+	}
+
+	vlans, err := api.GetVLANs()
+	if err != nil {
+		return nil, err
+	}
+
+	return Audit(*subnets, *ips, *vlans), nil
+}
+
+func auditSubnets(subnets []Subnet, vlans []VLAN) []AuditFinding {
+	findings := []AuditFinding{}
+
+	byID := make(map[int]*Subnet, len(subnets))
+	for i := range subnets {
+		byID[subnets[i].SubnetID] = &subnets[i]
+	}
+
+	vlanIDs := make(map[int]bool, len(vlans))
+	for _, v := range vlans {
+		vlanIDs[v.VlanID] = true
+	}
+
+	type parsed struct {
+		subnet *Subnet
+		prefix netip.Prefix
+	}
+	groups := make(map[int][]parsed)
+
+	for i := range subnets {
+		s := &subnets[i]
+
+		if s.ParentSubnetID != 0 && byID[s.ParentSubnetID] == nil {
+			findings = append(findings, AuditFinding{
+				Rule:       AuditRuleOrphanedSubnet,
+				Severity:   AuditSeverityWarning,
+				Message:    fmt.Sprintf("subnet %s references missing parent subnet id %d", subnetLabel(s), s.ParentSubnetID),
+				ObjectType: "subnet",
+				ObjectID:   s.SubnetID,
+				RelatedIDs: []int{s.ParentSubnetID},
+				VrfGroupID: s.VrfGroupID,
+			})
+		}
+
+		if vlans != nil && s.ParentVlanID != 0 && !vlanIDs[s.ParentVlanID] {
+			findings = append(findings, AuditFinding{
+				Rule:       AuditRuleMissingVLAN,
+				Severity:   AuditSeverityWarning,
+				Message:    fmt.Sprintf("subnet %s references missing vlan id %d", subnetLabel(s), s.ParentVlanID),
+				ObjectType: "subnet",
+				ObjectID:   s.SubnetID,
+				RelatedIDs: []int{s.ParentVlanID},
+				VrfGroupID: s.VrfGroupID,
+			})
+		}
+
+		p, err := s.Prefix()
+		if err != nil {
+			findings = append(findings, AuditFinding{
+				Rule:       AuditRuleInvalidAddress,
+				Severity:   AuditSeverityError,
+				Message:    fmt.Sprintf("subnet %s has an invalid network: %s", subnetLabel(s), err),
+				ObjectType: "subnet",
+				ObjectID:   s.SubnetID,
+				VrfGroupID: s.VrfGroupID,
+			})
+			continue
+		}
+		groups[s.VrfGroupID] = append(groups[s.VrfGroupID], parsed{subnet: s, prefix: p})
+	}
+
+	// a subnet may legitimately overlap its ancestors
+	isAncestor := func(a, b *Subnet) bool {
+		seen := map[int]bool{}
+		for p := byID[b.ParentSubnetID]; p != nil && !seen[p.SubnetID]; p = byID[p.ParentSubnetID] {
+			if p.SubnetID == a.SubnetID {
+				return true
+			}
+			seen[p.SubnetID] = true
+		}
+		return false
+	}
+
+	for vrfGroupID, group := range groups {
+		// containers before the subnets they hold, so findings are stable
+		sort.SliceStable(group, func(i, j int) bool {
+			if c := group[i].prefix.Addr().Compare(group[j].prefix.Addr()); c != 0 {
+				return c < 0
+			}
+			return group[i].prefix.Bits() < group[j].prefix.Bits()
+		})
+
+		for i := range group {
+			for j := i + 1; j < len(group); j++ {
+				a, b := group[i], group[j]
+				if ipmath.Last(a.prefix).Less(b.prefix.Addr()) {
+					break
+				}
+				if !ipmath.Overlaps(a.prefix, b.prefix) {
+					continue
+				}
+				if a.prefix != b.prefix && (isAncestor(a.subnet, b.subnet) || isAncestor(b.subnet, a.subnet)) {
+					continue
+				}
+
+				findings = append(findings, AuditFinding{
+					Rule:     AuditRuleOverlappingSubnets,
+					Severity: AuditSeverityError,
+					Message: fmt.Sprintf("subnet %s overlaps subnet %s",
+						subnetLabel(a.subnet), subnetLabel(b.subnet)),
+					ObjectType: "subnet",
+					ObjectID:   a.subnet.SubnetID,
+					RelatedIDs: []int{b.subnet.SubnetID},
+					VrfGroupID: vrfGroupID,
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+func auditIPs(subnets []Subnet, ips []IP) []AuditFinding {
+	findings := []AuditFinding{}
+
+	byID := make(map[int]*Subnet, len(subnets))
+	for i := range subnets {
+		byID[subnets[i].SubnetID] = &subnets[i]
+	}
+
+	type key struct {
+		vrfGroupID int
+		addr       netip.Addr
+	}
+	seen := make(map[key][]int)
+	order := []key{}
+
+	for i := range ips {
+		ip := &ips[i]
+
+		a, err := ip.Addr()
+		if err != nil {
+			findings = append(findings, AuditFinding{
+				Rule:       AuditRuleInvalidAddress,
+				Severity:   AuditSeverityError,
+				Message:    fmt.Sprintf("ip id %d has an invalid address: %s", ip.ID, err),
+				ObjectType: "ip",
+				ObjectID:   ip.ID,
+				VrfGroupID: ip.VRFGroupID,
+			})
+			continue
+		}
+
+		vrfGroupID := ip.VRFGroupID
+		if s, ok := byID[ip.SubnetID]; ok {
+			if vrfGroupID == 0 {
+				vrfGroupID = s.VrfGroupID
+			}
+
+			begin, end, err := s.Range()
+			if err == nil && (a.Less(begin) || end.Less(a)) {
+				findings = append(findings, AuditFinding{
+					Rule:     AuditRuleIPOutsideRange,
+					Severity: AuditSeverityError,
+					Message: fmt.Sprintf("ip %s is outside of subnet %s range %s - %s",
+						a, subnetLabel(s), begin, end),
+					ObjectType: "ip",
+					ObjectID:   ip.ID,
+					RelatedIDs: []int{s.SubnetID},
+					VrfGroupID: vrfGroupID,
+				})
+			}
+		}
+
+		k := key{vrfGroupID: vrfGroupID, addr: a}
+		if _, ok := seen[k]; !ok {
+			order = append(order, k)
+		}
+		seen[k] = append(seen[k], ip.ID)
+	}
+
+	for _, k := range order {
+		ids := seen[k]
+		if len(ids) < 2 {
+			continue
+		}
+		findings = append(findings, AuditFinding{
+			Rule:       AuditRuleDuplicateAddress,
+			Severity:   AuditSeverityError,
+			Message:    fmt.Sprintf("address %s is recorded %d times", k.addr, len(ids)),
+			ObjectType: "ip",
+			ObjectID:   ids[0],
+			RelatedIDs: ids[1:],
+			VrfGroupID: k.vrfGroupID,
+		})
+	}
+
+	return findings
+}
+
+// subnetLabel describes a subnet for messages
+func subnetLabel(s *Subnet) string {
+	l := fmt.Sprintf("%s/%d", NormalizeAddress(s.Network), s.MaskBits)
+	if s.Name != "" {
+		l += " (" + s.Name + ")"
+	}
+	return l
+}
@@ -0,0 +1,157 @@
+package device42
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// findingStrings describes findings as rule:object id:related ids
+func findingStrings(findings []AuditFinding) []string {
+	s := []string{}
+	for _, f := range findings {
+		s = append(s, fmt.Sprintf("%s:%d:%v", f.Rule, f.ObjectID, f.RelatedIDs))
+	}
+	return s
+}
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets []Subnet
+		ips     []IP
+		vlans   []VLAN
+		want    []string
+	}{
+		{
+			name: "clean",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 16, VrfGroupID: 1},
+				{SubnetID: 2, Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 1, VrfGroupID: 1, ParentVlanID: 7},
+				{SubnetID: 3, Network: "10.0.1.0", MaskBits: 24, ParentSubnetID: 1, VrfGroupID: 1},
+			},
+			ips: []IP{
+				{ID: 1, Address: "10.0.0.5", SubnetID: 2},
+				{ID: 2, Address: "10.0.1.5", SubnetID: 3},
+			},
+			vlans: []VLAN{{VlanID: 7}},
+			want:  []string{},
+		},
+		{
+			name: "overlapping subnets",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 16, VrfGroupID: 1},
+				{SubnetID: 2, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 1},
+				{SubnetID: 3, Network: "10.0.0.128", MaskBits: 25, ParentSubnetID: 2, VrfGroupID: 1},
+			},
+			want: []string{"overlapping-subnets:1:[2]", "overlapping-subnets:1:[3]"},
+		},
+		{
+			name: "same network under a parent",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 1},
+				{SubnetID: 2, Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 1, VrfGroupID: 1},
+			},
+			want: []string{"overlapping-subnets:1:[2]"},
+		},
+		{
+			name: "other vrf groups and families do not overlap",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 1},
+				{SubnetID: 2, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 2},
+				{SubnetID: 3, Network: "::ffff:10.0.0.0", MaskBits: 24, VrfGroupID: 3},
+				{SubnetID: 4, Network: "2001:db8::", MaskBits: 64, VrfGroupID: 1},
+				{SubnetID: 5, Network: "2001:db8::", MaskBits: 48, VrfGroupID: 1},
+			},
+			want: []string{"overlapping-subnets:5:[4]"},
+		},
+		{
+			name: "ip outside range",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 1, RangeBegin: "10.0.0.10", RangeEnd: "10.0.0.20"},
+				{SubnetID: 2, Network: "2001:db8::", MaskBits: 64, VrfGroupID: 1},
+			},
+			ips: []IP{
+				{ID: 1, Address: "10.0.0.9", SubnetID: 1},
+				{ID: 2, Address: "10.0.0.10", SubnetID: 1},
+				{ID: 3, Address: "10.0.0.20", SubnetID: 1},
+				{ID: 4, Address: "10.0.0.21", SubnetID: 1},
+				{ID: 5, Address: "2001:db8:0:1::1", SubnetID: 2},
+				{ID: 6, Address: "2001:db8::1", SubnetID: 2},
+			},
+			want: []string{"ip-outside-range:1:[1]", "ip-outside-range:4:[1]", "ip-outside-range:5:[2]"},
+		},
+		{
+			name: "duplicate addresses",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 1},
+				{SubnetID: 2, Network: "2001:db8::", MaskBits: 64, VrfGroupID: 1},
+			},
+			ips: []IP{
+				{ID: 1, Address: "10.0.0.5", SubnetID: 1},
+				{ID: 2, Address: "10.0.0.5", SubnetID: 1},
+				{ID: 3, Address: "10.0.0.5", VRFGroupID: 2},
+				{ID: 4, Address: "2001:db8::1", SubnetID: 2},
+				{ID: 5, Address: "2001:0db8::0001", SubnetID: 2},
+			},
+			want: []string{"duplicate-address:1:[2]", "duplicate-address:4:[5]"},
+		},
+		{
+			name: "orphaned subnet",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 9},
+			},
+			want: []string{"orphaned-subnet:1:[9]"},
+		},
+		{
+			name: "missing vlan",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, ParentVlanID: 7},
+				{SubnetID: 2, Network: "10.0.1.0", MaskBits: 24, ParentVlanID: 8},
+			},
+			vlans: []VLAN{{VlanID: 8}},
+			want:  []string{"missing-vlan:1:[7]"},
+		},
+		{
+			name: "vlans not checked",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, ParentVlanID: 7},
+			},
+			vlans: nil,
+			want:  []string{},
+		},
+		{
+			name: "invalid addresses",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "bogus", MaskBits: 24},
+				{SubnetID: 2, Network: "10.0.0.0", MaskBits: 33},
+			},
+			ips: []IP{
+				{ID: 3, Address: "10.0.0.300"},
+			},
+			want: []string{"invalid-address:1:[]", "invalid-address:2:[]", "invalid-address:3:[]"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := findingStrings(Audit(tt.subnets, tt.ips, tt.vlans))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Audit = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuditRulesDescribed(t *testing.T) {
+	for _, r := range []string{
+		AuditRuleOverlappingSubnets,
+		AuditRuleIPOutsideRange,
+		AuditRuleDuplicateAddress,
+		AuditRuleOrphanedSubnet,
+		AuditRuleMissingVLAN,
+		AuditRuleInvalidAddress,
+	} {
+		if AuditRules[r] == "" {
+			t.Errorf("audit rule %s has no description", r)
+		}
+	}
+}
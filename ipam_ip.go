@@ -3,6 +3,7 @@ package device42
 import (
 	"encoding/json"
 	"errors"
+	"net/netip"
 	"net/url"
 	"strconv"
 	"strings"
@@ -27,6 +28,7 @@ type IP struct {
 	Label       string    `json:"label" methods:"post"`
 	LastUpdated time.Time `json:"last_updated"`
 	MacAddress  string    `json:"mac_address"`
+	MAC         string    `json:"macaddress,omitempty" methods:"post"` // inconsistent...
 	MacID       int       `json:"mac_id"`
 	Notes       string    `json:"notes" methods:"post"`
 	Subnet      string    `json:"subnet" methods:"post"`
@@ -44,9 +46,19 @@ type IPs struct {
 	TotalCount int  `json:"total_count"`
 }
 
+// Addr will return the parsed address of the ip. IPAddress is the posted
+// address, so it wins over Address when it is set
+func (ip *IP) Addr() (netip.Addr, error) {
+	if ip.IPAddress != "" {
+		return ParseAddress(ip.IPAddress)
+	}
+	return ParseAddress(ip.Address)
+}
+
 type clearIP struct {
-	Address string `json:"ipaddress" methods:"post"`
-	Clear   string `json:"clear_all" methods:"post"`
+	Address  string `json:"ipaddress" methods:"post"`
+	SubnetID int    `json:"subnet_id" methods:"post"`
+	Clear    string `json:"clear_all" methods:"post"`
 }
 
 // GetIPs will return a list of all IPs
@@ -194,12 +206,20 @@ func (api *API) SuggestIPWithVRFGroupID(vrfGroupID, subnetID int, maskBits int,
 
 // SetIP will create or update an IP
 func (api *API) SetIP(ip *IP) (*IP, error) {
+	if err := normalizeIP(ip); err != nil {
+		return nil, err
+	}
+
 	s := strings.NewReader(utilities.PostParameters(ip).Encode())
 	b, err := api.Do("POST", "/ips/", s)
 	if err != nil {
 		return nil, err
 	}
 
+	if api.IsDryRun() {
+		return ip, nil
+	}
+
 	apiResponse := APIResponse{}
 
 	err = json.Unmarshal(b, &apiResponse)
@@ -222,12 +242,20 @@ func (api *API) SetIP(ip *IP) (*IP, error) {
 
 // UpdateIP will create or update an IP
 func (api *API) UpdateIP(ip *IP) (*IP, error) {
+	if err := normalizeIP(ip); err != nil {
+		return nil, err
+	}
+
 	s := strings.NewReader(utilities.PostParameters(ip).Encode())
 	b, err := api.Do("POST", "/ips/", s)
 	if err != nil {
 		return nil, err
 	}
 
+	if api.IsDryRun() {
+		return ip, nil
+	}
+
 	apiResponse := APIResponse{}
 
 	err = json.Unmarshal(b, &apiResponse)
@@ -248,11 +276,27 @@ func (api *API) UpdateIP(ip *IP) (*IP, error) {
 	return ip, nil
 }
 
+// normalizeIP will validate the address of an ip and make sure the posted
+// address is in its canonical form
+func normalizeIP(ip *IP) error {
+	a, err := ip.Addr()
+	if err != nil {
+		return err
+	}
+	ip.IPAddress = a.String()
+
+	if ip.MAC == "" {
+		ip.MAC = ip.MacAddress
+	}
+
+	return nil
+}
+
 // ClearIP will clear all configurations for a specified IP
 // and will mark the IP as avaliable
 func (api *API) ClearIP(ip string) error {
 	i := clearIP{
-		Address: ip,
+		Address: NormalizeAddress(ip),
 		Clear:   "yes",
 	}
 	s := strings.NewReader(utilities.PostParameters(i).Encode())
@@ -264,6 +308,23 @@ func (api *API) ClearIP(ip string) error {
 	return nil
 }
 
+// ClearIPWithSubnetID will clear an ip by address within a subnet, as the same
+// address may be used in more than one vrf group
+func (api *API) ClearIPWithSubnetID(a string, i int) error {
+	c := clearIP{
+		Address:  NormalizeAddress(a),
+		SubnetID: i,
+		Clear:    "yes",
+	}
+	s := strings.NewReader(utilities.PostParameters(c).Encode())
+	_, err := api.Do("POST", "/ips/", s)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // GetIPByID will return an IP by an ID
 func (api *API) GetIPByID(id int) (*IP, error) {
 	s := "/ips?ip_id=" + strconv.Itoa(id)
@@ -280,6 +341,10 @@ func (api *API) GetIPByID(id int) (*IP, error) {
 		return nil, err
 	}
 
+	if len(ips.List) == 0 {
+		return nil, errors.New("unable to find ip with id " + strconv.Itoa(id))
+	}
+
 	return &ips.List[0], nil
 }
 
@@ -318,6 +383,10 @@ func (api *API) GetIPByAddressWithSubnetID(a string, i int) (*IP, error) {
 		return nil, err
 	}
 
+	if len(ips.List) == 0 {
+		return nil, errors.New("unable to find ip with address " + a + " in subnet id " + strconv.Itoa(i))
+	}
+
 	return &ips.List[0], nil
 }
 
@@ -378,6 +447,15 @@ func (api *API) GetIPsBySubnet(s string) (*[]IP, error) {
 	return &ips.List, nil
 }
 
+// CountIPsBySubnetID will count a list of IPs by their subnet id
+func CountIPsBySubnetID(ips []IP) map[int]int {
+	counts := make(map[int]int)
+	for _, i := range ips {
+		counts[i.SubnetID]++
+	}
+	return counts
+}
+
 // DeleteIP will delete an IP by ID
 func (api *API) DeleteIP(id int) error {
 	_, err := api.Do(
@@ -0,0 +1,204 @@
+package device42
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAllocateRetries     = 5
+	defaultAllocateRetryDelay  = 500 * time.Millisecond
+	defaultAllocateSettleDelay = 250 * time.Millisecond
+)
+
+// leaseOwnerPattern matches an owner token stamped in an ip's notes
+var leaseOwnerPattern = regexp.MustCompile(`\[lease:([^\]]+)\]`)
+
+// AllocateIPOptions type
+type AllocateIPOptions struct {
+	// MaskBits of the suggested ip
+	MaskBits int
+	// Label to set on the allocated ip
+	Label string
+	// Notes to set on the allocated ip, the owner token is appended
+	Notes string
+	// Owner token used to claim the ip, a random one is generated if empty
+	Owner string
+	// Retries is how many times a collision is retried (default 5)
+	Retries int
+	// RetryDelay is the base delay between retries (default 500ms)
+	RetryDelay time.Duration
+	// SettleDelay is how long to wait before verifying ownership (default 250ms)
+	SettleDelay time.Duration
+}
+
+// IPLease type
+// a handle on an ip allocated by AllocateIP
+type IPLease struct {
+	IP    *IP
+	Owner string
+	api   *API
+}
+
+// Release will clear the leased ip if it is still owned by the lease
+func (l *IPLease) Release() error {
+	return l.api.ReleaseIP(l.IP.ID, l.Owner)
+}
+
+// AllocateIP will reserve an ip from a subnet and stamp it with an owner token.
+// the ip is re-read after it is claimed and, if another client claimed the same
+// address, the allocation is retried
+func (api *API) AllocateIP(ctx context.Context, subnetID int, opts AllocateIPOptions) (*IPLease, error) {
+	if opts.Owner == "" {
+		opts.Owner = uuid.New().String()
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = defaultAllocateRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultAllocateRetryDelay
+	}
+	if opts.SettleDelay <= 0 {
+		opts.SettleDelay = defaultAllocateSettleDelay
+	}
+
+	var lease *IPLease
+	claimed, err := retryAllocation(ctx, opts.Retries, opts.RetryDelay, func() (bool, error) {
+		ip, err := api.claimIP(ctx, subnetID, opts)
+		if err != nil || ip == nil {
+			if err == nil && api.IsLoggingDebug() {
+				api.WriteToDebugLog("ip allocation collision on subnet id " + strconv.Itoa(subnetID) + ", retrying")
+			}
+			return false, err
+		}
+		lease = &IPLease{IP: ip, Owner: opts.Owner, api: api}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.New("unable to allocate ip in subnet id " + strconv.Itoa(subnetID) + ": too many collisions")
+	}
+
+	return lease, nil
+}
+
+// ReleaseIP will clear an ip by id if it is owned by the given owner token
+func (api *API) ReleaseIP(id int, owner string) error {
+	ip, err := api.GetIPByID(id)
+	if err != nil {
+		return err
+	}
+
+	if ipLeaseOwner(ip) != owner {
+		return errors.New("ip " + ip.Address + " is not owned by " + owner)
+	}
+
+	return api.ClearIPWithSubnetID(ip.Address, ip.SubnetID)
+}
+
+// claimIP will make a single attempt at claiming an ip. a nil ip with a nil
+// error means the claim collided with another client
+func (api *API) claimIP(ctx context.Context, subnetID int, opts AllocateIPOptions) (*IP, error) {
+	ip, err := api.SuggestIPWithSubnetID(subnetID, opts.MaskBits, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// someone else may have stamped the address between suggest and now
+	current, err := api.GetIPByAddressWithSubnetID(ip.Address, subnetID)
+	if err == nil {
+		if o := ipLeaseOwner(current); o != "" && o != opts.Owner {
+			return nil, nil
+		}
+	}
+
+	ip.Label = opts.Label
+	ip.Notes = leaseNotes(opts.Notes, opts.Owner)
+
+	ip, err = api.SetIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	if api.IsDryRun() {
+		return ip, nil
+	}
+
+	if err := sleepContext(ctx, opts.SettleDelay); err != nil {
+		return nil, err
+	}
+
+	ip, err = api.GetIPByID(ip.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ipLeaseOwner(ip) != opts.Owner {
+		return nil, nil
+	}
+
+	return ip, nil
+}
+
+// ipLeaseOwner returns the owner token stamped on an ip
+func ipLeaseOwner(ip *IP) string {
+	m := leaseOwnerPattern.FindStringSubmatch(ip.Notes)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// leaseNotes appends the owner token to notes
+func leaseNotes(notes, owner string) string {
+	t := "[lease:" + owner + "]"
+	if notes == "" {
+		return t
+	}
+	return notes + " " + t
+}
+
+// retryAllocation will call attempt until it succeeds, at most retries more
+// times. attempt returns false when it collided with another client. every
+// retry waits longer, with jitter so colliding clients spread out
+func retryAllocation(ctx context.Context, retries int, delay time.Duration, attempt func() (bool, error)) (bool, error) {
+	// the global source is not seeded, so every allocator would wait alike
+	jitter := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			d := delay*time.Duration(i) + time.Duration(jitter.Int63n(int64(delay)))
+			if err := sleepContext(ctx, d); err != nil {
+				return false, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		ok, err := attempt()
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// sleepContext sleeps for d or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,246 @@
+package device42
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIPLeaseOwner(t *testing.T) {
+	tests := []struct {
+		notes string
+		want  string
+	}{
+		{notes: "", want: ""},
+		{notes: "web server", want: ""},
+		{notes: "[lease:abc]", want: "abc"},
+		{notes: "web server [lease:host-1:42]", want: "host-1:42"},
+		{notes: "[lease:first] [lease:second]", want: "first"},
+		{notes: "[lease:]", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := ipLeaseOwner(&IP{Notes: tt.notes}); got != tt.want {
+			t.Errorf("ipLeaseOwner(%q) = %q, want %q", tt.notes, got, tt.want)
+		}
+	}
+}
+
+func TestLeaseNotes(t *testing.T) {
+	tests := []struct {
+		notes string
+		owner string
+		want  string
+	}{
+		{notes: "", owner: "abc", want: "[lease:abc]"},
+		{notes: "web server", owner: "abc", want: "web server [lease:abc]"},
+	}
+
+	for _, tt := range tests {
+		got := leaseNotes(tt.notes, tt.owner)
+		if got != tt.want {
+			t.Errorf("leaseNotes(%q, %q) = %q, want %q", tt.notes, tt.owner, got, tt.want)
+		}
+		if o := ipLeaseOwner(&IP{Notes: got}); o != tt.owner {
+			t.Errorf("ipLeaseOwner(leaseNotes(%q, %q)) = %q", tt.notes, tt.owner, o)
+		}
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	if err := sleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("sleepContext = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleepContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("sleepContext with a cancelled context = %v, want %v", err, context.Canceled)
+	}
+}
+
+// leaseServer type
+// a subnet of ips handing out suggestions in order. competitor, when set,
+// claims an address the moment it is posted
+type leaseServer struct {
+	suggestions []string
+	ips         map[string]*IP
+	competitor  func(ip *IP)
+	cleared     []string
+	nextID      int
+}
+
+func (l *leaseServer) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/suggest_ip":
+			if len(l.suggestions) == 0 {
+				http.Error(w, "{}", http.StatusServiceUnavailable)
+				return
+			}
+			a := l.suggestions[0]
+			l.suggestions = l.suggestions[1:]
+			writeJSON(w, map[string]string{"ip": a})
+		case r.Method == "GET" && r.URL.Path == "/ips" && q.Get("ip_id") != "":
+			for _, ip := range l.ips {
+				if strconv.Itoa(ip.ID) == q.Get("ip_id") {
+					writeJSON(w, IPs{List: []IP{*ip}})
+					return
+				}
+			}
+			writeJSON(w, IPs{List: []IP{}})
+		case r.Method == "GET" && r.URL.Path == "/ips":
+			if ip, ok := l.ips[q.Get("address")]; ok {
+				writeJSON(w, IPs{List: []IP{*ip}})
+				return
+			}
+			writeJSON(w, IPs{List: []IP{}})
+		case r.Method == "POST" && r.URL.Path == "/ips/":
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			if r.PostForm.Get("clear_all") == "yes" {
+				l.cleared = append(l.cleared, r.PostForm.Get("ipaddress")+" "+r.PostForm.Get("subnet_id"))
+				writeJSON(w, map[string]interface{}{"code": 0, "msg": []interface{}{"cleared"}})
+				return
+			}
+			a := r.PostForm.Get("ipaddress")
+			ip, ok := l.ips[a]
+			if !ok {
+				l.nextID++
+				ip = &IP{ID: l.nextID, Address: a}
+				l.ips[a] = ip
+			}
+			ip.Label = r.PostForm.Get("label")
+			ip.Notes = r.PostForm.Get("notes")
+			ip.SubnetID, _ = strconv.Atoi(r.PostForm.Get("subnet_id"))
+			if l.competitor != nil {
+				l.competitor(ip)
+			}
+			writeCreated(w, ip.ID)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.Error(w, "{}", http.StatusNotFound)
+		}
+	}
+}
+
+func TestAllocateIP(t *testing.T) {
+	fast := AllocateIPOptions{Owner: "me", Label: "web", Retries: 2, RetryDelay: time.Millisecond, SettleDelay: time.Millisecond}
+
+	tests := []struct {
+		name        string
+		suggestions []string
+		ips         map[string]*IP
+		competitor  func(ip *IP)
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "first suggestion",
+			suggestions: []string{"10.0.0.5"},
+			want:        "10.0.0.5",
+		},
+		{
+			name:        "suggestion already leased",
+			suggestions: []string{"10.0.0.5", "10.0.0.6"},
+			ips:         map[string]*IP{"10.0.0.5": {ID: 50, Address: "10.0.0.5", Notes: "[lease:them]"}},
+			want:        "10.0.0.6",
+		},
+		{
+			name:        "lease taken over before it settled",
+			suggestions: []string{"10.0.0.5", "10.0.0.6"},
+			competitor: func(ip *IP) {
+				if ip.Address == "10.0.0.5" {
+					ip.Notes = "[lease:them]"
+				}
+			},
+			want: "10.0.0.6",
+		},
+		{
+			name:        "too many collisions",
+			suggestions: []string{"10.0.0.5", "10.0.0.6", "10.0.0.7"},
+			competitor:  func(ip *IP) { ip.Notes = "[lease:them]" },
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		l := &leaseServer{suggestions: tt.suggestions, ips: map[string]*IP{}, competitor: tt.competitor, nextID: 100}
+		for a, ip := range tt.ips {
+			l.ips[a] = ip
+		}
+		api := newTestAPI(t, l.handle(t))
+
+		lease, err := api.AllocateIP(context.Background(), 1, fast)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: AllocateIP error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if lease.IP.Address != tt.want || lease.IP.Label != "web" || lease.Owner != "me" || ipLeaseOwner(lease.IP) != "me" {
+			t.Errorf("%s: AllocateIP = %+v, want %s owned by me", tt.name, lease.IP, tt.want)
+		}
+		if n := len(l.suggestions); n != 0 {
+			t.Errorf("%s: %d suggestions left", tt.name, n)
+		}
+	}
+}
+
+func TestAllocateIPCancelled(t *testing.T) {
+	l := &leaseServer{
+		suggestions: []string{"10.0.0.5", "10.0.0.6"},
+		ips:         map[string]*IP{},
+		competitor:  func(ip *IP) { ip.Notes = "[lease:them]" },
+	}
+	api := newTestAPI(t, l.handle(t))
+
+	// the collision is retried after an hour, unless the context ends first
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := api.AllocateIP(ctx, 1, AllocateIPOptions{RetryDelay: time.Hour, SettleDelay: time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AllocateIP error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Minute {
+		t.Errorf("AllocateIP returned after %s", d)
+	}
+	if len(l.suggestions) != 1 {
+		t.Errorf("AllocateIP retried after the context ended")
+	}
+}
+
+func TestReleaseIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   string
+		cleared []string
+		wantErr bool
+	}{
+		{name: "owner", owner: "me", cleared: []string{"10.0.0.5 7"}},
+		{name: "not the owner", owner: "them", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		l := &leaseServer{ips: map[string]*IP{"10.0.0.5": {ID: 5, Address: "10.0.0.5", SubnetID: 7, Notes: "[lease:me]"}}}
+		api := newTestAPI(t, l.handle(t))
+
+		err := api.ReleaseIP(5, tt.owner)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ReleaseIP error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		// the address is cleared within its subnet only
+		if !reflect.DeepEqual(l.cleared, tt.cleared) {
+			t.Errorf("%s: cleared %v, want %v", tt.name, l.cleared, tt.cleared)
+		}
+	}
+}
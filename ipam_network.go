@@ -0,0 +1,168 @@
+package device42
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/chopnico/device42-go/ipmath"
+)
+
+// NetworkSpec type
+// describes a network segment to provision
+type NetworkSpec struct {
+	// Name of the subnet, and of the vlan unless VLANName is set
+	Name string
+	// ParentSubnetID is the subnet to carve the network from
+	ParentSubnetID int
+	// MaskBits of the network
+	MaskBits int
+	// Tags set on the subnet and vlan
+	Tags []string
+	// VLANRangeStart and VLANRangeEnd bound the vlan number to allocate
+	VLANRangeStart int
+	VLANRangeEnd   int
+	// VLANName overrides the name of the vlan
+	VLANName string
+	// VLANScopeTags scope which vlans count as used
+	VLANScopeTags []string
+	// SkipBroadcast leaves the ipv4 broadcast address unreserved
+	SkipBroadcast bool
+}
+
+// ProvisionedNetwork type
+type ProvisionedNetwork struct {
+	VLAN      *VLAN   `json:"vlan"`
+	Subnet    *Subnet `json:"subnet"`
+	Gateway   *IP     `json:"gateway"`
+	Broadcast *IP     `json:"broadcast,omitempty"`
+}
+
+// ProvisionNetwork will allocate a vlan, carve a child subnet out of a parent
+// and attach it to the vlan, and reserve the gateway and broadcast ips. if a
+// step fails, everything created so far is deleted again, newest first. the
+// update of the child subnet is not reverted on its own, deleting the child
+// undoes it
+func (api *API) ProvisionNetwork(ctx context.Context, spec NetworkSpec) (*ProvisionedNetwork, error) {
+	if spec.Name == "" {
+		return nil, errors.New("a network name must be specified")
+	}
+	if spec.VLANName == "" {
+		spec.VLANName = spec.Name
+	}
+
+	n := ProvisionedNetwork{}
+	rollback := []func() error{}
+
+	fail := func(err error) (*ProvisionedNetwork, error) {
+		errs := []string{err.Error()}
+		for i := len(rollback) - 1; i >= 0; i-- {
+			if rerr := rollback[i](); rerr != nil {
+				errs = append(errs, "rollback: "+rerr.Error())
+			}
+		}
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+
+	parent, err := api.GetSubnetByID(spec.ParentSubnetID)
+	if err != nil {
+		return nil, err
+	}
+	parentPrefix, err := parent.Prefix()
+	if err != nil {
+		return nil, err
+	}
+	if spec.MaskBits >= parentPrefix.Addr().BitLen()-1 {
+		return nil, errors.New("a /" + strconv.Itoa(spec.MaskBits) + " network is too small for a gateway")
+	}
+
+	n.VLAN, err = api.AllocateVLANWithOptions(ctx, AllocateVLANOptions{
+		RangeStart: spec.VLANRangeStart,
+		RangeEnd:   spec.VLANRangeEnd,
+		Name:       spec.VLANName,
+		Tags:       spec.Tags,
+		ScopeTags:  spec.VLANScopeTags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	vlanID := n.VLAN.VlanID
+	rollback = append(rollback, func() error { return api.DeleteVLAN(vlanID) })
+
+	if err := ctx.Err(); err != nil {
+		return fail(err)
+	}
+
+	// create_child reserves the network on the appliance, so another caller
+	// can not be handed the same network before it is updated below
+	child, err := api.SetChildSubnet(spec.ParentSubnetID, spec.MaskBits)
+	if err != nil {
+		return fail(err)
+	}
+	if api.IsDryRun() {
+		// nothing was created, so show the network that would be suggested
+		child, err = api.SuggestSubnet(spec.ParentSubnetID, spec.MaskBits, spec.Name, false)
+		if err != nil {
+			return fail(err)
+		}
+	} else {
+		childID := child.SubnetID
+		rollback = append(rollback, func() error { return api.DeleteSubnet(childID) })
+	}
+	prefix, err := child.Prefix()
+	if err != nil {
+		return fail(err)
+	}
+
+	gateway := prefix.Addr().Next()
+	n.Subnet, err = api.SetSubnet(&Subnet{
+		Name:           spec.Name,
+		Network:        child.Network,
+		MaskBits:       child.MaskBits,
+		ParentSubnetID: spec.ParentSubnetID,
+		VrfGroupID:     parent.VrfGroupID,
+		ParentVlanID:   vlanID,
+		Gateway:        gateway.String(),
+		Tags:           spec.Tags,
+		Allocated:      "yes",
+	})
+	if err != nil {
+		return fail(err)
+	}
+	subnetID := n.Subnet.SubnetID
+	if !api.IsDryRun() && subnetID != child.SubnetID {
+		return fail(errors.New("network " + prefix.String() + " was not updated in place, subnet " +
+			strconv.Itoa(subnetID) + " was changed instead of " + strconv.Itoa(child.SubnetID)))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(err)
+	}
+
+	n.Gateway, err = api.reserveNetworkIP(gateway.String(), subnetID, "gateway", spec.Name)
+	if err != nil {
+		return fail(err)
+	}
+	gatewayID := n.Gateway.ID
+	rollback = append(rollback, func() error { return api.DeleteIP(gatewayID) })
+
+	if prefix.Addr().Is4() && prefix.Bits() < 31 && !spec.SkipBroadcast {
+		n.Broadcast, err = api.reserveNetworkIP(ipmath.Last(prefix).String(), subnetID, "broadcast", spec.Name)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	return &n, nil
+}
+
+// reserveNetworkIP will create a labelled ip within a provisioned subnet
+func (api *API) reserveNetworkIP(address string, subnetID int, role, name string) (*IP, error) {
+	return api.SetIP(&IP{
+		IPAddress: address,
+		SubnetID:  subnetID,
+		Label:     name + "-" + role,
+		Notes:     role + " reserved by network provisioning",
+	})
+}
@@ -0,0 +1,129 @@
+package device42
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// provisionServer answers the requests made by ProvisionNetwork, failing the
+// post of one step. changes are the mutating requests, in order
+func provisionServer(t *testing.T, fail string, changes *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			*changes = append(*changes, r.Method+" "+r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		q := r.URL.Query()
+
+		failed := func(step string) bool {
+			if fail != step {
+				return false
+			}
+			writeJSON(w, map[string]interface{}{"code": 1, "msg": []interface{}{step + " failed"}})
+			return true
+		}
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/subnets" && q.Get("subnet_id") == "1":
+			writeJSON(w, Subnets{List: []Subnet{{SubnetID: 1, Network: "10.0.0.0", MaskBits: 16, VrfGroupID: 2}}})
+		case r.Method == "GET" && r.URL.Path == "/subnets" && q.Get("subnet_id") == "20":
+			writeJSON(w, Subnets{List: []Subnet{{SubnetID: 20, Name: "web", Network: "10.0.5.0", MaskBits: 24, ParentVlanID: 30}}})
+		case r.Method == "GET" && (r.URL.Path == "/vlans/" || r.URL.Path == "/vlans"):
+			if len(*changes) == 0 {
+				writeJSON(w, VLANs{List: []VLAN{}})
+				return
+			}
+			writeJSON(w, VLANs{List: []VLAN{{VlanID: 30, Number: 100, Name: "web"}}})
+		case r.Method == "GET" && r.URL.Path == "/ips":
+			if q.Get("ip_id") == "41" {
+				writeJSON(w, IPs{List: []IP{{ID: 41, Address: "10.0.5.255", SubnetID: 20}}})
+				return
+			}
+			writeJSON(w, IPs{List: []IP{{ID: 40, Address: "10.0.5.1", SubnetID: 20}}})
+		case r.Method == "POST" && r.URL.Path == "/vlans/":
+			writeCreated(w, 30)
+		case r.Method == "POST" && r.URL.Path == "/subnets/create_child/":
+			writeJSON(w, childSubnet{ID: 20, ParentSubnetID: 1, MaskBits: 24, Network: "10.0.5.0"})
+		case r.Method == "POST" && r.URL.Path == "/subnets/":
+			if !failed("subnet") {
+				writeCreated(w, 20)
+			}
+		case r.Method == "POST" && r.URL.Path == "/ips/" && r.PostForm.Get("ipaddress") == "10.0.5.1":
+			if !failed("gateway") {
+				writeCreated(w, 40)
+			}
+		case r.Method == "POST" && r.URL.Path == "/ips/" && r.PostForm.Get("ipaddress") == "10.0.5.255":
+			if !failed("broadcast") {
+				writeCreated(w, 41)
+			}
+		case r.Method == "DELETE":
+			writeJSON(w, map[string]interface{}{"deleted": true})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.Error(w, "{}", http.StatusNotFound)
+		}
+	}
+}
+
+func TestProvisionNetwork(t *testing.T) {
+	// the vlan, the child subnet and its update are made before any step fails
+	created := func(changes ...string) []string {
+		return append([]string{"POST /vlans/", "POST /subnets/create_child/", "POST /subnets/"}, changes...)
+	}
+
+	tests := []struct {
+		fail    string
+		changes []string
+	}{
+		{
+			fail:    "",
+			changes: created("POST /ips/", "POST /ips/"),
+		},
+		{
+			fail:    "subnet",
+			changes: created("DELETE /subnets/20/", "DELETE /vlans/30/"),
+		},
+		{
+			fail:    "gateway",
+			changes: created("POST /ips/", "DELETE /subnets/20/", "DELETE /vlans/30/"),
+		},
+		{
+			// the update of the child subnet is not reverted on its own,
+			// deleting the child undoes it
+			fail:    "broadcast",
+			changes: created("POST /ips/", "POST /ips/", "DELETE /ips/40/", "DELETE /subnets/20/", "DELETE /vlans/30/"),
+		},
+	}
+
+	for _, tt := range tests {
+		changes := []string{}
+		api := newTestAPI(t, provisionServer(t, tt.fail, &changes))
+
+		n, err := api.ProvisionNetwork(context.Background(), NetworkSpec{
+			Name:           "web",
+			ParentSubnetID: 1,
+			MaskBits:       24,
+			VLANRangeStart: 100,
+			VLANRangeEnd:   199,
+		})
+		if tt.fail == "" {
+			if err != nil {
+				t.Fatalf("ProvisionNetwork error = %v", err)
+			}
+			if n.VLAN.VlanID != 30 || n.Subnet.SubnetID != 20 || n.Gateway.ID != 40 || n.Broadcast == nil || n.Broadcast.ID != 41 {
+				t.Errorf("ProvisionNetwork = %+v", n)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.fail+" failed") {
+			t.Errorf("%s: ProvisionNetwork error = %v, want the %s failure", tt.fail, err, tt.fail)
+		}
+
+		if !reflect.DeepEqual(changes, tt.changes) {
+			t.Errorf("%s: changes\n%v\nwant\n%v", tt.fail, changes, tt.changes)
+		}
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/netip"
 	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/chopnico/device42-go/internal/utilities"
+	"github.com/chopnico/device42-go/ipmath"
 )
 
 // Subnet type
@@ -29,7 +31,7 @@ type Subnet struct {
 	Network               string        `json:"network" validate:"required" methods:"post"`
 	Notes                 string        `json:"notes"`
 	ParentSubnetID        int           `json:"parent_subnet_id" methods:"post"`
-	ParentVlanID          int           `json:"parent_vlan_id"`
+	ParentVlanID          int           `json:"parent_vlan_id" methods:"post"`
 	ParentVlanName        string        `json:"parent_vlan_name"`
 	ParentVlanNumber      interface{}   `json:"parent_vlan_number"`
 	RangeBegin            string        `json:"range_begin" methods:"post"`
@@ -56,19 +58,93 @@ type suggestSubnet struct {
 }
 
 type childSubnet struct {
-	id             int    `json:"subnet_id"`
-	parentSubnetID int    `json:"parent_subnet_id" methods:"post"`
-	maskBits       int    `json:"mask_bits" methods:"post"`
-	network        string `json:"network"`
+	ID             int    `json:"subnet_id"`
+	ParentSubnetID int    `json:"parent_subnet_id" methods:"post"`
+	MaskBits       int    `json:"mask_bits" methods:"post"`
+	Network        string `json:"network"`
+}
+
+// Prefix will return the parsed network of the subnet
+func (s *Subnet) Prefix() (netip.Prefix, error) {
+	return ParsePrefix(s.Network, s.MaskBits)
+}
+
+// IsIPv6 checks if the subnet is an ipv6 subnet
+func (s *Subnet) IsIPv6() bool {
+	a, err := ParseAddress(s.Network)
+	if err != nil {
+		return false
+	}
+	return a.Is6()
+}
+
+// Range will return the first and last assignable addresses of the subnet.
+// range_begin and range_end are used when set, otherwise the bounds of the network
+func (s *Subnet) Range() (netip.Addr, netip.Addr, error) {
+	p, err := s.Prefix()
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, err
+	}
+
+	begin, end := p.Addr(), ipmath.Last(p)
+	if s.RangeBegin != "" {
+		begin, err = ParseAddress(s.RangeBegin)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, err
+		}
+	}
+	if s.RangeEnd != "" {
+		end, err = ParseAddress(s.RangeEnd)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, err
+		}
+	}
+
+	return begin, end, nil
+}
+
+// GatewayAddr will return the parsed gateway of the subnet
+func (s *Subnet) GatewayAddr() (netip.Addr, error) {
+	g, ok := s.Gateway.(string)
+	if !ok || g == "" {
+		return netip.Addr{}, errors.New("subnet " + s.Name + " has no gateway")
+	}
+	return ParseAddress(g)
+}
+
+// normalizeSubnet will validate the network of a subnet and make sure the
+// posted addresses are in their canonical form
+func normalizeSubnet(s *Subnet) error {
+	p, err := s.Prefix()
+	if err != nil {
+		return err
+	}
+	s.Network = p.Addr().String()
+
+	s.RangeBegin = NormalizeAddress(s.RangeBegin)
+	s.RangeEnd = NormalizeAddress(s.RangeEnd)
+	if g, ok := s.Gateway.(string); ok {
+		s.Gateway = NormalizeAddress(g)
+	}
+
+	return nil
 }
 
 // SetSubnet will add or update a subnet
 func (api *API) SetSubnet(subnet *Subnet) (*Subnet, error) {
+	if err := normalizeSubnet(subnet); err != nil {
+		return nil, err
+	}
+
 	p := strings.NewReader(utilities.PostParameters(subnet).Encode())
 	b, err := api.Do("POST", "/subnets/", p)
 	if err != nil {
 		return nil, err
 	}
+
+	if api.IsDryRun() {
+		return subnet, nil
+	}
 	apiResponse := APIResponse{}
 
 	err = json.Unmarshal(b, &apiResponse)
@@ -93,8 +169,8 @@ func (api *API) SetSubnet(subnet *Subnet) (*Subnet, error) {
 // used for dynamic subnet allocation
 func (api *API) SetChildSubnet(parentID, maskBits int) (*Subnet, error) {
 	c := childSubnet{
-		parentSubnetID: parentID,
-		maskBits:       maskBits,
+		ParentSubnetID: parentID,
+		MaskBits:       maskBits,
 	}
 	p := strings.NewReader(utilities.PostParameters(c).Encode())
 	b, err := api.Do("POST", "/subnets/create_child/", p)
@@ -102,12 +178,16 @@ func (api *API) SetChildSubnet(parentID, maskBits int) (*Subnet, error) {
 		return nil, err
 	}
 
+	if api.IsDryRun() {
+		return &Subnet{ParentSubnetID: parentID, MaskBits: maskBits}, nil
+	}
+
 	err = json.Unmarshal(b, &c)
 	if err != nil {
 		return nil, err
 	}
 
-	subnet, err := api.GetSubnetByID(c.id)
+	subnet, err := api.GetSubnetByID(c.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -115,8 +195,13 @@ func (api *API) SetChildSubnet(parentID, maskBits int) (*Subnet, error) {
 	return subnet, nil
 }
 
-// SuggestSubnet will return the next avaliable subnet from a parent subnet
+// SuggestSubnet will return the next avaliable subnet from a parent subnet.
+// ipv6 parents accept mask bits up to 128, e.g. /64 or /56 children
 func (api *API) SuggestSubnet(parentID, maskBits int, name string, create bool) (*Subnet, error) {
+	if maskBits < 1 || maskBits > 128 {
+		return nil, errors.New("invalid mask bits " + strconv.Itoa(maskBits))
+	}
+
 	b, err := api.Do(
 		"GET",
 		"/suggest_subnet/"+strconv.Itoa(parentID)+"?mask_bits="+strconv.Itoa(maskBits),
@@ -133,6 +218,17 @@ func (api *API) SuggestSubnet(parentID, maskBits int, name string, create bool)
 		return nil, err
 	}
 
+	// the appliance may hand back the network in expanded form, or without
+	// the requested mask for large ipv6 prefixes
+	if resp.MaskBits == 0 {
+		resp.MaskBits = maskBits
+	}
+	prefix, err := ParsePrefix(resp.Network, resp.MaskBits)
+	if err != nil {
+		return nil, err
+	}
+	resp.Network = prefix.Addr().String()
+
 	if create {
 		subnet, err = api.SetSubnet(
 			&Subnet{
@@ -194,7 +290,7 @@ func (api *API) GetSubnetByNameWithNetwork(n, m string) (*Subnet, error) {
 	}
 
 	if len(subnets.List) == 0 {
-		return nil, errors.New("unable to find subnet with name " + n)
+		return nil, fmt.Errorf("%w with name %s", ErrSubnetNotFound, n)
 	}
 	return &subnets.List[0], nil
 }
@@ -217,7 +313,7 @@ func (api *API) GetSubnetByNameWithVRFGroupID(n string, i int) (*Subnet, error)
 	}
 
 	if len(subnets.List) == 0 {
-		return nil, errors.New("unable to find subnet with name " + n)
+		return nil, fmt.Errorf("%w with name %s", ErrSubnetNotFound, n)
 	}
 	return &subnets.List[0], nil
 }
@@ -240,7 +336,7 @@ func (api *API) GetSubnetsByVlanID(i int) (*[]Subnet, error) {
 	}
 
 	if len(subnets.List) == 0 {
-		return nil, errors.New("unable to find subnet with vlan id " + strconv.Itoa(i))
+		return nil, fmt.Errorf("%w with vlan id %d", ErrSubnetNotFound, i)
 	}
 
 	return &subnets.List, nil
@@ -264,7 +360,7 @@ func (api *API) GetSubnetsByVRFGroupID(i int) (*[]Subnet, error) {
 	}
 
 	if len(subnets.List) == 0 {
-		return nil, errors.New("unable to find subnet with vrf group id " + strconv.Itoa(i))
+		return nil, fmt.Errorf("%w with vrf group id %d", ErrSubnetNotFound, i)
 	}
 
 	return &subnets.List, nil
@@ -288,7 +384,7 @@ func (api *API) GetSubnetsByParentSubnetID(i int) (*[]Subnet, error) {
 	}
 
 	if len(subnets.List) == 0 {
-		return nil, errors.New("unable to find subnet with parent subnet id " + strconv.Itoa(i))
+		return nil, fmt.Errorf("%w with parent subnet id %d", ErrSubnetNotFound, i)
 	}
 
 	return &subnets.List, nil
@@ -312,7 +408,7 @@ func (api *API) GetSubnetsByParentSubnetIDWithVRFGroupID(p, v int) (*[]Subnet, e
 	}
 
 	if len(subnets.List) == 0 {
-		return nil, errors.New("unable to find subnet with parent subnet id " + strconv.Itoa(p))
+		return nil, fmt.Errorf("%w with parent subnet id %d", ErrSubnetNotFound, p)
 	}
 
 	return &subnets.List, nil
@@ -335,6 +431,10 @@ func (api *API) GetSubnetByID(id int) (*Subnet, error) {
 		return nil, err
 	}
 
+	if len(subnets.List) == 0 {
+		return nil, fmt.Errorf("%w with id %d", ErrSubnetNotFound, id)
+	}
+
 	return &subnets.List[0], nil
 }
 
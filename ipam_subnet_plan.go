@@ -0,0 +1,138 @@
+package device42
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/chopnico/device42-go/ipmath"
+)
+
+// SubnetPlan type
+// a carving plan of child subnets within a parent subnet
+type SubnetPlan struct {
+	Parent   Subnet   `json:"parent"`
+	Existing []Subnet `json:"existing"`
+	Planned  []Subnet `json:"planned"`
+}
+
+// ParseSubnetPlanSizes will parse a list of sizes, e.g. "24x4,27x8,26",
+// into carving requests. a size without a count is requested once
+func ParseSubnetPlanSizes(s string) ([]ipmath.Request, error) {
+	requests := []ipmath.Request{}
+
+	for _, size := range strings.Split(s, ",") {
+		size = strings.TrimPrefix(strings.TrimSpace(size), "/")
+		if size == "" {
+			continue
+		}
+
+		r := ipmath.Request{Count: 1}
+		parts := strings.SplitN(size, "x", 2)
+
+		bits, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, errors.New("invalid size " + size)
+		}
+		// the family is not known yet, so only ipv6's limit can be checked
+		if bits < 1 || bits > 128 {
+			return nil, errors.New("invalid mask bits in size " + size + ", expected 1 to 128")
+		}
+		r.Bits = bits
+
+		if len(parts) == 2 {
+			r.Count, err = strconv.Atoi(parts[1])
+			if err != nil || r.Count < 1 {
+				return nil, errors.New("invalid count in size " + size)
+			}
+		}
+
+		requests = append(requests, r)
+	}
+
+	if len(requests) == 0 {
+		return nil, errors.New("no sizes given")
+	}
+
+	return requests, nil
+}
+
+// PlanSubnets will carve the requested child subnets out of a parent subnet
+// around its existing children. nothing is sent to device42
+func PlanSubnets(parent Subnet, children []Subnet, requests []ipmath.Request) (*SubnetPlan, error) {
+	p, err := parent.Prefix()
+	if err != nil {
+		return nil, err
+	}
+
+	used := []netip.Prefix{}
+	for _, c := range children {
+		cp, err := c.Prefix()
+		if err != nil {
+			return nil, err
+		}
+		used = append(used, cp)
+	}
+
+	prefixes, err := ipmath.Plan(p, used, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := SubnetPlan{
+		Parent:   parent,
+		Existing: children,
+	}
+	for _, i := range prefixes {
+		plan.Planned = append(plan.Planned, Subnet{
+			Network:        i.Addr().String(),
+			MaskBits:       i.Bits(),
+			ParentSubnetID: parent.SubnetID,
+			VrfGroupID:     parent.VrfGroupID,
+			VrfGroupName:   parent.VrfGroupName,
+		})
+	}
+
+	return &plan, nil
+}
+
+// PlanChildSubnets will fetch a parent subnet and its children and carve
+// the requested child subnets out of it
+func (api *API) PlanChildSubnets(parentID int, requests []ipmath.Request) (*SubnetPlan, error) {
+	parent, err := api.GetSubnetByID(parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := api.GetSubnetsByParentSubnetID(parentID)
+	if err != nil {
+		if !errors.Is(err, ErrSubnetNotFound) {
+			return nil, err
+		}
+		children = &[]Subnet{}
+	}
+
+	return PlanSubnets(*parent, *children, requests)
+}
+
+// CreateSubnetPlan will create the planned subnets of a plan. subnets without
+// a name are named after their network
+func (api *API) CreateSubnetPlan(plan *SubnetPlan) (*[]Subnet, error) {
+	subnets := []Subnet{}
+
+	for _, i := range plan.Planned {
+		if i.Name == "" {
+			i.Name = i.Network + "/" + strconv.Itoa(i.MaskBits)
+		}
+		i.Allocated = "yes"
+
+		subnet, err := api.SetSubnet(&i)
+		if err != nil {
+			return &subnets, err
+		}
+		subnets = append(subnets, *subnet)
+	}
+
+	return &subnets, nil
+}
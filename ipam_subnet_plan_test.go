@@ -0,0 +1,114 @@
+package device42
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chopnico/device42-go/ipmath"
+)
+
+func TestParseSubnetPlanSizes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []ipmath.Request
+		wantErr bool
+	}{
+		{in: "24", want: []ipmath.Request{{Bits: 24, Count: 1}}},
+		{in: "/24x4, 27x8,26", want: []ipmath.Request{{Bits: 24, Count: 4}, {Bits: 27, Count: 8}, {Bits: 26, Count: 1}}},
+		{in: "64x2,", want: []ipmath.Request{{Bits: 64, Count: 2}}},
+		{in: "128", want: []ipmath.Request{{Bits: 128, Count: 1}}},
+		{in: "", wantErr: true},
+		{in: " , ", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "129", wantErr: true},
+		{in: "-24", wantErr: true},
+		{in: "24x0", wantErr: true},
+		{in: "24x", wantErr: true},
+		{in: "ax2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSubnetPlanSizes(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSubnetPlanSizes(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSubnetPlanSizes(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSubnets(t *testing.T) {
+	parent := Subnet{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 2, VrfGroupName: "default"}
+
+	tests := []struct {
+		name     string
+		parent   Subnet
+		children []Subnet
+		requests []ipmath.Request
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "around children",
+			parent:   parent,
+			children: []Subnet{{Network: "10.0.0.0", MaskBits: 25}},
+			requests: []ipmath.Request{{Bits: 26, Count: 2}},
+			want:     []string{"10.0.0.128/26", "10.0.0.192/26"},
+		},
+		{
+			name:     "ipv6",
+			parent:   Subnet{SubnetID: 1, Network: "2001:db8::", MaskBits: 56},
+			requests: []ipmath.Request{{Bits: 64, Count: 2}},
+			want:     []string{"2001:db8::/64", "2001:db8:0:1::/64"},
+		},
+		{
+			name:     "ipv6 size for ipv4 parent",
+			parent:   parent,
+			requests: []ipmath.Request{{Bits: 64, Count: 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "full",
+			parent:   parent,
+			children: []Subnet{{Network: "10.0.0.0", MaskBits: 24}},
+			requests: []ipmath.Request{{Bits: 26, Count: 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid child",
+			parent:   parent,
+			children: []Subnet{{Network: "bogus", MaskBits: 24}},
+			requests: []ipmath.Request{{Bits: 26, Count: 1}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		plan, err := PlanSubnets(tt.parent, tt.children, tt.requests)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: PlanSubnets error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		got := []string{}
+		for _, s := range plan.Planned {
+			p, err := s.Prefix()
+			if err != nil {
+				t.Errorf("%s: planned subnet %v: %v", tt.name, s, err)
+				continue
+			}
+			got = append(got, p.String())
+			if s.ParentSubnetID != tt.parent.SubnetID || s.VrfGroupID != tt.parent.VrfGroupID || s.VrfGroupName != tt.parent.VrfGroupName {
+				t.Errorf("%s: planned subnet %s does not belong to the parent: %+v", tt.name, p, s)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: PlanSubnets = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
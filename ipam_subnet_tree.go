@@ -0,0 +1,208 @@
+package device42
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+)
+
+// SubnetNode type
+// a subnet within a subnet hierarchy
+type SubnetNode struct {
+	Subnet   Subnet        `json:"subnet"`
+	Children []*SubnetNode `json:"children"`
+	Parent   *SubnetNode   `json:"-"`
+}
+
+// Depth returns how deep the node sits within its tree, roots are 0
+func (n *SubnetNode) Depth() int {
+	d := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		d++
+	}
+	return d
+}
+
+// Walk will visit the node and all of its descendants depth first. returning
+// false from fn skips the children of that node
+func (n *SubnetNode) Walk(fn func(n *SubnetNode, depth int) bool) {
+	n.walk(fn, 0)
+}
+
+func (n *SubnetNode) walk(fn func(n *SubnetNode, depth int) bool, depth int) {
+	if !fn(n, depth) {
+		return
+	}
+	for _, c := range n.Children {
+		c.walk(fn, depth+1)
+	}
+}
+
+// BuildSubnetTree will arrange subnets into trees using their parent subnet ids.
+// subnets whose parent is not in the list become roots, as do subnets whose
+// parents lead back to themselves. roots and children are sorted by network
+func BuildSubnetTree(subnets []Subnet) []*SubnetNode {
+	nodes := make(map[int]*SubnetNode, len(subnets))
+	order := make([]*SubnetNode, 0, len(subnets))
+
+	for _, s := range subnets {
+		n := &SubnetNode{Subnet: s}
+		nodes[s.SubnetID] = n
+		order = append(order, n)
+	}
+
+	parent := func(n *SubnetNode) *SubnetNode {
+		p, ok := nodes[n.Subnet.ParentSubnetID]
+		if !ok || n.Subnet.ParentSubnetID == 0 || p == n {
+			return nil
+		}
+		return p
+	}
+	cyclic := subnetCycles(order, parent)
+
+	roots := []*SubnetNode{}
+	for _, n := range order {
+		p := parent(n)
+		if p == nil || cyclic[n] {
+			roots = append(roots, n)
+			continue
+		}
+		n.Parent = p
+		p.Children = append(p.Children, n)
+	}
+
+	sortSubnetNodes(roots)
+	for _, n := range order {
+		sortSubnetNodes(n.Children)
+	}
+
+	return roots
+}
+
+// subnetCycles will find the nodes whose parent chain leads back to
+// themselves. without a root above them they would never be reached
+func subnetCycles(order []*SubnetNode, parent func(n *SubnetNode) *SubnetNode) map[*SubnetNode]bool {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*SubnetNode]int, len(order))
+	cyclic := make(map[*SubnetNode]bool)
+
+	for _, n := range order {
+		path := []*SubnetNode{}
+		cur := n
+		for cur != nil && state[cur] == 0 {
+			state[cur] = visiting
+			path = append(path, cur)
+			cur = parent(cur)
+		}
+
+		// the chain reached a node of this path again, everything from there on is the cycle
+		if cur != nil && state[cur] == visiting {
+			for i := len(path) - 1; i >= 0; i-- {
+				cyclic[path[i]] = true
+				if path[i] == cur {
+					break
+				}
+			}
+		}
+		for _, i := range path {
+			state[i] = visited
+		}
+	}
+
+	return cyclic
+}
+
+// WalkSubnetTree will walk every node of every root
+func WalkSubnetTree(roots []*SubnetNode, fn func(n *SubnetNode, depth int) bool) {
+	for _, r := range roots {
+		r.Walk(fn)
+	}
+}
+
+// FilterSubnetTree will return a copy of the trees holding only the subnets
+// matching fn, along with their ancestors so the hierarchy is kept
+func FilterSubnetTree(roots []*SubnetNode, fn func(s *Subnet) bool) []*SubnetNode {
+	filtered := []*SubnetNode{}
+
+	for _, r := range roots {
+		if n := filterSubnetNode(r, nil, fn); n != nil {
+			filtered = append(filtered, n)
+		}
+	}
+
+	return filtered
+}
+
+func filterSubnetNode(n, parent *SubnetNode, fn func(s *Subnet) bool) *SubnetNode {
+	c := &SubnetNode{Subnet: n.Subnet, Parent: parent}
+
+	for _, i := range n.Children {
+		if f := filterSubnetNode(i, c, fn); f != nil {
+			c.Children = append(c.Children, f)
+		}
+	}
+
+	if len(c.Children) == 0 && !fn(&c.Subnet) {
+		return nil
+	}
+
+	return c
+}
+
+// GetSubnetTree will return the subnet hierarchy of a vrf group
+func (api *API) GetSubnetTree(vrfGroupID int) ([]*SubnetNode, error) {
+	subnets, err := api.GetSubnetsByVRFGroupID(vrfGroupID)
+	if err != nil {
+		if errors.Is(err, ErrSubnetNotFound) {
+			return []*SubnetNode{}, nil
+		}
+		return nil, err
+	}
+
+	return BuildSubnetTree(*subnets), nil
+}
+
+// Capacity will return the number of addresses within the subnet's range.
+// the network and broadcast addresses of ipv4 subnets are left out unless
+// the subnet allows them or a range is set
+func (s *Subnet) Capacity() (*big.Int, error) {
+	begin, end, err := s.Range()
+	if err != nil {
+		return nil, err
+	}
+
+	n := new(big.Int).Sub(
+		new(big.Int).SetBytes(end.AsSlice()),
+		new(big.Int).SetBytes(begin.AsSlice()),
+	)
+	n.Add(n, big.NewInt(1))
+
+	if begin.Is4() && s.MaskBits < 31 {
+		if s.RangeBegin == "" && s.AllowNetworkAddress != "yes" {
+			n.Sub(n, big.NewInt(1))
+		}
+		if s.RangeEnd == "" && s.AllowBroadcastAddress != "yes" {
+			n.Sub(n, big.NewInt(1))
+		}
+	}
+
+	return n, nil
+}
+
+// sortSubnetNodes sorts nodes by network address
+func sortSubnetNodes(nodes []*SubnetNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, errA := nodes[i].Subnet.Prefix()
+		b, errB := nodes[j].Subnet.Prefix()
+		if errA != nil || errB != nil {
+			return nodes[i].Subnet.Network < nodes[j].Subnet.Network
+		}
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+}
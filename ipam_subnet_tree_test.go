@@ -0,0 +1,146 @@
+package device42
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// treeString lists the ids of a tree depth first, indented by depth
+func treeString(roots []*SubnetNode) []string {
+	s := []string{}
+	WalkSubnetTree(roots, func(n *SubnetNode, depth int) bool {
+		if n.Depth() != depth {
+			s = append(s, "depth mismatch at "+strconv.Itoa(n.Subnet.SubnetID))
+		}
+		line := ""
+		for i := 0; i < depth; i++ {
+			line += "  "
+		}
+		s = append(s, line+strconv.Itoa(n.Subnet.SubnetID))
+		return true
+	})
+	return s
+}
+
+func TestBuildSubnetTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets []Subnet
+		want    []string
+	}{
+		{
+			name:    "empty",
+			subnets: nil,
+			want:    []string{},
+		},
+		{
+			name: "nested and sorted",
+			subnets: []Subnet{
+				{SubnetID: 3, Network: "10.0.1.0", MaskBits: 24, ParentSubnetID: 1},
+				{SubnetID: 2, Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 1},
+				{SubnetID: 4, Network: "10.0.0.0", MaskBits: 25, ParentSubnetID: 2},
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 16},
+				{SubnetID: 5, Network: "2001:db8::", MaskBits: 32},
+				{SubnetID: 6, Network: "192.168.0.0", MaskBits: 16},
+			},
+			want: []string{"1", "  2", "    4", "  3", "6", "5"},
+		},
+		{
+			name: "missing parent",
+			subnets: []Subnet{
+				{SubnetID: 2, Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 99},
+			},
+			want: []string{"2"},
+		},
+		{
+			name: "own parent",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 1},
+				{SubnetID: 2, Network: "10.0.0.0", MaskBits: 25, ParentSubnetID: 1},
+			},
+			want: []string{"1", "  2"},
+		},
+		{
+			name: "cycle",
+			subnets: []Subnet{
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 2},
+				{SubnetID: 2, Network: "10.0.1.0", MaskBits: 24, ParentSubnetID: 1},
+			},
+			want: []string{"1", "2"},
+		},
+		{
+			name: "cycle with a tail",
+			subnets: []Subnet{
+				{SubnetID: 4, Network: "10.0.3.0", MaskBits: 24, ParentSubnetID: 1},
+				{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 2},
+				{SubnetID: 2, Network: "10.0.1.0", MaskBits: 24, ParentSubnetID: 3},
+				{SubnetID: 3, Network: "10.0.2.0", MaskBits: 24, ParentSubnetID: 1},
+			},
+			want: []string{"1", "  4", "2", "3"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := treeString(BuildSubnetTree(tt.subnets))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: BuildSubnetTree = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterSubnetTree(t *testing.T) {
+	roots := BuildSubnetTree([]Subnet{
+		{SubnetID: 1, Name: "site", Network: "10.0.0.0", MaskBits: 16},
+		{SubnetID: 2, Name: "web", Network: "10.0.0.0", MaskBits: 24, ParentSubnetID: 1},
+		{SubnetID: 3, Name: "db", Network: "10.0.1.0", MaskBits: 24, ParentSubnetID: 1},
+		{SubnetID: 4, Name: "other", Network: "192.168.0.0", MaskBits: 24},
+	})
+
+	tests := []struct {
+		name string
+		fn   func(s *Subnet) bool
+		want []string
+	}{
+		{name: "keeps ancestors", fn: func(s *Subnet) bool { return s.Name == "db" }, want: []string{"1", "  3"}},
+		{name: "root", fn: func(s *Subnet) bool { return s.Name == "other" }, want: []string{"4"}},
+		{name: "none", fn: func(s *Subnet) bool { return false }, want: []string{}},
+		{name: "all", fn: func(s *Subnet) bool { return true }, want: []string{"1", "  2", "  3", "4"}},
+	}
+
+	for _, tt := range tests {
+		got := treeString(FilterSubnetTree(roots, tt.fn))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: FilterSubnetTree = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubnetCapacity(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnet  Subnet
+		want    string
+		wantErr bool
+	}{
+		{name: "ipv4", subnet: Subnet{Network: "10.0.0.0", MaskBits: 24}, want: "254"},
+		{name: "ipv4 network allowed", subnet: Subnet{Network: "10.0.0.0", MaskBits: 24, AllowNetworkAddress: "yes"}, want: "255"},
+		{name: "ipv4 both allowed", subnet: Subnet{Network: "10.0.0.0", MaskBits: 24, AllowNetworkAddress: "yes", AllowBroadcastAddress: "yes"}, want: "256"},
+		{name: "ipv4 range", subnet: Subnet{Network: "10.0.0.0", MaskBits: 24, RangeBegin: "10.0.0.10", RangeEnd: "10.0.0.19"}, want: "10"},
+		{name: "ipv4 /31", subnet: Subnet{Network: "10.0.0.0", MaskBits: 31}, want: "2"},
+		{name: "ipv4 /32", subnet: Subnet{Network: "10.0.0.1", MaskBits: 32}, want: "1"},
+		{name: "ipv6", subnet: Subnet{Network: "2001:db8::", MaskBits: 64}, want: "18446744073709551616"},
+		{name: "invalid", subnet: Subnet{Network: "bogus", MaskBits: 24}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.subnet.Capacity()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Capacity error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("%s: Capacity = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
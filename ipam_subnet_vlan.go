@@ -0,0 +1,95 @@
+package device42
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chopnico/device42-go/internal/utilities"
+)
+
+// VLANDetails type
+// a vlan with the subnets attached to it
+type VLANDetails struct {
+	VLAN    VLAN     `json:"vlan"`
+	Subnets []Subnet `json:"subnets"`
+}
+
+type subnetVLAN struct {
+	Network    string `json:"network" methods:"post"`
+	MaskBits   int    `json:"mask_bits" methods:"post"`
+	VrfGroupID int    `json:"vrf_group_id" methods:"post"`
+}
+
+// AttachSubnetToVLAN will set the parent vlan of a subnet
+func (api *API) AttachSubnetToVLAN(subnetID, vlanID int) (*Subnet, error) {
+	if _, err := api.GetVLANByID(vlanID); err != nil {
+		return nil, err
+	}
+
+	return api.setSubnetVLAN(subnetID, strconv.Itoa(vlanID))
+}
+
+// DetachSubnetFromVLAN will clear the parent vlan of a subnet
+func (api *API) DetachSubnetFromVLAN(subnetID int) (*Subnet, error) {
+	return api.setSubnetVLAN(subnetID, "")
+}
+
+// GetVLANDetails will return a vlan along with its subnets
+func (api *API) GetVLANDetails(id int) (*VLANDetails, error) {
+	vlan, err := api.GetVLANByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	d := VLANDetails{
+		VLAN:    *vlan,
+		Subnets: []Subnet{},
+	}
+
+	subnets, err := api.GetSubnetsByVlanID(id)
+	if err != nil {
+		if !errors.Is(err, ErrSubnetNotFound) {
+			return nil, err
+		}
+	} else {
+		d.Subnets = *subnets
+	}
+
+	return &d, nil
+}
+
+// setSubnetVLAN will post the parent vlan id of a subnet. an empty vlan id
+// is posted as is to clear the association
+func (api *API) setSubnetVLAN(subnetID int, vlanID string) (*Subnet, error) {
+	subnet, err := api.GetSubnetByID(subnetID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := utilities.PostParameters(subnetVLAN{
+		Network:    subnet.Network,
+		MaskBits:   subnet.MaskBits,
+		VrfGroupID: subnet.VrfGroupID,
+	})
+	p.Set("parent_vlan_id", vlanID)
+
+	b, err := api.Do("POST", "/subnets/", strings.NewReader(p.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	apiResponse := APIResponse{}
+
+	err = json.Unmarshal(b, &apiResponse)
+	if err != nil {
+		return nil, err
+	}
+	if apiResponse.Code != 0 {
+		return nil, fmt.Errorf("%v", apiResponse.Message)
+	}
+
+	return api.GetSubnetByID(subnetID)
+}
@@ -0,0 +1,63 @@
+package device42
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSubnetVLAN(t *testing.T) {
+	tests := []struct {
+		name    string
+		attach  int
+		want    url.Values
+		wantErr bool
+	}{
+		{
+			name:   "attach",
+			attach: 5,
+			want:   url.Values{"network": {"10.0.0.0"}, "mask_bits": {"24"}, "vrf_group_id": {"2"}, "parent_vlan_id": {"5"}},
+		},
+		{
+			name: "detach",
+			want: url.Values{"network": {"10.0.0.0"}, "mask_bits": {"24"}, "vrf_group_id": {"2"}, "parent_vlan_id": {""}},
+		},
+		{name: "unknown vlan", attach: 6, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var posted url.Values
+		api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "GET" && r.URL.Path == "/vlans/5":
+				writeJSON(w, VLAN{VlanID: 5, Number: 100})
+			case r.Method == "GET" && r.URL.Path == "/vlans/6":
+				http.Error(w, "{}", http.StatusNotFound)
+			case r.Method == "GET" && r.URL.Path == "/subnets":
+				writeJSON(w, Subnets{List: []Subnet{{SubnetID: 3, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 2}}})
+			case r.Method == "POST" && r.URL.Path == "/subnets/":
+				if err := r.ParseForm(); err != nil {
+					t.Fatal(err)
+				}
+				posted = r.PostForm
+				writeCreated(w, 3)
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			}
+		})
+
+		var err error
+		if tt.attach != 0 {
+			_, err = api.AttachSubnetToVLAN(3, tt.attach)
+		} else {
+			_, err = api.DetachSubnetFromVLAN(3)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(posted, tt.want) {
+			t.Errorf("%s: posted %v, want %v", tt.name, posted, tt.want)
+		}
+	}
+}
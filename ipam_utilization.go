@@ -0,0 +1,193 @@
+package device42
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// SubnetUtilization type
+type SubnetUtilization struct {
+	SubnetID       int      `json:"subnet_id"`
+	ParentSubnetID int      `json:"parent_subnet_id"`
+	Name           string   `json:"name"`
+	Network        string   `json:"network"`
+	MaskBits       int      `json:"mask_bits"`
+	VrfGroupID     int      `json:"vrf_group_id"`
+	VrfGroupName   string   `json:"vrf_group_name"`
+	Tags           []string `json:"tags"`
+	// Used is the number of ips assigned directly within the subnet
+	Used int64 `json:"used"`
+	// TotalUsed is Used plus the used ips of all child subnets
+	TotalUsed int64    `json:"total_used"`
+	Capacity  *big.Int `json:"capacity"`
+	Free      *big.Int `json:"free"`
+	// Percent is TotalUsed over Capacity
+	Percent float64 `json:"percent"`
+}
+
+// VRFGroupUtilization type
+type VRFGroupUtilization struct {
+	VrfGroupID   int      `json:"vrf_group_id"`
+	VrfGroupName string   `json:"vrf_group_name"`
+	Subnets      int      `json:"subnets"`
+	Used         int64    `json:"used"`
+	Capacity     *big.Int `json:"capacity"`
+	Free         *big.Int `json:"free"`
+	Percent      float64  `json:"percent"`
+}
+
+// UtilizationReport type
+type UtilizationReport struct {
+	Subnets   []SubnetUtilization   `json:"subnets"`
+	VRFGroups []VRFGroupUtilization `json:"vrf_groups"`
+}
+
+// Exceeding will return the subnets whose utilization is above a percentage
+func (r *UtilizationReport) Exceeding(percent float64) []SubnetUtilization {
+	e := []SubnetUtilization{}
+	for _, i := range r.Subnets {
+		if i.Percent > percent {
+			e = append(e, i)
+		}
+	}
+	return e
+}
+
+// CalculateUtilization will calculate the utilization of subnets, rolled up
+// through the parent chain and per vrf group. used counts come from the ips
+// when given, otherwise from the subnet's assigned count
+func CalculateUtilization(subnets []Subnet, ips []IP) *UtilizationReport {
+	var counts map[int]int
+	if ips != nil {
+		counts = CountIPsBySubnetID(ips)
+	}
+
+	roots := BuildSubnetTree(subnets)
+	utilization := make(map[int]*SubnetUtilization, len(subnets))
+	report := UtilizationReport{}
+
+	var rollup func(n *SubnetNode) int64
+	rollup = func(n *SubnetNode) int64 {
+		u := newSubnetUtilization(&n.Subnet, counts)
+		u.TotalUsed = u.Used
+		for _, c := range n.Children {
+			u.TotalUsed += rollup(c)
+		}
+		u.Free, u.Percent = freeAndPercent(u.TotalUsed, u.Capacity)
+		utilization[n.Subnet.SubnetID] = u
+		return u.TotalUsed
+	}
+
+	groups := make(map[int]*VRFGroupUtilization)
+	for _, r := range roots {
+		rollup(r)
+
+		// only roots count towards the vrf group, children are already rolled up
+		u := utilization[r.Subnet.SubnetID]
+		g, ok := groups[u.VrfGroupID]
+		if !ok {
+			g = &VRFGroupUtilization{
+				VrfGroupID:   u.VrfGroupID,
+				VrfGroupName: u.VrfGroupName,
+				Capacity:     new(big.Int),
+			}
+			groups[u.VrfGroupID] = g
+		}
+		g.Used += u.TotalUsed
+		g.Capacity.Add(g.Capacity, u.Capacity)
+	}
+
+	WalkSubnetTree(roots, func(n *SubnetNode, depth int) bool {
+		report.Subnets = append(report.Subnets, *utilization[n.Subnet.SubnetID])
+		if g, ok := groups[n.Subnet.VrfGroupID]; ok {
+			g.Subnets++
+		}
+		return true
+	})
+
+	for _, g := range groups {
+		g.Free, g.Percent = freeAndPercent(g.Used, g.Capacity)
+		report.VRFGroups = append(report.VRFGroups, *g)
+	}
+	sort.Slice(report.VRFGroups, func(i, j int) bool {
+		return report.VRFGroups[i].VrfGroupName < report.VRFGroups[j].VrfGroupName
+	})
+
+	return &report
+}
+
+// GetUtilization will fetch subnets and calculate their utilization. when
+// countIPs is set the ips are fetched and counted, otherwise device42's
+// assigned counts are used. a vrf group id of 0 includes all subnets
+func (api *API) GetUtilization(vrfGroupID int, countIPs bool) (*UtilizationReport, error) {
+	var (
+		subnets *[]Subnet
+		err     error
+	)
+
+	if vrfGroupID != 0 {
+		subnets, err = api.GetSubnetsByVRFGroupID(vrfGroupID)
+	} else {
+		subnets, err = api.GetSubnets()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []IP
+	if countIPs {
+		i, err := api.GetIPs()
+		if err != nil {
+			return nil, err
+		}
+		ips = *i
+	}
+
+	return CalculateUtilization(*subnets, ips), nil
+}
+
+func newSubnetUtilization(s *Subnet, counts map[int]int) *SubnetUtilization {
+	u := SubnetUtilization{
+		SubnetID:       s.SubnetID,
+		ParentSubnetID: s.ParentSubnetID,
+		Name:           s.Name,
+		Network:        NormalizeAddress(s.Network),
+		MaskBits:       s.MaskBits,
+		VrfGroupID:     s.VrfGroupID,
+		VrfGroupName:   s.VrfGroupName,
+		Tags:           s.Tags,
+	}
+
+	if counts != nil {
+		u.Used = int64(counts[s.SubnetID])
+	} else if n, err := strconv.ParseInt(s.Assigned, 10, 64); err == nil {
+		u.Used = n
+	}
+
+	c, err := s.Capacity()
+	if err != nil || c.Sign() < 0 {
+		c = new(big.Int)
+	}
+	u.Capacity = c
+
+	return &u
+}
+
+// freeAndPercent returns the free addresses and used percentage of a capacity
+func freeAndPercent(used int64, capacity *big.Int) (*big.Int, float64) {
+	free := new(big.Int).Sub(capacity, big.NewInt(used))
+	if free.Sign() < 0 {
+		free.SetInt64(0)
+	}
+	if capacity.Sign() == 0 {
+		return free, 0
+	}
+
+	pct, _ := new(big.Float).Quo(
+		new(big.Float).SetInt64(used*100),
+		new(big.Float).SetInt(capacity),
+	).Float64()
+
+	return free, pct
+}
@@ -0,0 +1,138 @@
+package device42
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalculateUtilization(t *testing.T) {
+	subnets := []Subnet{
+		{SubnetID: 1, Name: "site", Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 1, VrfGroupName: "a", Assigned: "1"},
+		{SubnetID: 2, Name: "web", Network: "10.0.0.0", MaskBits: 26, ParentSubnetID: 1, VrfGroupID: 1, VrfGroupName: "a", Assigned: "10"},
+		{SubnetID: 3, Name: "db", Network: "10.0.0.64", MaskBits: 26, ParentSubnetID: 1, VrfGroupID: 1, VrfGroupName: "a", Assigned: "5"},
+		{SubnetID: 4, Name: "lab", Network: "192.168.0.0", MaskBits: 30, VrfGroupID: 2, VrfGroupName: "b", Assigned: "4"},
+		{SubnetID: 5, Name: "v6", Network: "2001:db8::", MaskBits: 64, VrfGroupID: 2, VrfGroupName: "b"},
+	}
+
+	type want struct {
+		used, total int64
+		capacity    string
+		free        string
+		percent     float64
+	}
+
+	tests := []struct {
+		name    string
+		ips     []IP
+		subnets map[int]want
+		groups  map[string]want
+	}{
+		{
+			name: "assigned counts",
+			ips:  nil,
+			subnets: map[int]want{
+				1: {used: 1, total: 16, capacity: "254", free: "238", percent: 1600.0 / 254},
+				2: {used: 10, total: 10, capacity: "62", free: "52", percent: 1000.0 / 62},
+				3: {used: 5, total: 5, capacity: "62", free: "57", percent: 500.0 / 62},
+				// more used than there is room for is capped at no free addresses
+				4: {used: 4, total: 4, capacity: "2", free: "0", percent: 200},
+				5: {used: 0, total: 0, capacity: "18446744073709551616", free: "18446744073709551616", percent: 0},
+			},
+			groups: map[string]want{
+				"a": {total: 16, capacity: "254", free: "238", percent: 1600.0 / 254},
+				"b": {total: 4, capacity: "18446744073709551618", free: "18446744073709551614"},
+			},
+		},
+		{
+			name: "counted ips",
+			ips:  []IP{{SubnetID: 2}, {SubnetID: 2}, {SubnetID: 3}, {SubnetID: 99}},
+			subnets: map[int]want{
+				1: {used: 0, total: 3, capacity: "254", free: "251", percent: 300.0 / 254},
+				2: {used: 2, total: 2, capacity: "62", free: "60", percent: 200.0 / 62},
+				3: {used: 1, total: 1, capacity: "62", free: "61", percent: 100.0 / 62},
+				4: {used: 0, total: 0, capacity: "2", free: "2", percent: 0},
+				5: {used: 0, total: 0, capacity: "18446744073709551616", free: "18446744073709551616", percent: 0},
+			},
+			groups: map[string]want{
+				"a": {total: 3, capacity: "254", free: "251", percent: 300.0 / 254},
+				"b": {total: 0, capacity: "18446744073709551618", free: "18446744073709551618"},
+			},
+		},
+	}
+
+	closeTo := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	for _, tt := range tests {
+		r := CalculateUtilization(subnets, tt.ips)
+
+		if len(r.Subnets) != len(tt.subnets) {
+			t.Errorf("%s: got %d subnets, want %d", tt.name, len(r.Subnets), len(tt.subnets))
+		}
+		for _, u := range r.Subnets {
+			w, ok := tt.subnets[u.SubnetID]
+			if !ok {
+				t.Errorf("%s: unexpected subnet %d", tt.name, u.SubnetID)
+				continue
+			}
+			if u.Used != w.used || u.TotalUsed != w.total || u.Capacity.String() != w.capacity ||
+				u.Free.String() != w.free || !closeTo(u.Percent, w.percent) {
+				t.Errorf("%s: subnet %d = used %d, total %d, capacity %s, free %s, percent %f, want %+v",
+					tt.name, u.SubnetID, u.Used, u.TotalUsed, u.Capacity, u.Free, u.Percent, w)
+			}
+		}
+
+		if len(r.VRFGroups) != len(tt.groups) {
+			t.Errorf("%s: got %d vrf groups, want %d", tt.name, len(r.VRFGroups), len(tt.groups))
+		}
+		for _, g := range r.VRFGroups {
+			w, ok := tt.groups[g.VrfGroupName]
+			if !ok {
+				t.Errorf("%s: unexpected vrf group %s", tt.name, g.VrfGroupName)
+				continue
+			}
+			if g.Used != w.total || g.Capacity.String() != w.capacity || g.Free.String() != w.free {
+				t.Errorf("%s: vrf group %s = used %d, capacity %s, free %s, want %+v",
+					tt.name, g.VrfGroupName, g.Used, g.Capacity, g.Free, w)
+			}
+			if w.percent != 0 && !closeTo(g.Percent, w.percent) {
+				t.Errorf("%s: vrf group %s percent = %f, want %f", tt.name, g.VrfGroupName, g.Percent, w.percent)
+			}
+		}
+	}
+}
+
+func TestUtilizationReportExceeding(t *testing.T) {
+	r := UtilizationReport{Subnets: []SubnetUtilization{
+		{SubnetID: 1, Percent: 50},
+		{SubnetID: 2, Percent: 80},
+		{SubnetID: 3, Percent: 95.5},
+	}}
+
+	tests := []struct {
+		percent float64
+		want    []int
+	}{
+		{percent: 0, want: []int{1, 2, 3}},
+		{percent: 50, want: []int{2, 3}},
+		{percent: 80, want: []int{3}},
+		{percent: 95.5, want: []int{}},
+		{percent: 100, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		got := []int{}
+		for _, i := range r.Exceeding(tt.percent) {
+			got = append(got, i.SubnetID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Exceeding(%v) = %v, want %v", tt.percent, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Exceeding(%v) = %v, want %v", tt.percent, got, tt.want)
+				break
+			}
+		}
+	}
+}
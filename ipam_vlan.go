@@ -130,6 +130,20 @@ func (api *API) GetVLANByID(id int) (*VLAN, error) {
 
 // GetVLANByNumber will return a vlan by an number
 func (api *API) GetVLANByNumber(n int) (*VLAN, error) {
+	vlans, err := api.GetVLANsByNumber(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(*vlans) == 0 {
+		return nil, errors.New("unable to find vlan with number " + strconv.Itoa(n))
+	}
+
+	return &(*vlans)[0], nil
+}
+
+// GetVLANsByNumber will return all vlans with a number
+func (api *API) GetVLANsByNumber(n int) (*[]VLAN, error) {
 	b, err := api.Do(
 		"GET",
 		"/vlans?number="+strconv.Itoa(n),
@@ -145,7 +159,7 @@ func (api *API) GetVLANByNumber(n int) (*VLAN, error) {
 		return nil, err
 	}
 
-	return &vlans.List[0], nil
+	return &vlans.List, nil
 }
 
 // SetVLAN will add or update a vlan
@@ -155,6 +169,10 @@ func (api *API) SetVLAN(v *VLAN) (*VLAN, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	if api.IsDryRun() {
+		return v, nil
+	}
 	apiResponse := APIResponse{}
 
 	err = json.Unmarshal(b, &apiResponse)
@@ -180,3 +198,27 @@ func (api *API) SetVLAN(v *VLAN) (*VLAN, error) {
 
 	return v, nil
 }
+
+// UpdateVLAN will update a vlan by its id
+func (api *API) UpdateVLAN(v *VLAN) (*VLAN, error) {
+	p := strings.NewReader(utilities.PostParameters(v).Encode())
+	b, err := api.Do("PUT", "/vlans/"+strconv.Itoa(v.VlanID)+"/", p)
+	if err != nil {
+		return nil, err
+	}
+
+	if api.IsDryRun() {
+		return v, nil
+	}
+	apiResponse := APIResponse{}
+
+	err = json.Unmarshal(b, &apiResponse)
+	if err != nil {
+		return nil, err
+	}
+	if apiResponse.Code != 0 {
+		return nil, errors.New(apiResponse.Message.([]interface{})[0].(string))
+	}
+
+	return api.GetVLANByID(v.VlanID)
+}
@@ -0,0 +1,178 @@
+package device42
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// AllocateVLANOptions type
+type AllocateVLANOptions struct {
+	// RangeStart and RangeEnd bound the vlan numbers to allocate from
+	RangeStart int
+	RangeEnd   int
+	// Name, Description, Notes and Tags are set on the created vlan
+	Name        string
+	Description string
+	Notes       string
+	Tags        []string
+	// ScopeTags only counts vlans with any of these tags as used. they are
+	// added to the created vlan, so it counts against later allocations
+	ScopeTags []string
+	// Retries is how many times a collision is retried (default 5)
+	Retries int
+	// RetryDelay is the base delay between retries (default 500ms)
+	RetryDelay time.Duration
+}
+
+// AllocateVLAN will create a vlan with the lowest free number within a range
+func (api *API) AllocateVLAN(ctx context.Context, rangeStart, rangeEnd int, name string, tags []string) (*VLAN, error) {
+	return api.AllocateVLANWithOptions(ctx, AllocateVLANOptions{
+		RangeStart: rangeStart,
+		RangeEnd:   rangeEnd,
+		Name:       name,
+		Tags:       tags,
+	})
+}
+
+// AllocateVLANWithOptions will create a vlan with the lowest free number within
+// a range. existing vlans can be scoped by tag, so the same number may be used
+// elsewhere. created vlans are not on any switch, so a switch can not scope
+// an allocation. when another client creates the same number at the
+// same time, the vlan with the lowest id wins and the others retry
+func (api *API) AllocateVLANWithOptions(ctx context.Context, opts AllocateVLANOptions) (*VLAN, error) {
+	if opts.RangeStart < 1 || opts.RangeEnd > 4094 || opts.RangeStart > opts.RangeEnd {
+		return nil, errors.New("invalid vlan range " + strconv.Itoa(opts.RangeStart) + "-" + strconv.Itoa(opts.RangeEnd))
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = defaultAllocateRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultAllocateRetryDelay
+	}
+
+	var vlan *VLAN
+	won, err := retryAllocation(ctx, opts.Retries, opts.RetryDelay, func() (bool, error) {
+		vlans, err := api.scopedVLANs(opts)
+		if err != nil {
+			return false, err
+		}
+
+		n, ok := NextFreeVLANNumber(vlans, opts.RangeStart, opts.RangeEnd)
+		if !ok {
+			return false, errors.New("no free vlan numbers in range " + strconv.Itoa(opts.RangeStart) + "-" + strconv.Itoa(opts.RangeEnd))
+		}
+
+		vlan, err = api.SetVLAN(&VLAN{
+			Number:      n,
+			Name:        opts.Name,
+			Description: opts.Description,
+			Notes:       opts.Notes,
+			Tags:        mergeTags(opts.Tags, opts.ScopeTags),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		won, err := api.wonVLAN(vlan, opts)
+		if err != nil || won {
+			return won, err
+		}
+
+		// someone else got there first, give the number back
+		if err := api.DeleteVLAN(vlan.VlanID); err != nil {
+			return false, err
+		}
+		if api.IsLoggingDebug() {
+			api.WriteToDebugLog("vlan allocation collision on number " + strconv.Itoa(n) + ", retrying")
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !won {
+		return nil, errors.New("unable to allocate vlan: too many collisions")
+	}
+
+	return vlan, nil
+}
+
+// NextFreeVLANNumber will return the lowest vlan number within a range that
+// is not used by any of the vlans
+func NextFreeVLANNumber(vlans []VLAN, rangeStart, rangeEnd int) (int, bool) {
+	used := make(map[int]bool, len(vlans))
+	for _, v := range vlans {
+		used[v.Number] = true
+	}
+
+	for n := rangeStart; n <= rangeEnd; n++ {
+		if !used[n] {
+			return n, true
+		}
+	}
+
+	return 0, false
+}
+
+// scopedVLANs will list the vlans that count as used for an allocation
+func (api *API) scopedVLANs(opts AllocateVLANOptions) ([]VLAN, error) {
+	var (
+		vlans *[]VLAN
+		err   error
+	)
+
+	if len(opts.ScopeTags) > 0 {
+		vlans, err = api.GetVLANsByAnyTags(opts.ScopeTags)
+	} else {
+		vlans, err = api.GetVLANs()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return *vlans, nil
+}
+
+// wonVLAN checks if a created vlan is the oldest vlan in scope with its number
+func (api *API) wonVLAN(vlan *VLAN, opts AllocateVLANOptions) (bool, error) {
+	vlans, err := api.GetVLANsByNumber(vlan.Number)
+	if err != nil {
+		return false, err
+	}
+
+	contenders := []VLAN{*vlan}
+	for _, v := range *vlans {
+		if v.VlanID != vlan.VlanID && (len(opts.ScopeTags) == 0 || hasAnyTag(v.Tags, opts.ScopeTags)) {
+			contenders = append(contenders, v)
+		}
+	}
+	sort.Slice(contenders, func(i, j int) bool { return contenders[i].VlanID < contenders[j].VlanID })
+
+	return contenders[0].VlanID == vlan.VlanID, nil
+}
+
+// hasAnyTag checks if any of the tags are in a list of tags
+func hasAnyTag(tags, any []string) bool {
+	for _, t := range tags {
+		for _, a := range any {
+			if t == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeTags will add the tags missing from tags
+func mergeTags(tags, more []string) []string {
+	merged := append([]string{}, tags...)
+	for _, t := range more {
+		if !hasAnyTag(merged, []string{t}) {
+			merged = append(merged, t)
+		}
+	}
+	return merged
+}
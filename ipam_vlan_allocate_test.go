@@ -0,0 +1,186 @@
+package device42
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextFreeVLANNumber(t *testing.T) {
+	vlans := []VLAN{{Number: 100}, {Number: 101}, {Number: 103}, {Number: 103}}
+
+	tests := []struct {
+		name       string
+		start, end int
+		want       int
+		wantOK     bool
+	}{
+		{name: "gap", start: 100, end: 110, want: 102, wantOK: true},
+		{name: "start free", start: 90, end: 110, want: 90, wantOK: true},
+		{name: "single free", start: 102, end: 102, want: 102, wantOK: true},
+		{name: "after used", start: 103, end: 104, want: 104, wantOK: true},
+		{name: "full", start: 100, end: 101, wantOK: false},
+		{name: "empty range", start: 110, end: 100, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NextFreeVLANNumber(vlans, tt.start, tt.end)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("%s: NextFreeVLANNumber(%d, %d) = %d, %v, want %d, %v", tt.name, tt.start, tt.end, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestHasAnyTag(t *testing.T) {
+	tests := []struct {
+		tags, any []string
+		want      bool
+	}{
+		{tags: []string{"a", "b"}, any: []string{"b"}, want: true},
+		{tags: []string{"a", "b"}, any: []string{"c", "a"}, want: true},
+		{tags: []string{"a"}, any: []string{"A"}, want: false},
+		{tags: nil, any: []string{"a"}, want: false},
+		{tags: []string{"a"}, any: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := hasAnyTag(tt.tags, tt.any); got != tt.want {
+			t.Errorf("hasAnyTag(%v, %v) = %v, want %v", tt.tags, tt.any, got, tt.want)
+		}
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	tests := []struct {
+		tags, more []string
+		want       []string
+	}{
+		{tags: nil, more: nil, want: []string{}},
+		{tags: []string{"web"}, more: nil, want: []string{"web"}},
+		{tags: nil, more: []string{"prod"}, want: []string{"prod"}},
+		{tags: []string{"web", "prod"}, more: []string{"prod", "dmz"}, want: []string{"web", "prod", "dmz"}},
+	}
+
+	for _, tt := range tests {
+		if got := mergeTags(tt.tags, tt.more); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mergeTags(%v, %v) = %v, want %v", tt.tags, tt.more, got, tt.want)
+		}
+	}
+}
+
+// vlanServer type
+// vlans kept in memory. competitor, when set, is called before a vlan is
+// created and may create one of its own
+type vlanServer struct {
+	vlans      []VLAN
+	nextID     int
+	posted     []string
+	deleted    []int
+	competitor func(s *vlanServer, number int)
+}
+
+func (v *vlanServer) create(number int, name string, tags []string) int {
+	v.nextID++
+	v.vlans = append(v.vlans, VLAN{VlanID: v.nextID, Number: number, Name: name, Tags: tags})
+	return v.nextID
+}
+
+func (v *vlanServer) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/vlans/":
+			writeJSON(w, VLANs{List: v.vlans})
+		case r.Method == "GET" && r.URL.Path == "/vlans":
+			l := []VLAN{}
+			for _, i := range v.vlans {
+				if (q.Get("tags") != "" && hasAnyTag(i.Tags, strings.Split(q.Get("tags"), ","))) ||
+					(q.Get("number") != "" && strconv.Itoa(i.Number) == q.Get("number")) {
+					l = append(l, i)
+				}
+			}
+			writeJSON(w, VLANs{List: l})
+		case r.Method == "POST" && r.URL.Path == "/vlans/":
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			v.posted = append(v.posted, r.PostForm.Encode())
+			number, _ := strconv.Atoi(r.PostForm.Get("number"))
+			if v.competitor != nil {
+				v.competitor(v, number)
+			}
+			writeCreated(w, v.create(number, r.PostForm.Get("name"), strings.Split(r.PostForm.Get("tags"), ",")))
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/vlans/"):
+			id, _ := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/vlans/"), "/"))
+			v.deleted = append(v.deleted, id)
+			for n, i := range v.vlans {
+				if i.VlanID == id {
+					v.vlans = append(v.vlans[:n], v.vlans[n+1:]...)
+					break
+				}
+			}
+			writeJSON(w, map[string]interface{}{"deleted": true})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.Error(w, "{}", http.StatusNotFound)
+		}
+	}
+}
+
+func TestAllocateVLANScoped(t *testing.T) {
+	v := &vlanServer{nextID: 10}
+	v.create(100, "prod-web", []string{"prod"})
+	v.create(101, "lab-web", []string{"lab"})
+	api := newTestAPI(t, v.handle(t))
+
+	opts := AllocateVLANOptions{RangeStart: 100, RangeEnd: 110, Name: "web", Tags: []string{"web"}, ScopeTags: []string{"prod"}}
+
+	// the scope tags are set on the first vlan, so the second one counts it
+	got := []int{}
+	for i := 0; i < 2; i++ {
+		vlan, err := api.AllocateVLANWithOptions(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, vlan.Number)
+	}
+	if want := []int{101, 102}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allocated numbers %v, want %v", got, want)
+	}
+	want := []string{"name=web&number=101&tags=web%2Cprod", "name=web&number=102&tags=web%2Cprod"}
+	if !reflect.DeepEqual(v.posted, want) {
+		t.Errorf("posted %v, want %v", v.posted, want)
+	}
+}
+
+func TestAllocateVLANCollision(t *testing.T) {
+	v := &vlanServer{nextID: 10}
+	// another client creates the same number first, once
+	v.competitor = func(s *vlanServer, number int) {
+		s.competitor = nil
+		s.create(number, "theirs", []string{"prod"})
+	}
+	api := newTestAPI(t, v.handle(t))
+
+	vlan, err := api.AllocateVLANWithOptions(context.Background(), AllocateVLANOptions{
+		RangeStart: 100,
+		RangeEnd:   110,
+		Name:       "web",
+		ScopeTags:  []string{"prod"},
+		RetryDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vlan.Number != 101 || vlan.Name != "web" {
+		t.Errorf("AllocateVLANWithOptions = %+v, want number 101", vlan)
+	}
+	// the losing vlan, created after the competitor's, is given back
+	if want := []int{12}; !reflect.DeepEqual(v.deleted, want) {
+		t.Errorf("deleted %v, want %v", v.deleted, want)
+	}
+}
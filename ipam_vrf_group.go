@@ -94,6 +94,10 @@ func (api *API) SetVRFGroup(v *VRFGroup) (*VRFGroup, error) {
 		return nil, err
 	}
 
+	if api.IsDryRun() {
+		return v, nil
+	}
+
 	vrfGroup, err := api.GetVRFGroupByName(v.Name)
 	if err != nil {
 		return nil, err
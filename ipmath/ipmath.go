@@ -0,0 +1,247 @@
+// Package ipmath provides offline subnet math for planning ipam changes
+// without touching device42. it works on net/netip prefixes and addresses
+// and supports both ipv4 and ipv6
+package ipmath
+
+import (
+	"errors"
+	"math/big"
+	"net/netip"
+	"sort"
+	"strconv"
+)
+
+// MaxSplit is the maximum number of prefixes Split will return
+const MaxSplit = 1 << 16
+
+// Contains checks if inner is fully contained within outer
+func Contains(outer, inner netip.Prefix) bool {
+	outer, inner = outer.Masked(), inner.Masked()
+	return outer.Addr().BitLen() == inner.Addr().BitLen() &&
+		outer.Bits() <= inner.Bits() &&
+		outer.Contains(inner.Addr())
+}
+
+// Overlaps checks if two prefixes share any addresses
+func Overlaps(a, b netip.Prefix) bool {
+	return a.Masked().Overlaps(b.Masked())
+}
+
+// First returns the first address of a prefix
+func First(p netip.Prefix) netip.Addr {
+	return p.Masked().Addr()
+}
+
+// Last returns the last address of a prefix
+func Last(p netip.Prefix) netip.Addr {
+	p = p.Masked()
+	b := p.Addr().AsSlice()
+
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+// Size returns the number of addresses within a prefix
+func Size(p netip.Prefix) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(p.Addr().BitLen()-p.Bits()))
+}
+
+// Split will divide a prefix into all of its children with the given mask bits
+func Split(p netip.Prefix, bits int) ([]netip.Prefix, error) {
+	p = p.Masked()
+	if bits < p.Bits() || bits > p.Addr().BitLen() {
+		return nil, errors.New("cannot split " + p.String() + " into /" + strconv.Itoa(bits))
+	}
+	if bits-p.Bits() > 16 {
+		return nil, errors.New("splitting " + p.String() + " into /" + strconv.Itoa(bits) + " exceeds " + strconv.Itoa(MaxSplit) + " prefixes")
+	}
+
+	n := 1 << (bits - p.Bits())
+	step := blockSize(p.Addr().BitLen(), bits)
+	prefixes := make([]netip.Prefix, 0, n)
+
+	cur := toInt(p.Addr())
+	for i := 0; i < n; i++ {
+		prefixes = append(prefixes, netip.PrefixFrom(toAddr(cur, p.Addr().BitLen()), bits))
+		cur = new(big.Int).Add(cur, step)
+	}
+
+	return prefixes, nil
+}
+
+// Summarize will merge prefixes into the smallest set of prefixes covering
+// the same addresses. contained and adjacent prefixes are collapsed
+func Summarize(prefixes []netip.Prefix) []netip.Prefix {
+	ps := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p.IsValid() {
+			ps = append(ps, p.Masked())
+		}
+	}
+	sortPrefixes(ps)
+
+	for {
+		merged := false
+		out := make([]netip.Prefix, 0, len(ps))
+
+		for _, p := range ps {
+			if len(out) == 0 {
+				out = append(out, p)
+				continue
+			}
+
+			last := out[len(out)-1]
+			switch {
+			case Contains(last, p):
+				merged = true
+			case siblings(last, p):
+				out[len(out)-1] = netip.PrefixFrom(last.Addr(), last.Bits()-1).Masked()
+				merged = true
+			default:
+				out = append(out, p)
+			}
+		}
+
+		ps = out
+		if !merged {
+			return ps
+		}
+	}
+}
+
+// NextFree will return the lowest prefix with the given mask bits inside the
+// parent that does not overlap any of the used prefixes
+func NextFree(parent netip.Prefix, bits int, used []netip.Prefix) (netip.Prefix, bool) {
+	parent = parent.Masked()
+	bitLen := parent.Addr().BitLen()
+	if bits < parent.Bits() || bits > bitLen {
+		return netip.Prefix{}, false
+	}
+
+	u := make([]netip.Prefix, 0, len(used))
+	for _, p := range used {
+		if p.IsValid() && Overlaps(parent, p) {
+			u = append(u, p.Masked())
+		}
+	}
+	sortPrefixes(u)
+
+	step := blockSize(bitLen, bits)
+	end := toInt(Last(parent))
+	cur := toInt(parent.Addr())
+
+	for cur.Cmp(end) <= 0 {
+		candidate := netip.PrefixFrom(toAddr(cur, bitLen), bits)
+
+		var conflict *netip.Prefix
+		for i := range u {
+			if Overlaps(candidate, u[i]) {
+				conflict = &u[i]
+				break
+			}
+		}
+		if conflict == nil {
+			return candidate, true
+		}
+
+		// skip past the conflicting prefix, aligned to the block size
+		next := new(big.Int).Add(toInt(Last(*conflict)), big.NewInt(1))
+		if next.Cmp(cur) <= 0 {
+			next = new(big.Int).Add(cur, step)
+		}
+		cur = alignUp(next, step)
+	}
+
+	return netip.Prefix{}, false
+}
+
+// Request type
+// a number of prefixes with the same mask bits to carve out of a parent
+type Request struct {
+	Bits  int
+	Count int
+}
+
+// Plan will carve the requested prefixes out of a parent around the used
+// prefixes. larger blocks are placed first to keep the parent packed, and
+// the result is sorted by address
+func Plan(parent netip.Prefix, used []netip.Prefix, requests []Request) ([]netip.Prefix, error) {
+	bitLen := parent.Addr().BitLen()
+	for _, r := range requests {
+		if r.Bits < parent.Bits() || r.Bits > bitLen {
+			return nil, errors.New("invalid size /" + strconv.Itoa(r.Bits) + " for " + parent.String() +
+				", expected /" + strconv.Itoa(parent.Bits()) + " to /" + strconv.Itoa(bitLen))
+		}
+	}
+
+	reqs := append([]Request(nil), requests...)
+	sort.SliceStable(reqs, func(i, j int) bool { return reqs[i].Bits < reqs[j].Bits })
+
+	taken := append([]netip.Prefix(nil), used...)
+	planned := []netip.Prefix{}
+
+	for _, r := range reqs {
+		for i := 0; i < r.Count; i++ {
+			p, ok := NextFree(parent, r.Bits, taken)
+			if !ok {
+				return nil, errors.New("not enough free space in " + parent.String() + " for /" + strconv.Itoa(r.Bits))
+			}
+			taken = append(taken, p)
+			planned = append(planned, p)
+		}
+	}
+
+	sortPrefixes(planned)
+	return planned, nil
+}
+
+// siblings checks if two prefixes are the two halves of the same parent
+func siblings(a, b netip.Prefix) bool {
+	if a.Bits() != b.Bits() || a.Bits() == 0 || a.Addr().BitLen() != b.Addr().BitLen() {
+		return false
+	}
+	pa := netip.PrefixFrom(a.Addr(), a.Bits()-1).Masked()
+	pb := netip.PrefixFrom(b.Addr(), b.Bits()-1).Masked()
+	return pa == pb && a != b
+}
+
+// sortPrefixes sorts by family, address, then mask bits
+func sortPrefixes(ps []netip.Prefix) {
+	sort.Slice(ps, func(i, j int) bool {
+		if c := ps[i].Addr().Compare(ps[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return ps[i].Bits() < ps[j].Bits()
+	})
+}
+
+// blockSize returns the number of addresses in a prefix of the given mask bits
+func blockSize(bitLen, bits int) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(bitLen-bits))
+}
+
+// alignUp rounds n up to the next multiple of step
+func alignUp(n, step *big.Int) *big.Int {
+	m := new(big.Int).Mod(n, step)
+	if m.Sign() == 0 {
+		return n
+	}
+	return new(big.Int).Add(new(big.Int).Sub(n, m), step)
+}
+
+// toInt converts an address into an integer
+func toInt(a netip.Addr) *big.Int {
+	return new(big.Int).SetBytes(a.AsSlice())
+}
+
+// toAddr converts an integer into an address of the given bit length
+func toAddr(n *big.Int, bitLen int) netip.Addr {
+	b := make([]byte, bitLen/8)
+	n.FillBytes(b)
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
@@ -0,0 +1,260 @@
+package ipmath
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func prefixes(s ...string) []netip.Prefix {
+	ps := make([]netip.Prefix, 0, len(s))
+	for _, i := range s {
+		ps = append(ps, netip.MustParsePrefix(i))
+	}
+	return ps
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		outer, inner string
+		want         bool
+	}{
+		{outer: "10.0.0.0/8", inner: "10.1.0.0/16", want: true},
+		{outer: "10.0.0.0/8", inner: "10.0.0.0/8", want: true},
+		{outer: "10.1.0.0/16", inner: "10.0.0.0/8", want: false},
+		{outer: "10.0.0.0/8", inner: "11.0.0.0/16", want: false},
+		{outer: "0.0.0.0/0", inner: "255.255.255.255/32", want: true},
+		{outer: "10.0.0.5/8", inner: "10.1.0.0/16", want: true},
+		{outer: "2001:db8::/32", inner: "2001:db8:1::/48", want: true},
+		{outer: "::/0", inner: "10.0.0.0/8", want: false},
+		{outer: "0.0.0.0/0", inner: "::/0", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := Contains(netip.MustParsePrefix(tt.outer), netip.MustParsePrefix(tt.inner)); got != tt.want {
+			t.Errorf("Contains(%s, %s) = %v, want %v", tt.outer, tt.inner, got, tt.want)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "10.0.0.0/24", b: "10.0.0.128/25", want: true},
+		{a: "10.0.0.0/25", b: "10.0.0.128/25", want: false},
+		{a: "2001:db8::/64", b: "2001:db8::1/128", want: true},
+		{a: "10.0.0.0/8", b: "::/0", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := Overlaps(netip.MustParsePrefix(tt.a), netip.MustParsePrefix(tt.b)); got != tt.want {
+			t.Errorf("Overlaps(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFirstLastSize(t *testing.T) {
+	tests := []struct {
+		prefix string
+		first  string
+		last   string
+		size   string
+	}{
+		{prefix: "10.0.0.0/24", first: "10.0.0.0", last: "10.0.0.255", size: "256"},
+		{prefix: "10.0.0.77/24", first: "10.0.0.0", last: "10.0.0.255", size: "256"},
+		{prefix: "10.0.0.1/32", first: "10.0.0.1", last: "10.0.0.1", size: "1"},
+		{prefix: "10.0.0.0/31", first: "10.0.0.0", last: "10.0.0.1", size: "2"},
+		{prefix: "0.0.0.0/0", first: "0.0.0.0", last: "255.255.255.255", size: "4294967296"},
+		{prefix: "10.0.0.0/9", first: "10.0.0.0", last: "10.127.255.255", size: "8388608"},
+		{prefix: "2001:db8::/64", first: "2001:db8::", last: "2001:db8::ffff:ffff:ffff:ffff", size: "18446744073709551616"},
+		{prefix: "2001:db8::1/128", first: "2001:db8::1", last: "2001:db8::1", size: "1"},
+		{prefix: "::/0", first: "::", last: "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", size: "340282366920938463463374607431768211456"},
+	}
+
+	for _, tt := range tests {
+		p := netip.MustParsePrefix(tt.prefix)
+		if got := First(p).String(); got != tt.first {
+			t.Errorf("First(%s) = %s, want %s", tt.prefix, got, tt.first)
+		}
+		if got := Last(p).String(); got != tt.last {
+			t.Errorf("Last(%s) = %s, want %s", tt.prefix, got, tt.last)
+		}
+		if got := Size(p).String(); got != tt.size {
+			t.Errorf("Size(%s) = %s, want %s", tt.prefix, got, tt.size)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		bits    int
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{prefix: "10.0.0.0/24", bits: 26, want: prefixes("10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26")},
+		{prefix: "10.0.0.0/24", bits: 24, want: prefixes("10.0.0.0/24")},
+		{prefix: "10.0.0.5/24", bits: 25, want: prefixes("10.0.0.0/25", "10.0.0.128/25")},
+		{prefix: "255.255.255.252/30", bits: 32, want: prefixes("255.255.255.252/32", "255.255.255.253/32", "255.255.255.254/32", "255.255.255.255/32")},
+		{prefix: "2001:db8::/126", bits: 127, want: prefixes("2001:db8::/127", "2001:db8::2/127")},
+		{prefix: "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127", bits: 128, want: prefixes("ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/128", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff/128")},
+		{prefix: "10.0.0.0/24", bits: 23, wantErr: true},
+		{prefix: "10.0.0.0/24", bits: 33, wantErr: true},
+		{prefix: "2001:db8::/32", bits: 129, wantErr: true},
+		{prefix: "10.0.0.0/8", bits: 25, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Split(netip.MustParsePrefix(tt.prefix), tt.bits)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Split(%s, %d) error = %v, wantErr %v", tt.prefix, tt.bits, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%s, %d) = %v, want %v", tt.prefix, tt.bits, got, tt.want)
+		}
+	}
+}
+
+func TestSplitMax(t *testing.T) {
+	got, err := Split(netip.MustParsePrefix("10.0.0.0/8"), 24)
+	if err != nil {
+		t.Fatalf("Split(10.0.0.0/8, 24) error = %v", err)
+	}
+	if len(got) != MaxSplit {
+		t.Errorf("Split(10.0.0.0/8, 24) returned %d prefixes, want %d", len(got), MaxSplit)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []netip.Prefix
+		want []netip.Prefix
+	}{
+		{name: "empty", in: nil, want: []netip.Prefix{}},
+		{name: "siblings", in: prefixes("10.0.0.0/25", "10.0.0.128/25"), want: prefixes("10.0.0.0/24")},
+		{name: "cascade", in: prefixes("10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/25"), want: prefixes("10.0.0.0/24")},
+		{name: "contained", in: prefixes("10.0.0.0/24", "10.0.0.64/26"), want: prefixes("10.0.0.0/24")},
+		{name: "duplicates", in: prefixes("10.0.0.0/24", "10.0.0.0/24"), want: prefixes("10.0.0.0/24")},
+		{name: "adjacent not aligned", in: prefixes("10.0.1.0/24", "10.0.2.0/24"), want: prefixes("10.0.1.0/24", "10.0.2.0/24")},
+		{name: "unmasked", in: prefixes("10.0.0.1/25", "10.0.0.129/25"), want: prefixes("10.0.0.0/24")},
+		{name: "families kept apart", in: prefixes("::/1", "128.0.0.0/1", "0.0.0.0/1"), want: prefixes("0.0.0.0/0", "::/1")},
+		{name: "ipv6", in: prefixes("2001:db8::/49", "2001:db8:0:8000::/49"), want: prefixes("2001:db8::/48")},
+		{name: "whole space", in: prefixes("0.0.0.0/1", "128.0.0.0/1"), want: prefixes("0.0.0.0/0")},
+	}
+
+	for _, tt := range tests {
+		if got := Summarize(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Summarize(%v) = %v, want %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNextFree(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent string
+		bits   int
+		used   []netip.Prefix
+		want   string
+		wantOK bool
+	}{
+		{name: "empty", parent: "10.0.0.0/24", bits: 26, want: "10.0.0.0/26", wantOK: true},
+		{name: "after used", parent: "10.0.0.0/24", bits: 26, used: prefixes("10.0.0.0/26"), want: "10.0.0.64/26", wantOK: true},
+		{name: "aligned past a small block", parent: "10.0.0.0/24", bits: 26, used: prefixes("10.0.0.8/29"), want: "10.0.0.64/26", wantOK: true},
+		{name: "gap", parent: "10.0.0.0/24", bits: 26, used: prefixes("10.0.0.0/26", "10.0.0.128/25"), want: "10.0.0.64/26", wantOK: true},
+		{name: "full", parent: "10.0.0.0/24", bits: 25, used: prefixes("10.0.0.0/25", "10.0.0.128/25"), wantOK: false},
+		{name: "used covers parent", parent: "10.0.0.0/24", bits: 26, used: prefixes("10.0.0.0/16"), wantOK: false},
+		{name: "outside used ignored", parent: "10.0.0.0/24", bits: 24, used: prefixes("10.0.1.0/24"), want: "10.0.0.0/24", wantOK: true},
+		{name: "whole parent", parent: "10.0.0.0/24", bits: 24, want: "10.0.0.0/24", wantOK: true},
+		{name: "too large", parent: "10.0.0.0/24", bits: 23, wantOK: false},
+		{name: "too small", parent: "10.0.0.0/24", bits: 33, wantOK: false},
+		{name: "end of the address space", parent: "255.255.255.0/24", bits: 25, used: prefixes("255.255.255.0/25"), want: "255.255.255.128/25", wantOK: true},
+		{name: "end of the address space full", parent: "255.255.255.0/24", bits: 25, used: prefixes("255.255.255.0/25", "255.255.255.128/25"), wantOK: false},
+		{name: "host", parent: "10.0.0.0/30", bits: 32, used: prefixes("10.0.0.0/31"), want: "10.0.0.2/32", wantOK: true},
+		{name: "ipv6", parent: "2001:db8::/48", bits: 64, used: prefixes("2001:db8::/64"), want: "2001:db8:0:1::/64", wantOK: true},
+		{name: "ipv6 end of the address space", parent: "ffff:ffff:ffff:ffff::/64", bits: 65, used: prefixes("ffff:ffff:ffff:ffff::/65"), want: "ffff:ffff:ffff:ffff:8000::/65", wantOK: true},
+		{name: "ipv6 full", parent: "2001:db8::/127", bits: 128, used: prefixes("2001:db8::/128", "2001:db8::1/128"), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NextFree(netip.MustParsePrefix(tt.parent), tt.bits, tt.used)
+		if ok != tt.wantOK {
+			t.Errorf("%s: NextFree ok = %v, want %v", tt.name, ok, tt.wantOK)
+			continue
+		}
+		if ok && got.String() != tt.want {
+			t.Errorf("%s: NextFree = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		parent   string
+		used     []netip.Prefix
+		requests []Request
+		want     []netip.Prefix
+		wantErr  bool
+	}{
+		{
+			name:     "larger first",
+			parent:   "10.0.0.0/24",
+			requests: []Request{{Bits: 27, Count: 2}, {Bits: 25, Count: 1}},
+			want:     prefixes("10.0.0.0/25", "10.0.0.128/27", "10.0.0.160/27"),
+		},
+		{
+			name:     "around used",
+			parent:   "10.0.0.0/24",
+			used:     prefixes("10.0.0.0/26"),
+			requests: []Request{{Bits: 26, Count: 3}},
+			want:     prefixes("10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26"),
+		},
+		{
+			name:     "not enough space",
+			parent:   "10.0.0.0/24",
+			used:     prefixes("10.0.0.0/25"),
+			requests: []Request{{Bits: 25, Count: 2}},
+			wantErr:  true,
+		},
+		{
+			name:     "larger than parent",
+			parent:   "10.0.0.0/24",
+			requests: []Request{{Bits: 23, Count: 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "ipv6 size for ipv4 parent",
+			parent:   "10.0.0.0/24",
+			requests: []Request{{Bits: 64, Count: 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "ipv6",
+			parent:   "2001:db8::/48",
+			requests: []Request{{Bits: 64, Count: 2}, {Bits: 56, Count: 1}},
+			want:     prefixes("2001:db8::/56", "2001:db8:0:100::/64", "2001:db8:0:101::/64"),
+		},
+		{
+			name:     "no requests",
+			parent:   "10.0.0.0/24",
+			requests: nil,
+			want:     []netip.Prefix{},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Plan(netip.MustParsePrefix(tt.parent), tt.used, tt.requests)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Plan error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Plan = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
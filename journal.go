@@ -0,0 +1,573 @@
+package device42
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chopnico/device42-go/internal/utilities"
+	"github.com/google/uuid"
+)
+
+// journal actions
+const (
+	JournalActionCreate  = "create"
+	JournalActionUpdate  = "update"
+	JournalActionDelete  = "delete"
+	JournalActionReserve = "reserve"
+)
+
+// JournalEntry type
+// a single change made through the api
+type JournalEntry struct {
+	Time     time.Time       `json:"time"`
+	Action   string          `json:"action"`
+	Resource string          `json:"resource"`
+	ObjectID int             `json:"object_id,omitempty"`
+	Address  string          `json:"address,omitempty"`
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Params   url.Values      `json:"params,omitempty"`
+	Prior    json.RawMessage `json:"prior,omitempty"`
+	// Unrecoverable is why the change can not be undone, e.g. its prior
+	// state could not be read
+	Unrecoverable string `json:"unrecoverable,omitempty"`
+}
+
+// Journal type
+// a record of the changes made through the api, which can be undone
+type Journal struct {
+	ID          string         `json:"id"`
+	Description string         `json:"description"`
+	Created     time.Time      `json:"created"`
+	Undone      *time.Time     `json:"undone,omitempty"`
+	Entries     []JournalEntry `json:"entries"`
+
+	dir string
+	mu  sync.Mutex
+}
+
+// journalResources maps api resources to the types used for their prior state
+var journalResources = map[string]func() interface{}{
+	"ips":       func() interface{} { return &IP{} },
+	"subnets":   func() interface{} { return &Subnet{} },
+	"vlans":     func() interface{} { return &VLAN{} },
+	"vrfgroup":  func() interface{} { return &VRFGroup{} },
+	"buildings": func() interface{} { return &Building{} },
+}
+
+// DefaultJournalDir returns the directory journals are kept in by default
+func DefaultJournalDir() string {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		d = os.TempDir()
+	}
+	return filepath.Join(d, "device42", "journal")
+}
+
+// NewJournal creates a new journal kept in dir. nothing is written until the
+// first change is recorded
+func NewJournal(dir, description string) *Journal {
+	now := time.Now()
+	return &Journal{
+		ID:          now.Format("20060102-150405") + "-" + uuid.New().String()[:8],
+		Description: description,
+		Created:     now,
+		Entries:     []JournalEntry{},
+		dir:         dir,
+	}
+}
+
+// OpenJournal reads a journal from dir
+func OpenJournal(dir, id string) (*Journal, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, errors.New("invalid journal id " + id)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("unable to find journal with id " + id)
+		}
+		return nil, err
+	}
+
+	j := Journal{dir: dir}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, err
+	}
+
+	return &j, nil
+}
+
+// ListJournals returns all journals in dir, newest first
+func ListJournals(dir string) ([]*Journal, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	journals := []*Journal{}
+	for _, f := range files {
+		j, err := OpenJournal(dir, strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		journals = append(journals, j)
+	}
+	sort.Slice(journals, func(i, j int) bool { return journals[i].Created.After(journals[j].Created) })
+
+	return journals, nil
+}
+
+// Len returns the number of recorded changes
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.Entries)
+}
+
+// Save writes the journal to its directory
+func (j *Journal) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.save()
+}
+
+func (j *Journal) save() error {
+	if err := os.MkdirAll(j.dir, 0700); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(j.dir, j.ID+".json"), b, 0600)
+}
+
+// record adds an entry and persists the journal straight away, so changes
+// are not lost if the process dies half way through
+func (j *Journal) record(e JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e.Time = time.Now()
+	j.Entries = append(j.Entries, e)
+
+	return j.save()
+}
+
+// Journal will record every create, update and delete made through the api
+// in j
+func (api *API) Journal(j *Journal) *API {
+	api.option("journal", j)
+	return api
+}
+
+// journal returns the journal changes are recorded in, if any
+func (api *API) journal() *Journal {
+	j, _ := api.options["journal"].(*Journal)
+	return j
+}
+
+// doJournaled will perform a request and record it in the journal. the prior
+// state of updated and deleted objects is fetched before the request is made.
+// journaling is bookkeeping, so when it fails the problem is logged and the
+// request is still made
+func (api *API) doJournaled(j *Journal, method, path string, body io.Reader) ([]byte, error) {
+	var raw []byte
+	if body != nil {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+		body = bytes.NewReader(raw)
+	}
+
+	params, _ := url.ParseQuery(string(raw))
+	resource, id := journalResource(path)
+	e := JournalEntry{
+		Method:   method,
+		Path:     path,
+		Resource: resource,
+		ObjectID: id,
+		Params:   params,
+	}
+
+	// suggest_ip with reserve_ip=yes is a GET that reserves an ip
+	if isReserving(method, path) {
+		b, err := api.do(method, path, body)
+		if err != nil {
+			return nil, err
+		}
+		ip := IP{}
+		if json.Unmarshal(b, &ip) == nil && ip.Address != "" {
+			e.Action = JournalActionReserve
+			e.Resource = "ips"
+			e.Address = ip.Address
+			api.recordJournal(j, e)
+		}
+		return b, nil
+	}
+
+	if _, ok := journalResources[resource]; !ok {
+		api.WriteToInfoLog("journal : changes to " + path + " are not journaled and can not be undone")
+		return api.do(method, path, body)
+	}
+
+	var err error
+	switch {
+	case method == "DELETE":
+		e.Action = JournalActionDelete
+		e.Prior, err = api.priorByID(resource, id)
+	case method == "PUT" && id != 0:
+		e.Action = JournalActionUpdate
+		e.Prior, err = api.priorByID(resource, id)
+	default:
+		// without the prior state a post may be a create or an update
+		e.Action = JournalActionUpdate
+		e.Prior, err = api.priorByParams(resource, path, params)
+		if err == nil && e.Prior == nil {
+			e.Action = JournalActionCreate
+		}
+	}
+	if err != nil {
+		e.Unrecoverable = "the prior state could not be read: " + err.Error()
+		api.WriteToInfoLog("journal : " + method + " " + path + " can not be undone, " + e.Unrecoverable)
+	}
+
+	b, err := api.do(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Action == JournalActionCreate {
+		e.ObjectID = api.createdID(resource, b, params)
+	}
+	api.recordJournal(j, e)
+
+	return b, nil
+}
+
+// recordJournal will record a change that has been made. failing to record
+// it is logged, as the change can not be taken back
+func (api *API) recordJournal(j *Journal, e JournalEntry) {
+	if err := j.record(e); err != nil {
+		api.WriteToInfoLog("journal : unable to record " + e.Method + " " + e.Path + ": " + err.Error())
+	}
+}
+
+// journalResource splits a path into its resource and object id
+func journalResource(path string) (string, int) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", 0
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	id := 0
+	if len(parts) > 1 {
+		id, _ = strconv.Atoi(parts[1])
+	}
+
+	return parts[0], id
+}
+
+// isReserving checks if a GET request reserves an ip
+func isReserving(method, path string) bool {
+	return method == "GET" && strings.HasPrefix(path, "/suggest_ip") && strings.Contains(path, "reserve_ip=yes")
+}
+
+// priorByID fetches the current state of an object by id
+func (api *API) priorByID(resource string, id int) (json.RawMessage, error) {
+	var (
+		v   interface{}
+		err error
+	)
+
+	switch resource {
+	case "ips":
+		v, err = api.GetIPByID(id)
+	case "subnets":
+		v, err = api.GetSubnetByID(id)
+	case "vlans":
+		v, err = api.GetVLANByID(id)
+	case "vrfgroup":
+		v, err = api.GetVRFGroupByID(id)
+	case "buildings":
+		v, err = api.GetBuildingByID(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// priorByParams fetches the current state of the object a post would
+// update. nil is returned when the post creates a new object
+func (api *API) priorByParams(resource, path string, params url.Values) (json.RawMessage, error) {
+	var v interface{}
+
+	switch resource {
+	case "ips":
+		a := params.Get("ipaddress")
+		if a == "" {
+			return nil, nil
+		}
+		ip, err := api.findIP(a, params.Get("subnet_id"), params.Get("subnet"))
+		if err != nil {
+			return nil, err
+		}
+		if ip == nil {
+			return nil, nil
+		}
+		v = ip
+	case "subnets":
+		if strings.Contains(path, "create_child") {
+			return nil, nil
+		}
+		s, err := api.findSubnet(params.Get("network"), params.Get("mask_bits"), params.Get("vrf_group_id"), params.Get("vrf_group"))
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return nil, nil
+		}
+		v = s
+	case "vlans":
+		// posted vlans are always created, numbers are not unique. updates are a put by id
+		return nil, nil
+	case "vrfgroup":
+		groups, err := api.GetVRFGroups()
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range *groups {
+			if g.Name == params.Get("name") {
+				v = g
+			}
+		}
+	case "buildings":
+		buildings, err := api.GetBuildings()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range *buildings {
+			if b.Name == params.Get("name") {
+				v = b
+			}
+		}
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
+
+// findIP will look up an ip by address, returning nil if it does not exist
+func (api *API) findIP(address, subnetID, subnet string) (*IP, error) {
+	q := "/ips/?address=" + url.QueryEscape(address)
+	if subnetID != "" {
+		q += "&subnet_id=" + url.QueryEscape(subnetID)
+	} else if subnet != "" {
+		q += "&subnet=" + url.QueryEscape(subnet)
+	}
+
+	b, err := api.do("GET", q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := IPs{}
+	if err := json.Unmarshal(b, &ips); err != nil {
+		return nil, err
+	}
+	for _, i := range ips.List {
+		if EqualAddresses(i.Address, address) {
+			return &i, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findSubnet will look up a subnet by network, returning nil if it does not exist
+func (api *API) findSubnet(network, maskBits, vrfGroupID, vrfGroup string) (*Subnet, error) {
+	b, err := api.do("GET", "/subnets/?network="+url.QueryEscape(network), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	subnets := Subnets{}
+	if err := json.Unmarshal(b, &subnets); err != nil {
+		return nil, err
+	}
+	for _, s := range subnets.List {
+		if !EqualAddresses(s.Network, network) || strconv.Itoa(s.MaskBits) != maskBits {
+			continue
+		}
+		if vrfGroupID != "" && strconv.Itoa(s.VrfGroupID) != vrfGroupID {
+			continue
+		}
+		if vrfGroup != "" && s.VrfGroupName != vrfGroup {
+			continue
+		}
+		return &s, nil
+	}
+
+	return nil, nil
+}
+
+// createdID will pull the id of a created object out of a response
+func (api *API) createdID(resource string, b []byte, params url.Values) int {
+	r := APIResponse{}
+	if json.Unmarshal(b, &r) == nil {
+		if m, ok := r.Message.([]interface{}); ok && len(m) > 1 {
+			if id, ok := m[1].(float64); ok {
+				return int(id)
+			}
+		}
+	}
+
+	// create_child answers with the new subnet itself
+	c := childSubnet{}
+	if json.Unmarshal(b, &c) == nil && c.ID != 0 {
+		return c.ID
+	}
+
+	// otherwise look the object up by what was posted
+	switch resource {
+	case "vrfgroup":
+		if g, err := api.GetVRFGroupByName(params.Get("name")); err == nil {
+			return g.ID
+		}
+	case "buildings":
+		if b, err := api.GetBuildingByName(params.Get("name")); err == nil {
+			return b.BuildingID
+		}
+	case "ips":
+		ip, err := api.findIP(params.Get("ipaddress"), params.Get("subnet_id"), params.Get("subnet"))
+		if err == nil && ip != nil {
+			return ip.ID
+		}
+	case "subnets":
+		s, err := api.findSubnet(params.Get("network"), params.Get("mask_bits"), params.Get("vrf_group_id"), params.Get("vrf_group"))
+		if err == nil && s != nil {
+			return s.SubnetID
+		}
+	}
+
+	return 0
+}
+
+// Undo will revert the changes recorded in a journal in reverse order. created
+// objects are deleted, updated objects are posted back with their prior state
+// and deleted objects are recreated (with a new id). fields which were empty
+// before an update are not cleared. in dry run mode the reverts are only
+// logged and the journal is left as it is
+func (api *API) Undo(j *Journal) error {
+	if j.Undone != nil {
+		return errors.New("journal " + j.ID + " was already undone")
+	}
+
+	// check every entry up front so a journal is not left half reverted
+	for _, e := range j.Entries {
+		if e.Unrecoverable != "" {
+			return errors.New("journal " + j.ID + " holds a change to " + e.Resource + " (" + e.Method + " " + e.Path +
+				") that can not be undone, " + e.Unrecoverable + ". it has to be reverted by hand")
+		}
+		if e.Action == JournalActionCreate && e.ObjectID == 0 {
+			return errors.New("journal " + j.ID + " holds a created " + e.Resource + " (" + e.Method + " " + e.Path +
+				") whose id was not recorded, it has to be removed by hand")
+		}
+	}
+
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		e := j.Entries[i]
+
+		if err := api.undoEntry(e); err != nil {
+			return fmt.Errorf("undo %s %s %d: %w", e.Action, e.Resource, e.ObjectID, err)
+		}
+		if api.IsDryRun() {
+			continue
+		}
+
+		// drop undone entries so a failed undo can be resumed
+		j.mu.Lock()
+		j.Entries = j.Entries[:i]
+		if i == 0 {
+			now := time.Now()
+			j.Undone = &now
+			j.Entries = []JournalEntry{}
+		}
+		err := j.save()
+		j.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (api *API) undoEntry(e JournalEntry) error {
+	switch e.Action {
+	case JournalActionReserve:
+		p := utilities.PostParameters(clearIP{Address: NormalizeAddress(e.Address), Clear: "yes"})
+		return api.revert("POST", "/ips/", strings.NewReader(p.Encode()))
+	case JournalActionCreate:
+		if e.ObjectID == 0 {
+			return errors.New("the id of the created object was not recorded")
+		}
+		return api.revert("DELETE", "/"+e.Resource+"/"+strconv.Itoa(e.ObjectID)+"/", nil)
+	case JournalActionUpdate, JournalActionDelete:
+		newPrior, ok := journalResources[e.Resource]
+		if !ok {
+			return errors.New("unknown resource " + e.Resource)
+		}
+		prior := newPrior()
+		if err := json.Unmarshal(e.Prior, prior); err != nil {
+			return err
+		}
+		if ip, ok := prior.(*IP); ok {
+			if err := normalizeIP(ip); err != nil {
+				return err
+			}
+		}
+		p := strings.NewReader(utilities.PostParameters(prior).Encode())
+		if e.Method == "PUT" {
+			return api.revert("PUT", e.Path, p)
+		}
+		return api.revert("POST", "/"+e.Resource+"/", p)
+	}
+
+	return errors.New("unknown action " + e.Action)
+}
+
+// revert will make an undo request. reverts are not journaled themselves,
+// and in dry run mode they are only logged
+func (api *API) revert(method, path string, body io.Reader) error {
+	if api.IsDryRun() {
+		_, err := api.doDryRun(method, path, body)
+		return err
+	}
+
+	_, err := api.do(method, path, body)
+	return err
+}
@@ -0,0 +1,293 @@
+package device42
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalResource(t *testing.T) {
+	tests := []struct {
+		path     string
+		resource string
+		id       int
+	}{
+		{path: "/ips/", resource: "ips"},
+		{path: "/ips/12/", resource: "ips", id: 12},
+		{path: "/subnets/create_child/", resource: "subnets"},
+		{path: "/vlans/7", resource: "vlans", id: 7},
+		{path: "/subnets/?network=10.0.0.0", resource: "subnets"},
+		{path: "/suggest_ip/?subnet_id=1&reserve_ip=yes", resource: "suggest_ip"},
+	}
+
+	for _, tt := range tests {
+		resource, id := journalResource(tt.path)
+		if resource != tt.resource || id != tt.id {
+			t.Errorf("journalResource(%q) = %q, %d, want %q, %d", tt.path, resource, id, tt.resource, tt.id)
+		}
+	}
+}
+
+func TestIsReserving(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         bool
+	}{
+		{method: "GET", path: "/suggest_ip/?subnet_id=1&reserve_ip=yes", want: true},
+		{method: "GET", path: "/suggest_ip/?subnet_id=1", want: false},
+		{method: "POST", path: "/suggest_ip/?subnet_id=1&reserve_ip=yes", want: false},
+		{method: "GET", path: "/ips/?reserve_ip=yes", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isReserving(tt.method, tt.path); got != tt.want {
+			t.Errorf("isReserving(%q, %q) = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	older := NewJournal(dir, "older")
+	older.Created = older.Created.Add(-time.Hour)
+	if err := older.record(JournalEntry{Action: JournalActionCreate, Resource: "ips", ObjectID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	j := NewJournal(dir, "ipam ip set")
+	if err := j.record(JournalEntry{Action: JournalActionCreate, Resource: "subnets", ObjectID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.record(JournalEntry{Action: JournalActionDelete, Resource: "vlans", ObjectID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := OpenJournal(dir, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Description != j.Description || o.Len() != 2 || o.Entries[1].Resource != "vlans" || o.Entries[1].Time.IsZero() {
+		t.Errorf("OpenJournal = %+v, want %+v", o, j)
+	}
+
+	list, err := ListJournals(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != j.ID || list[1].ID != older.ID {
+		t.Errorf("ListJournals did not return the journals newest first: %+v", list)
+	}
+}
+
+func TestOpenJournalInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, id := range []string{"", "../x", `a\b`, "missing"} {
+		if _, err := OpenJournal(dir, id); err == nil {
+			t.Errorf("OpenJournal(%q) succeeded", id)
+		}
+	}
+}
+
+func TestUndoRefused(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		journal *Journal
+		wantErr string
+	}{
+		{
+			name:    "already undone",
+			journal: &Journal{ID: "a", Undone: &now},
+			wantErr: "already undone",
+		},
+		{
+			name: "created id not recorded",
+			journal: &Journal{ID: "b", Entries: []JournalEntry{
+				{Action: JournalActionCreate, Resource: "ips", ObjectID: 4},
+				{Action: JournalActionCreate, Resource: "subnets", Method: "POST", Path: "/subnets/"},
+			}},
+			wantErr: "removed by hand",
+		},
+	}
+
+	// the journals are refused before any request is made
+	api := &API{}
+	for _, tt := range tests {
+		err := api.Undo(tt.journal)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Undo error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// ipStore type
+// ips kept in memory by id. changes are the mutating requests, in order
+type ipStore struct {
+	ips     map[int]*IP
+	nextID  int
+	changes []string
+	broken  bool
+}
+
+func (s *ipStore) byAddress(a string) *IP {
+	for _, ip := range s.ips {
+		if ip.Address == a {
+			return ip
+		}
+	}
+	return nil
+}
+
+func (s *ipStore) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Method != "GET" {
+			s.changes = append(s.changes, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+r.PostForm.Encode()))
+		}
+		q := r.URL.Query()
+
+		switch {
+		case r.Method == "GET" && s.broken:
+			http.Error(w, "{}", http.StatusServiceUnavailable)
+		case r.Method == "GET" && q.Get("ip_id") != "":
+			id, _ := strconv.Atoi(q.Get("ip_id"))
+			if ip, ok := s.ips[id]; ok {
+				writeJSON(w, IPs{List: []IP{*ip}})
+				return
+			}
+			writeJSON(w, IPs{List: []IP{}})
+		case r.Method == "GET":
+			if ip := s.byAddress(q.Get("address")); ip != nil {
+				writeJSON(w, IPs{List: []IP{*ip}})
+				return
+			}
+			writeJSON(w, IPs{List: []IP{}})
+		case r.Method == "POST":
+			ip := s.byAddress(r.PostForm.Get("ipaddress"))
+			if ip == nil {
+				s.nextID++
+				ip = &IP{ID: s.nextID, Address: r.PostForm.Get("ipaddress")}
+				s.ips[ip.ID] = ip
+			}
+			ip.Label = r.PostForm.Get("label")
+			ip.SubnetID, _ = strconv.Atoi(r.PostForm.Get("subnet_id"))
+			writeCreated(w, ip.ID)
+		case r.Method == "DELETE":
+			id, _ := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/ips/"), "/"))
+			delete(s.ips, id)
+			writeJSON(w, map[string]interface{}{"deleted": true})
+		}
+	}
+}
+
+func TestJournalUndo(t *testing.T) {
+	tests := []struct {
+		name    string
+		ips     map[int]*IP
+		change  func(api *API) error
+		changes []string
+		undo    []string
+		want    map[int]*IP
+	}{
+		{
+			name: "create is deleted",
+			ips:  map[int]*IP{},
+			change: func(api *API) error {
+				_, err := api.SetIP(&IP{IPAddress: "10.0.0.5", SubnetID: 1, Label: "web"})
+				return err
+			},
+			changes: []string{"POST /ips/ ipaddress=10.0.0.5&label=web&subnet_id=1"},
+			undo:    []string{"DELETE /ips/8/"},
+			want:    map[int]*IP{},
+		},
+		{
+			name: "update is posted back",
+			ips:  map[int]*IP{7: {ID: 7, Address: "10.0.0.5", SubnetID: 1, Label: "old"}},
+			change: func(api *API) error {
+				_, err := api.SetIP(&IP{IPAddress: "10.0.0.5", SubnetID: 1, Label: "web"})
+				return err
+			},
+			changes: []string{"POST /ips/ ipaddress=10.0.0.5&label=web&subnet_id=1"},
+			undo:    []string{"POST /ips/ ipaddress=10.0.0.5&label=old&subnet_id=1"},
+			want:    map[int]*IP{7: {ID: 7, Address: "10.0.0.5", SubnetID: 1, Label: "old"}},
+		},
+		{
+			name:    "delete is recreated",
+			ips:     map[int]*IP{7: {ID: 7, Address: "10.0.0.5", SubnetID: 1, Label: "old"}},
+			change:  func(api *API) error { return api.DeleteIP(7) },
+			changes: []string{"DELETE /ips/7/"},
+			undo:    []string{"POST /ips/ ipaddress=10.0.0.5&label=old&subnet_id=1"},
+			want:    map[int]*IP{8: {ID: 8, Address: "10.0.0.5", SubnetID: 1, Label: "old"}},
+		},
+	}
+
+	for _, tt := range tests {
+		s := &ipStore{ips: tt.ips, nextID: 7}
+		api := newTestAPI(t, s.handle(t))
+		j := NewJournal(t.TempDir(), tt.name)
+		api.Journal(j)
+
+		if err := tt.change(api); err != nil {
+			t.Fatalf("%s: change error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(s.changes, tt.changes) {
+			t.Errorf("%s: changes %v, want %v", tt.name, s.changes, tt.changes)
+		}
+
+		s.changes = nil
+		if err := api.Undo(j); err != nil {
+			t.Fatalf("%s: Undo error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(s.changes, tt.undo) {
+			t.Errorf("%s: undo changes %v, want %v", tt.name, s.changes, tt.undo)
+		}
+		if !reflect.DeepEqual(s.ips, tt.want) {
+			t.Errorf("%s: ips after undo %v, want %v", tt.name, s.ips, tt.want)
+		}
+		if j.Undone == nil || j.Len() != 0 {
+			t.Errorf("%s: journal not marked undone: %+v", tt.name, j)
+		}
+	}
+}
+
+func TestJournalNotFatal(t *testing.T) {
+	s := &ipStore{ips: map[int]*IP{7: {ID: 7, Address: "10.0.0.5"}}, broken: true}
+	api := newTestAPI(t, s.handle(t))
+	j := NewJournal(t.TempDir(), "broken")
+	api.Journal(j)
+
+	// the prior state can not be read, the delete is still made
+	if err := api.DeleteIP(7); err != nil {
+		t.Fatalf("DeleteIP error = %v", err)
+	}
+	if want := []string{"DELETE /ips/7/"}; !reflect.DeepEqual(s.changes, want) {
+		t.Errorf("changes %v, want %v", s.changes, want)
+	}
+	if j.Len() != 1 || j.Entries[0].Unrecoverable == "" {
+		t.Errorf("journal entries = %+v, want an unrecoverable delete", j.Entries)
+	}
+
+	// resources the journal does not know are sent without being recorded
+	if _, err := api.Do("POST", "/devices/", strings.NewReader("name=x")); err != nil {
+		t.Fatalf("Do error = %v", err)
+	}
+	if len(s.changes) != 2 || j.Len() != 1 {
+		t.Errorf("changes %v, journal entries %+v", s.changes, j.Entries)
+	}
+
+	s.changes = nil
+	if err := api.Undo(j); err == nil || !strings.Contains(err.Error(), "reverted by hand") {
+		t.Errorf("Undo error = %v, want the change refused", err)
+	}
+	if len(s.changes) != 0 {
+		t.Errorf("Undo made changes %v", s.changes)
+	}
+}
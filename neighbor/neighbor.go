@@ -0,0 +1,149 @@
+// Package neighbor parses arp and neighbor table dumps and reconciles them
+// against device42 ips
+package neighbor
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+// table formats
+const (
+	FormatAuto  = "auto"
+	FormatLinux = "linux"
+	FormatCisco = "cisco"
+)
+
+// Entry type
+// a reachable host from a neighbor table
+type Entry struct {
+	Address    netip.Addr `json:"address"`
+	MacAddress string     `json:"mac_address"`
+	Interface  string     `json:"interface"`
+	State      string     `json:"state"`
+}
+
+// Parse will parse a neighbor table dump. linux `ip neigh` and cisco
+// `show arp` / `show ipv6 neighbors` output are supported, and auto detects
+// the format per line. incomplete and failed entries are skipped
+func Parse(r io.Reader, format string) ([]Entry, error) {
+	var parse func(fields []string) (*Entry, bool)
+
+	switch format {
+	case FormatLinux:
+		parse = parseLinux
+	case FormatCisco:
+		parse = parseCisco
+	case FormatAuto, "":
+		parse = func(fields []string) (*Entry, bool) {
+			if e, ok := parseLinux(fields); ok {
+				return e, true
+			}
+			return parseCisco(fields)
+		}
+	default:
+		return nil, errors.New("unknown neighbor table format " + format)
+	}
+
+	entries := []Entry{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if e, ok := parse(fields); ok {
+			entries = append(entries, *e)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// parseLinux parses `ip neigh` lines, e.g.
+// 10.0.0.1 dev eth0 lladdr 00:11:22:33:44:55 REACHABLE
+func parseLinux(fields []string) (*Entry, bool) {
+	a, err := device42.ParseAddress(fields[0])
+	if err != nil {
+		return nil, false
+	}
+
+	e := Entry{Address: a}
+	for i := 1; i < len(fields); i++ {
+		switch fields[i] {
+		case "dev":
+			if i+1 < len(fields) {
+				e.Interface = fields[i+1]
+				i++
+			}
+		case "lladdr":
+			if i+1 < len(fields) {
+				e.MacAddress = normalizeMac(fields[i+1])
+				i++
+			}
+		default:
+			if strings.ToUpper(fields[i]) == fields[i] {
+				e.State = fields[i]
+			}
+		}
+	}
+
+	if e.MacAddress == "" || e.State == "FAILED" || e.State == "INCOMPLETE" {
+		return nil, false
+	}
+	return &e, true
+}
+
+// parseCisco parses `show arp` and `show ipv6 neighbors` lines, e.g.
+// Internet  10.0.0.1  5  0011.2233.4455  ARPA  Vlan10
+// 2001:DB8::1  0 0011.2233.4455  REACH Vl10
+func parseCisco(fields []string) (*Entry, bool) {
+	if strings.EqualFold(fields[0], "internet") && len(fields) >= 4 {
+		a, err := device42.ParseAddress(fields[1])
+		if err != nil {
+			return nil, false
+		}
+		mac := normalizeMac(fields[3])
+		if mac == "" {
+			return nil, false
+		}
+		e := Entry{Address: a, MacAddress: mac, State: "ARPA"}
+		if len(fields) >= 6 {
+			e.Interface = fields[5]
+		}
+		return &e, true
+	}
+
+	if len(fields) >= 5 {
+		a, err := device42.ParseAddress(fields[0])
+		if err != nil || !a.Is6() {
+			return nil, false
+		}
+		mac := normalizeMac(fields[2])
+		if mac == "" || fields[3] == "INCMP" {
+			return nil, false
+		}
+		return &Entry{Address: a, MacAddress: mac, State: fields[3], Interface: fields[4]}, true
+	}
+
+	return nil, false
+}
+
+// normalizeMac will return a mac address as lower case colon separated
+// octets, or an empty string if it is invalid
+func normalizeMac(s string) string {
+	m, err := net.ParseMAC(s)
+	if err != nil {
+		return ""
+	}
+	return m.String()
+}
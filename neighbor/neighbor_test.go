@@ -0,0 +1,147 @@
+package neighbor
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+const linuxTable = `10.0.0.1 dev eth0 lladdr 00:11:22:33:44:55 REACHABLE
+10.0.0.2 dev eth0 lladdr 00:11:22:33:44:56 STALE
+10.0.0.3 dev eth0  FAILED
+10.0.0.4 dev eth0 INCOMPLETE
+fe80::1 dev eth0 lladdr 00:11:22:33:44:57 router REACHABLE
+2001:db8::0001 dev eth1 lladdr 00-11-22-33-44-58 DELAY
+`
+
+const ciscoTable = `Protocol  Address          Age (min)  Hardware Addr   Type   Interface
+Internet  10.0.0.1                5   0011.2233.4455  ARPA   Vlan10
+Internet  10.0.0.5                -   0011.2233.4459  ARPA   Vlan10
+Internet  10.0.0.6                0   Incomplete      ARPA
+
+IPv6 Address                              Age Link-layer Addr State Interface
+2001:DB8::1                                 0 0011.2233.4458  REACH Vl10
+2001:DB8::2                                 0 -               INCMP Vl10
+`
+
+func entry(a, mac, state, iface string) Entry {
+	return Entry{Address: netip.MustParseAddr(a), MacAddress: mac, State: state, Interface: iface}
+}
+
+func TestParse(t *testing.T) {
+	linux := []Entry{
+		entry("10.0.0.1", "00:11:22:33:44:55", "REACHABLE", "eth0"),
+		entry("10.0.0.2", "00:11:22:33:44:56", "STALE", "eth0"),
+		entry("fe80::1", "00:11:22:33:44:57", "REACHABLE", "eth0"),
+		entry("2001:db8::1", "00:11:22:33:44:58", "DELAY", "eth1"),
+	}
+	cisco := []Entry{
+		entry("10.0.0.1", "00:11:22:33:44:55", "ARPA", "Vlan10"),
+		entry("10.0.0.5", "00:11:22:33:44:59", "ARPA", "Vlan10"),
+		entry("2001:db8::1", "00:11:22:33:44:58", "REACH", "Vl10"),
+	}
+
+	tests := []struct {
+		name    string
+		table   string
+		format  string
+		want    []Entry
+		wantErr bool
+	}{
+		{name: "linux", table: linuxTable, format: FormatLinux, want: linux},
+		{name: "linux auto", table: linuxTable, format: FormatAuto, want: linux},
+		{name: "cisco", table: ciscoTable, format: FormatCisco, want: cisco},
+		{name: "cisco auto", table: ciscoTable, format: "", want: cisco},
+		{name: "cisco as linux", table: ciscoTable, format: FormatLinux, want: []Entry{}},
+		{name: "empty", table: "", format: FormatAuto, want: []Entry{}},
+		{name: "unknown format", table: linuxTable, format: "juniper", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(strings.NewReader(tt.table), tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Parse error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Parse = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeMac(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "00:11:22:33:44:55", want: "00:11:22:33:44:55"},
+		{in: "00-11-22-AA-BB-CC", want: "00:11:22:aa:bb:cc"},
+		{in: "0011.22aa.bbcc", want: "00:11:22:aa:bb:cc"},
+		{in: "Incomplete", want: ""},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeMac(tt.in); got != tt.want {
+			t.Errorf("normalizeMac(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	subnets := []device42.Subnet{
+		{SubnetID: 1, Network: "10.0.0.0", MaskBits: 16},
+		{SubnetID: 2, Network: "10.0.0.0", MaskBits: 24},
+		{SubnetID: 3, Network: "10.1.0.0", MaskBits: 24},
+	}
+	ips := []device42.IP{
+		{ID: 1, Address: "10.0.0.1", SubnetID: 2, MacAddress: "00:11:22:33:44:55"},
+		{ID: 2, Address: "10.0.0.2", SubnetID: 2, MacAddress: "00:11:22:33:44:99"},
+		{ID: 3, Address: "10.0.0.3", SubnetID: 2},
+		{ID: 4, Address: "10.0.0.4", SubnetID: 2},
+		{ID: 5, Address: "10.1.0.1", SubnetID: 3},
+	}
+	entries := []Entry{
+		entry("10.0.0.3", "00:11:22:33:44:03", "REACHABLE", "eth0"),
+		entry("10.0.0.1", "00:11:22:33:44:55", "REACHABLE", "eth0"),
+		entry("10.0.0.2", "00:11:22:33:44:56", "REACHABLE", "eth0"),
+		entry("10.0.0.9", "00:11:22:33:44:09", "REACHABLE", "eth0"),
+		entry("10.0.0.1", "00:11:22:33:44:55", "STALE", "eth1"),
+		entry("192.168.0.1", "00:11:22:33:44:01", "REACHABLE", "eth2"),
+		entry("fe80::1", "00:11:22:33:44:57", "REACHABLE", "eth0"),
+	}
+
+	type result struct {
+		status   string
+		address  string
+		ipID     int
+		subnetID int
+	}
+	want := []result{
+		{status: StatusMatched, address: "10.0.0.1", ipID: 1, subnetID: 2},
+		{status: StatusMacMismatch, address: "10.0.0.2", ipID: 2, subnetID: 2},
+		// a record without a mac address matches any
+		{status: StatusMatched, address: "10.0.0.3", ipID: 3, subnetID: 2},
+		{status: StatusUnseen, address: "10.0.0.4", ipID: 4, subnetID: 2},
+		{status: StatusUnrecorded, address: "10.0.0.9", subnetID: 2},
+		{status: StatusUnrecorded, address: "192.168.0.1"},
+	}
+
+	got := []result{}
+	for _, r := range Reconcile(entries, subnets, ips) {
+		g := result{status: r.Status, address: r.Address.String()}
+		if r.IP != nil {
+			g.ipID = r.IP.ID
+		}
+		if r.Subnet != nil {
+			g.subnetID = r.Subnet.SubnetID
+		}
+		got = append(got, g)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package neighbor
+
+import (
+	"net/netip"
+	"sort"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+// reconcile statuses
+const (
+	StatusMatched     = "matched"
+	StatusUnrecorded  = "unrecorded"
+	StatusUnseen      = "unseen"
+	StatusMacMismatch = "mac-mismatch"
+	StatusCreated     = "created"
+)
+
+// Result type
+type Result struct {
+	Status  string           `json:"status"`
+	Address netip.Addr       `json:"address"`
+	Entry   *Entry           `json:"entry,omitempty"`
+	IP      *device42.IP     `json:"ip,omitempty"`
+	Subnet  *device42.Subnet `json:"subnet,omitempty"`
+}
+
+// Reconcile will match neighbor entries to ip records by address and subnet.
+// entries without a record are unrecorded, records without an entry are
+// unseen. only ips within subnets that hold at least one entry are checked
+// for being unseen, so a partial dump does not flag the whole appliance
+func Reconcile(entries []Entry, subnets []device42.Subnet, ips []device42.IP) []Result {
+	type parsedSubnet struct {
+		subnet *device42.Subnet
+		prefix netip.Prefix
+	}
+	parsed := []parsedSubnet{}
+	for i := range subnets {
+		if p, err := subnets[i].Prefix(); err == nil {
+			parsed = append(parsed, parsedSubnet{subnet: &subnets[i], prefix: p})
+		}
+	}
+
+	// the most specific subnet holding an address
+	subnetFor := func(a netip.Addr) *device42.Subnet {
+		var best *parsedSubnet
+		for i := range parsed {
+			if parsed[i].prefix.Contains(a) && (best == nil || parsed[i].prefix.Bits() > best.prefix.Bits()) {
+				best = &parsed[i]
+			}
+		}
+		if best == nil {
+			return nil
+		}
+		return best.subnet
+	}
+
+	recorded := map[netip.Addr][]*device42.IP{}
+	for i := range ips {
+		if a, err := ips[i].Addr(); err == nil {
+			recorded[a] = append(recorded[a], &ips[i])
+		}
+	}
+
+	results := []Result{}
+	seen := map[netip.Addr]bool{}
+	scope := map[int]bool{}
+
+	for i := range entries {
+		e := &entries[i]
+		// link local addresses are never recorded in ipam
+		if seen[e.Address] || e.Address.IsLinkLocalUnicast() {
+			continue
+		}
+		seen[e.Address] = true
+
+		s := subnetFor(e.Address)
+		if s != nil {
+			scope[s.SubnetID] = true
+		}
+
+		var ip *device42.IP
+		for _, r := range recorded[e.Address] {
+			if s == nil || r.SubnetID == s.SubnetID {
+				ip = r
+				break
+			}
+		}
+
+		switch {
+		case ip == nil:
+			results = append(results, Result{Status: StatusUnrecorded, Address: e.Address, Entry: e, Subnet: s})
+		case ip.MacAddress != "" && normalizeMac(ip.MacAddress) != e.MacAddress:
+			results = append(results, Result{Status: StatusMacMismatch, Address: e.Address, Entry: e, IP: ip, Subnet: s})
+		default:
+			results = append(results, Result{Status: StatusMatched, Address: e.Address, Entry: e, IP: ip, Subnet: s})
+		}
+	}
+
+	for i := range ips {
+		ip := &ips[i]
+		a, err := ip.Addr()
+		if err != nil || seen[a] || !scope[ip.SubnetID] {
+			continue
+		}
+		results = append(results, Result{Status: StatusUnseen, Address: a, IP: ip, Subnet: subnetFor(a)})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Address.Less(results[j].Address)
+	})
+
+	return results
+}
+
+// CreateUnrecorded will create ip records, with their mac address, for
+// unrecorded results that fall within a subnet. created results are updated
+// in place
+func CreateUnrecorded(api *device42.API, results []Result) error {
+	for i := range results {
+		r := &results[i]
+		if r.Status != StatusUnrecorded || r.Subnet == nil {
+			continue
+		}
+
+		ip, err := api.SetIP(&device42.IP{
+			IPAddress:  r.Address.String(),
+			SubnetID:   r.Subnet.SubnetID,
+			MacAddress: r.Entry.MacAddress,
+		})
+		if err != nil {
+			return err
+		}
+
+		r.IP = ip
+		r.Status = StatusCreated
+	}
+
+	return nil
+}
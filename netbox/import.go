@@ -0,0 +1,118 @@
+package netbox
+
+import (
+	"fmt"
+	"strconv"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+// ImportResult type
+// the device42 objects created or updated by an import
+type ImportResult struct {
+	Buildings []device42.Building `json:"buildings"`
+	VRFGroups []device42.VRFGroup `json:"vrf_groups"`
+	VLANs     []device42.VLAN     `json:"vlans"`
+	Subnets   []device42.Subnet   `json:"subnets"`
+	IPs       []device42.IP       `json:"ips"`
+}
+
+// Import will upsert a netbox document into device42. sites, vrfs, vlans,
+// prefixes and ip addresses are imported in that order so references between
+// them resolve, and the import stops at the first error
+func Import(api *device42.API, d *Document) (*ImportResult, error) {
+	r := ImportResult{
+		Buildings: []device42.Building{},
+		VRFGroups: []device42.VRFGroup{},
+		VLANs:     []device42.VLAN{},
+		Subnets:   []device42.Subnet{},
+		IPs:       []device42.IP{},
+	}
+
+	for _, s := range d.Sites {
+		b := s.ToBuilding()
+		n, err := api.SetBuilding(&b)
+		if err != nil {
+			return &r, fmt.Errorf("site %s: %w", s.Name, err)
+		}
+		r.Buildings = append(r.Buildings, *n)
+	}
+
+	for _, v := range d.VRFs {
+		g := v.ToVRFGroup()
+		n, err := api.SetVRFGroup(&g)
+		if err != nil {
+			return &r, fmt.Errorf("vrf %s: %w", v.Name, err)
+		}
+		r.VRFGroups = append(r.VRFGroups, *n)
+	}
+
+	// prefixes reference vlans by netbox id, or by vid and name
+	vlanIDs := map[string]int{}
+	for _, v := range d.VLANs {
+		vlan := v.ToVLAN()
+		n, err := upsertVLAN(api, &vlan)
+		if err != nil {
+			return &r, fmt.Errorf("vlan %d: %w", v.VID, err)
+		}
+		r.VLANs = append(r.VLANs, *n)
+
+		if v.ID != 0 {
+			vlanIDs["id:"+strconv.Itoa(v.ID)] = n.VlanID
+		}
+		vlanIDs[strconv.Itoa(v.VID)+":"+v.Name] = n.VlanID
+	}
+
+	for _, p := range d.Prefixes {
+		s, err := p.ToSubnet()
+		if err != nil {
+			return &r, err
+		}
+		if p.VLAN != nil {
+			if id, ok := vlanIDs["id:"+strconv.Itoa(p.VLAN.ID)]; ok && p.VLAN.ID != 0 {
+				s.ParentVlanID = id
+			} else if id, ok := vlanIDs[strconv.Itoa(p.VLAN.VID)+":"+p.VLAN.Name]; ok {
+				s.ParentVlanID = id
+			}
+		}
+
+		n, err := api.SetSubnet(&s)
+		if err != nil {
+			return &r, fmt.Errorf("prefix %s: %w", p.Prefix, err)
+		}
+		r.Subnets = append(r.Subnets, *n)
+	}
+
+	for _, a := range d.IPAddresses {
+		ip, err := a.ToIP()
+		if err != nil {
+			return &r, err
+		}
+
+		n, err := api.SetIP(&ip)
+		if err != nil {
+			return &r, fmt.Errorf("ip address %s: %w", a.Address, err)
+		}
+		r.IPs = append(r.IPs, *n)
+	}
+
+	return &r, nil
+}
+
+// upsertVLAN will update the vlan with the same number and name, or create it
+// when there is none. vlan numbers alone are not unique in device42
+func upsertVLAN(api *device42.API, v *device42.VLAN) (*device42.VLAN, error) {
+	vlans, err := api.GetVLANsByNumber(v.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range *vlans {
+		if i.Name == v.Name {
+			v.VlanID = i.VlanID
+			return api.UpdateVLAN(v)
+		}
+	}
+
+	return api.SetVLAN(v)
+}
@@ -0,0 +1,387 @@
+// Package netbox converts device42 ipam objects to and from netbox's json
+// shapes
+package netbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+// StatusActive is the status given to exported objects
+const StatusActive = "active"
+
+// CustomFieldID is the custom field holding an object's device42 id
+const CustomFieldID = "device42_id"
+
+// CustomFieldMacAddress is the custom field holding an ip's mac address
+const CustomFieldMacAddress = "mac_address"
+
+var (
+	slugReplacer = regexp.MustCompile(`[^a-z0-9_-]+`)
+	dnsName      = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+)
+
+// Ref type
+// a nested reference to another netbox object
+type Ref struct {
+	ID   int    `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	Slug string `json:"slug,omitempty"`
+	VID  int    `json:"vid,omitempty"`
+}
+
+// Tag type
+type Tag struct {
+	Name string `json:"name"`
+	Slug string `json:"slug,omitempty"`
+}
+
+// UnmarshalJSON will decode a tag given as an object or a name
+func (t *Tag) UnmarshalJSON(b []byte) error {
+	var n string
+	if json.Unmarshal(b, &n) == nil {
+		*t = Tag{Name: n, Slug: Slugify(n)}
+		return nil
+	}
+
+	type tag Tag
+	return json.Unmarshal(b, (*tag)(t))
+}
+
+// Status type
+// netbox exports a status as {"value": ..., "label": ...} but imports it as
+// the value alone
+type Status string
+
+// UnmarshalJSON will decode a status given as an object or a value
+func (s *Status) UnmarshalJSON(b []byte) error {
+	var v string
+	if json.Unmarshal(b, &v) == nil {
+		*s = Status(v)
+		return nil
+	}
+
+	o := struct {
+		Value string `json:"value"`
+	}{}
+	err := json.Unmarshal(b, &o)
+	if err != nil {
+		return err
+	}
+	*s = Status(o.Value)
+	return nil
+}
+
+// Site type
+type Site struct {
+	ID              int                    `json:"id,omitempty"`
+	Name            string                 `json:"name"`
+	Slug            string                 `json:"slug"`
+	Status          Status                 `json:"status,omitempty"`
+	PhysicalAddress string                 `json:"physical_address,omitempty"`
+	Comments        string                 `json:"comments,omitempty"`
+	CustomFields    map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// VRF type
+type VRF struct {
+	ID           int                    `json:"id,omitempty"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	Tags         []Tag                  `json:"tags,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// VLAN type
+type VLAN struct {
+	ID           int                    `json:"id,omitempty"`
+	VID          int                    `json:"vid"`
+	Name         string                 `json:"name"`
+	Status       Status                 `json:"status,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	Comments     string                 `json:"comments,omitempty"`
+	Tags         []Tag                  `json:"tags,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// Prefix type
+type Prefix struct {
+	ID           int                    `json:"id,omitempty"`
+	Prefix       string                 `json:"prefix"`
+	VRF          *Ref                   `json:"vrf,omitempty"`
+	VLAN         *Ref                   `json:"vlan,omitempty"`
+	Status       Status                 `json:"status,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	Comments     string                 `json:"comments,omitempty"`
+	Tags         []Tag                  `json:"tags,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// IPAddress type
+type IPAddress struct {
+	ID           int                    `json:"id,omitempty"`
+	Address      string                 `json:"address"`
+	VRF          *Ref                   `json:"vrf,omitempty"`
+	Status       Status                 `json:"status,omitempty"`
+	DNSName      string                 `json:"dns_name,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	Comments     string                 `json:"comments,omitempty"`
+	Tags         []Tag                  `json:"tags,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// Document type
+// every list holds objects in the shape of the matching netbox api endpoint
+type Document struct {
+	Sites       []Site      `json:"sites"`
+	VRFs        []VRF       `json:"vrfs"`
+	VLANs       []VLAN      `json:"vlans"`
+	Prefixes    []Prefix    `json:"prefixes"`
+	IPAddresses []IPAddress `json:"ip_addresses"`
+}
+
+// Slugify will build a netbox slug from a name
+func Slugify(n string) string {
+	return strings.Trim(slugReplacer.ReplaceAllString(strings.ToLower(n), "-"), "-")
+}
+
+// Export will convert device42 objects to a netbox document
+func Export(buildings []device42.Building, vrfGroups []device42.VRFGroup, vlans []device42.VLAN, subnets []device42.Subnet, ips []device42.IP) *Document {
+	d := Document{
+		Sites:       []Site{},
+		VRFs:        []VRF{},
+		VLANs:       []VLAN{},
+		Prefixes:    []Prefix{},
+		IPAddresses: []IPAddress{},
+	}
+
+	for _, b := range buildings {
+		d.Sites = append(d.Sites, FromBuilding(b))
+	}
+	for _, v := range vrfGroups {
+		d.VRFs = append(d.VRFs, FromVRFGroup(v))
+	}
+
+	vlansByID := make(map[int]device42.VLAN, len(vlans))
+	for _, v := range vlans {
+		vlansByID[v.VlanID] = v
+		d.VLANs = append(d.VLANs, FromVLAN(v))
+	}
+
+	masks := make(map[int]int, len(subnets))
+	for _, s := range subnets {
+		masks[s.SubnetID] = s.MaskBits
+
+		p := FromSubnet(s)
+		if v, ok := vlansByID[s.ParentVlanID]; ok {
+			p.VLAN = &Ref{VID: v.Number, Name: v.Name}
+		}
+		d.Prefixes = append(d.Prefixes, p)
+	}
+
+	for _, i := range ips {
+		a, err := FromIP(i, masks[i.SubnetID])
+		if err != nil {
+			continue
+		}
+		d.IPAddresses = append(d.IPAddresses, a)
+	}
+
+	sort.SliceStable(d.Prefixes, func(i, j int) bool { return lessPrefix(d.Prefixes[i].Prefix, d.Prefixes[j].Prefix) })
+
+	return &d
+}
+
+// lessPrefix orders prefixes by family, address, then mask bits. prefixes
+// that do not parse are ordered as text after those that do
+func lessPrefix(a, b string) bool {
+	pa, errA := netip.ParsePrefix(a)
+	pb, errB := netip.ParsePrefix(b)
+	if errA != nil || errB != nil {
+		if (errA == nil) != (errB == nil) {
+			return errA == nil
+		}
+		return a < b
+	}
+	if c := pa.Addr().Compare(pb.Addr()); c != 0 {
+		return c < 0
+	}
+	return pa.Bits() < pb.Bits()
+}
+
+// FromBuilding will convert a building to a site
+func FromBuilding(b device42.Building) Site {
+	return Site{
+		Name:            b.Name,
+		Slug:            Slugify(b.Name),
+		Status:          StatusActive,
+		PhysicalAddress: b.Address,
+		Comments:        b.Notes,
+		CustomFields:    map[string]interface{}{CustomFieldID: b.BuildingID},
+	}
+}
+
+// ToBuilding will convert a site to a building
+func (s *Site) ToBuilding() device42.Building {
+	return device42.Building{
+		Name:    s.Name,
+		Address: s.PhysicalAddress,
+		Notes:   s.Comments,
+	}
+}
+
+// FromVRFGroup will convert a vrf group to a vrf
+func FromVRFGroup(v device42.VRFGroup) VRF {
+	return VRF{
+		Name:         v.Name,
+		Description:  v.Description,
+		CustomFields: map[string]interface{}{CustomFieldID: v.ID},
+	}
+}
+
+// ToVRFGroup will convert a vrf to a vrf group
+func (v *VRF) ToVRFGroup() device42.VRFGroup {
+	return device42.VRFGroup{
+		Name:        v.Name,
+		Description: v.Description,
+	}
+}
+
+// FromVLAN will convert a vlan to a netbox vlan
+func FromVLAN(v device42.VLAN) VLAN {
+	return VLAN{
+		VID:          v.Number,
+		Name:         v.Name,
+		Status:       StatusActive,
+		Description:  v.Description,
+		Comments:     v.Notes,
+		Tags:         fromTags(v.Tags),
+		CustomFields: map[string]interface{}{CustomFieldID: v.VlanID},
+	}
+}
+
+// ToVLAN will convert a netbox vlan to a vlan
+func (v *VLAN) ToVLAN() device42.VLAN {
+	return device42.VLAN{
+		Number:      v.VID,
+		Name:        v.Name,
+		Description: v.Description,
+		Notes:       v.Comments,
+		Tags:        toTags(v.Tags),
+	}
+}
+
+// FromSubnet will convert a subnet to a prefix. the vlan is left for the
+// caller, as only the vlan's id is known to the subnet
+func FromSubnet(s device42.Subnet) Prefix {
+	p := Prefix{
+		Prefix:       device42.NormalizeAddress(s.Network) + "/" + strconv.Itoa(s.MaskBits),
+		Status:       StatusActive,
+		Description:  s.Name,
+		Comments:     s.Description,
+		Tags:         fromTags(s.Tags),
+		CustomFields: map[string]interface{}{CustomFieldID: s.SubnetID},
+	}
+	if s.VrfGroupName != "" {
+		p.VRF = &Ref{Name: s.VrfGroupName}
+	}
+	return p
+}
+
+// ToSubnet will convert a prefix to a subnet
+func (p *Prefix) ToSubnet() (device42.Subnet, error) {
+	prefix, err := netip.ParsePrefix(p.Prefix)
+	if err != nil {
+		return device42.Subnet{}, fmt.Errorf("prefix %s: %w", p.Prefix, err)
+	}
+
+	s := device42.Subnet{
+		Network:     prefix.Masked().Addr().String(),
+		MaskBits:    prefix.Bits(),
+		Name:        p.Description,
+		Description: p.Comments,
+		Tags:        toTags(p.Tags),
+	}
+	if p.VRF != nil {
+		s.VrfGroup = p.VRF.Name
+	}
+	return s, nil
+}
+
+// FromIP will convert an ip to an ip address. the mask bits of the ip's subnet
+// are needed as netbox records addresses with their prefix length
+func FromIP(ip device42.IP, maskBits int) (IPAddress, error) {
+	a, err := ip.Addr()
+	if err != nil {
+		return IPAddress{}, err
+	}
+	if maskBits == 0 {
+		maskBits = a.BitLen()
+	}
+
+	i := IPAddress{
+		Address:      a.String() + "/" + strconv.Itoa(maskBits),
+		Status:       StatusActive,
+		Description:  ip.Label,
+		Comments:     ip.Notes,
+		CustomFields: map[string]interface{}{CustomFieldID: ip.ID},
+	}
+	// labels are free text, only host names are valid dns names
+	if l := strings.ToLower(ip.Label); dnsName.MatchString(l) {
+		i.DNSName = l
+	}
+	if ip.MacAddress != "" {
+		i.CustomFields[CustomFieldMacAddress] = strings.ToLower(ip.MacAddress)
+	}
+	if ip.VRFGroup != "" {
+		i.VRF = &Ref{Name: ip.VRFGroup}
+	}
+	return i, nil
+}
+
+// ToIP will convert an ip address to an ip
+func (i *IPAddress) ToIP() (device42.IP, error) {
+	prefix, err := netip.ParsePrefix(i.Address)
+	if err != nil {
+		return device42.IP{}, fmt.Errorf("ip address %s: %w", i.Address, err)
+	}
+
+	ip := device42.IP{
+		Address: prefix.Addr().String(),
+		Label:   i.DNSName,
+		Notes:   i.Comments,
+	}
+	if ip.Label == "" {
+		ip.Label = i.Description
+	}
+	if m, ok := i.CustomFields[CustomFieldMacAddress].(string); ok {
+		ip.MacAddress = m
+	}
+	if i.VRF != nil {
+		ip.VRFGroup = i.VRF.Name
+	}
+	return ip, nil
+}
+
+func fromTags(tags []string) []Tag {
+	t := []Tag{}
+	for _, n := range tags {
+		t = append(t, Tag{Name: n, Slug: Slugify(n)})
+	}
+	return t
+}
+
+func toTags(tags []Tag) []string {
+	t := []string{}
+	for _, n := range tags {
+		t = append(t, n.Name)
+	}
+	return t
+}
@@ -0,0 +1,270 @@
+package netbox
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "HQ", want: "hq"},
+		{in: "Main Office #2", want: "main-office-2"},
+		{in: "  web_servers ", want: "web_servers"},
+		{in: "10.0.0.0/24", want: "10-0-0-0-24"},
+	}
+
+	for _, tt := range tests {
+		if got := Slugify(tt.in); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLessPrefix(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "10.0.0.0/24", b: "9.0.0.0/8", want: false},
+		{a: "9.0.0.0/8", b: "10.0.0.0/24", want: true},
+		{a: "10.0.0.0/16", b: "10.0.0.0/24", want: true},
+		{a: "10.0.0.0/24", b: "10.0.0.0/24", want: false},
+		{a: "192.168.0.0/16", b: "2001:db8::/32", want: true},
+		{a: "2001:db8::/32", b: "bogus", want: true},
+		{a: "bogus", b: "10.0.0.0/8", want: false},
+		{a: "a", b: "b", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := lessPrefix(tt.a, tt.b); got != tt.want {
+			t.Errorf("lessPrefix(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestExportPrefixOrder(t *testing.T) {
+	subnets := []device42.Subnet{
+		{SubnetID: 1, Network: "10.0.0.0", MaskBits: 24},
+		{SubnetID: 2, Network: "9.0.0.0", MaskBits: 8},
+		{SubnetID: 3, Network: "10.0.0.0", MaskBits: 16},
+		{SubnetID: 4, Network: "2001:db8::", MaskBits: 32, ParentVlanID: 7},
+	}
+	vlans := []device42.VLAN{{VlanID: 7, Number: 100, Name: "web"}}
+
+	d := Export(nil, nil, vlans, subnets, nil)
+
+	got := []string{}
+	for _, p := range d.Prefixes {
+		got = append(got, p.Prefix)
+	}
+	want := []string{"9.0.0.0/8", "10.0.0.0/16", "10.0.0.0/24", "2001:db8::/32"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Export prefixes = %v, want %v", got, want)
+	}
+	if v := d.Prefixes[3].VLAN; v == nil || *v != (Ref{VID: 100, Name: "web"}) {
+		t.Errorf("Export prefix vlan = %+v, want vid 100", v)
+	}
+}
+
+func TestFromIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       device42.IP
+		maskBits int
+		want     IPAddress
+		wantErr  bool
+	}{
+		{
+			name:     "host name label",
+			ip:       device42.IP{ID: 1, Address: "10.0.0.5", Label: "Web01", MacAddress: "AA:BB:CC:DD:EE:FF", VRFGroup: "prod"},
+			maskBits: 24,
+			want: IPAddress{
+				Address:      "10.0.0.5/24",
+				VRF:          &Ref{Name: "prod"},
+				Status:       StatusActive,
+				DNSName:      "web01",
+				Description:  "Web01",
+				CustomFields: map[string]interface{}{CustomFieldID: 1, CustomFieldMacAddress: "aa:bb:cc:dd:ee:ff"},
+			},
+		},
+		{
+			name: "free text label without a subnet",
+			ip:   device42.IP{ID: 2, Address: "2001:db8::5", Label: "web server", Notes: "n"},
+			want: IPAddress{
+				Address:      "2001:db8::5/128",
+				Status:       StatusActive,
+				Description:  "web server",
+				Comments:     "n",
+				CustomFields: map[string]interface{}{CustomFieldID: 2},
+			},
+		},
+		{name: "invalid", ip: device42.IP{Address: "bogus"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := FromIP(tt.ip, tt.maskBits)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: FromIP error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: FromIP = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestToIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      IPAddress
+		want    device42.IP
+		wantErr bool
+	}{
+		{
+			name: "dns name",
+			in: IPAddress{
+				Address:      "10.0.0.5/24",
+				DNSName:      "web01",
+				Description:  "web server",
+				Comments:     "n",
+				VRF:          &Ref{Name: "prod"},
+				CustomFields: map[string]interface{}{CustomFieldMacAddress: "aa:bb:cc:dd:ee:ff"},
+			},
+			want: device42.IP{Address: "10.0.0.5", Label: "web01", Notes: "n", MacAddress: "aa:bb:cc:dd:ee:ff", VRFGroup: "prod"},
+		},
+		{
+			name: "description",
+			in:   IPAddress{Address: "2001:db8::5/64", Description: "web server"},
+			want: device42.IP{Address: "2001:db8::5", Label: "web server"},
+		},
+		{name: "no prefix length", in: IPAddress{Address: "10.0.0.5"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.in.ToIP()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ToIP error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ToIP = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubnetRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      Prefix
+		want    device42.Subnet
+		wantErr bool
+	}{
+		{
+			name: "host bits are masked",
+			in:   Prefix{Prefix: "10.0.0.5/24", Description: "web", Comments: "c", VRF: &Ref{Name: "prod"}, Tags: []Tag{{Name: "dmz"}}},
+			want: device42.Subnet{Network: "10.0.0.0", MaskBits: 24, Name: "web", Description: "c", VrfGroup: "prod", Tags: []string{"dmz"}},
+		},
+		{
+			name: "ipv6",
+			in:   Prefix{Prefix: "2001:db8::/48"},
+			want: device42.Subnet{Network: "2001:db8::", MaskBits: 48, Tags: []string{}},
+		},
+		{name: "invalid", in: Prefix{Prefix: "10.0.0.0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.in.ToSubnet()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ToSubnet error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ToSubnet = %+v, want %+v", tt.name, got, tt.want)
+		}
+
+		// the vrf group is read back by name
+		got.VrfGroupName = got.VrfGroup
+		p := FromSubnet(got)
+		if p.Prefix != tt.want.Network+"/"+strconv.Itoa(tt.want.MaskBits) || p.Description != tt.in.Description || p.Comments != tt.in.Comments {
+			t.Errorf("%s: FromSubnet = %+v, want the prefix of %+v", tt.name, p, tt.in)
+		}
+		if (p.VRF == nil) != (tt.in.VRF == nil) {
+			t.Errorf("%s: FromSubnet vrf = %+v, want %+v", tt.name, p.VRF, tt.in.VRF)
+		}
+	}
+}
+
+func TestVLANRoundTrip(t *testing.T) {
+	v := device42.VLAN{VlanID: 3, Number: 100, Name: "web", Description: "d", Notes: "n", Tags: []string{"Prod Net"}}
+
+	n := FromVLAN(v)
+	if n.VID != 100 || n.Status != StatusActive || n.CustomFields[CustomFieldID] != 3 {
+		t.Errorf("FromVLAN = %+v", n)
+	}
+	if want := []Tag{{Name: "Prod Net", Slug: "prod-net"}}; !reflect.DeepEqual(n.Tags, want) {
+		t.Errorf("FromVLAN tags = %+v, want %+v", n.Tags, want)
+	}
+
+	got := n.ToVLAN()
+	v.VlanID = 0
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("ToVLAN = %+v, want %+v", got, v)
+	}
+}
+
+func TestFromBuilding(t *testing.T) {
+	got := FromBuilding(device42.Building{BuildingID: 1, Name: "Main Office", Address: "1 Road", Notes: "n"})
+	want := Site{
+		Name:            "Main Office",
+		Slug:            "main-office",
+		Status:          StatusActive,
+		PhysicalAddress: "1 Road",
+		Comments:        "n",
+		CustomFields:    map[string]interface{}{CustomFieldID: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromBuilding = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status Status
+		tags   []Tag
+	}{
+		{
+			name:   "import shape",
+			body:   `{"status": "reserved", "tags": ["Prod Net"]}`,
+			status: "reserved",
+			tags:   []Tag{{Name: "Prod Net", Slug: "prod-net"}},
+		},
+		{
+			name:   "export shape",
+			body:   `{"status": {"value": "active", "label": "Active"}, "tags": [{"name": "dmz", "slug": "dmz-zone"}]}`,
+			status: StatusActive,
+			tags:   []Tag{{Name: "dmz", Slug: "dmz-zone"}},
+		},
+	}
+
+	for _, tt := range tests {
+		p := Prefix{}
+		if err := json.Unmarshal([]byte(tt.body), &p); err != nil {
+			t.Errorf("%s: Unmarshal error = %v", tt.name, err)
+			continue
+		}
+		if p.Status != tt.status || !reflect.DeepEqual(p.Tags, tt.tags) {
+			t.Errorf("%s: status, tags = %q, %+v, want %q, %+v", tt.name, p.Status, p.Tags, tt.status, tt.tags)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package device42
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestAPI returns an api client for a test server running h
+func newTestAPI(t *testing.T, h http.HandlerFunc) *API {
+	t.Helper()
+
+	s := httptest.NewServer(h)
+	t.Cleanup(s.Close)
+
+	api, err := NewAPIBasicAuth("u", "p", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.url(s.URL)
+	api.InfoLogger(log.New(io.Discard, "", 0))
+
+	return api
+}
+
+// writeJSON answers a test request with v as json
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeCreated answers a test post the way device42 reports a created object
+func writeCreated(w http.ResponseWriter, id int) {
+	writeJSON(w, map[string]interface{}{"code": 0, "msg": []interface{}{"added", id, ""}})
+}
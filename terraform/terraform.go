@@ -0,0 +1,202 @@
+// Package terraform renders device42 ipam state as terraform json
+// configuration with import blocks
+package terraform
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+// resource types
+const (
+	ResourceBuilding = "device42_building"
+	ResourceVRFGroup = "device42_ipam_vrfgroup"
+	ResourceVLAN     = "device42_ipam_vlan"
+	ResourceSubnet   = "device42_ipam_subnet"
+)
+
+var nameReplacer = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Import type
+type Import struct {
+	To string `json:"to"`
+	ID string `json:"id"`
+}
+
+// Config type
+// a .tf.json document
+type Config struct {
+	Resource map[string]map[string]map[string]interface{} `json:"resource"`
+	Import   []Import                                     `json:"import"`
+
+	// addresses maps a resource type and device42 id to a resource address
+	addresses map[string]map[int]string
+}
+
+// Build will create a configuration with a resource and import block for
+// every object. resource names are derived from the object's name and id so
+// they are stable between exports, and references between the objects are
+// expressed as terraform references
+func Build(buildings []device42.Building, vrfGroups []device42.VRFGroup, vlans []device42.VLAN, subnets []device42.Subnet) *Config {
+	c := Config{
+		Resource:  map[string]map[string]map[string]interface{}{},
+		Import:    []Import{},
+		addresses: map[string]map[int]string{},
+	}
+
+	for _, b := range buildings {
+		c.add(ResourceBuilding, b.BuildingID, b.Name, attributes{
+			"name":         b.Name,
+			"address":      b.Address,
+			"contact_name": b.ContactName,
+			"notes":        b.Notes,
+		})
+	}
+
+	buildingsByName := map[string]int{}
+	for _, b := range buildings {
+		buildingsByName[b.Name] = b.BuildingID
+	}
+	for _, v := range vrfGroups {
+		names := []interface{}{}
+		for _, b := range v.Buildings {
+			if id, ok := buildingsByName[b]; ok {
+				names = append(names, c.ref(ResourceBuilding, id, "name"))
+			} else {
+				names = append(names, b)
+			}
+		}
+		c.add(ResourceVRFGroup, v.ID, v.Name, attributes{
+			"name":        v.Name,
+			"description": v.Description,
+			"buildings":   names,
+		})
+	}
+
+	for _, v := range vlans {
+		c.add(ResourceVLAN, v.VlanID, v.Name+"_"+strconv.Itoa(v.Number), attributes{
+			"number":      v.Number,
+			"name":        v.Name,
+			"description": v.Description,
+			"notes":       v.Notes,
+			"tags":        v.Tags,
+		})
+	}
+
+	// subnets may reference any other subnet as their parent, so every
+	// address is known before the resources are added
+	c.addresses[ResourceSubnet] = map[int]string{}
+	for _, s := range subnets {
+		c.addresses[ResourceSubnet][s.SubnetID] = ResourceSubnet + "." + resourceName(ResourceSubnet, subnetName(s), s.SubnetID)
+	}
+	for _, s := range subnets {
+		gateway := ""
+		if g, err := s.GatewayAddr(); err == nil {
+			gateway = g.String()
+		}
+
+		c.add(ResourceSubnet, s.SubnetID, subnetName(s), attributes{
+			"network":          device42.NormalizeAddress(s.Network),
+			"mask_bits":        s.MaskBits,
+			"name":             s.Name,
+			"description":      s.Description,
+			"gateway":          gateway,
+			"range_begin":      device42.NormalizeAddress(s.RangeBegin),
+			"range_end":        device42.NormalizeAddress(s.RangeEnd),
+			"vrf_group_id":     c.refOrID(ResourceVRFGroup, s.VrfGroupID),
+			"parent_vlan_id":   c.refOrID(ResourceVLAN, s.ParentVlanID),
+			"parent_subnet_id": c.refOrID(ResourceSubnet, s.ParentSubnetID),
+			"tags":             s.Tags,
+		})
+	}
+
+	sort.Slice(c.Import, func(i, j int) bool { return c.Import[i].To < c.Import[j].To })
+
+	return &c
+}
+
+// JSON will render the configuration as .tf.json
+func (c *Config) JSON() ([]byte, error) {
+	return json.MarshalIndent(c, "", "  ")
+}
+
+type attributes map[string]interface{}
+
+// add will add a resource and its import block, leaving out empty attributes
+func (c *Config) add(resource string, id int, name string, attrs attributes) {
+	if _, ok := c.Resource[resource]; !ok {
+		c.Resource[resource] = map[string]map[string]interface{}{}
+	}
+	if _, ok := c.addresses[resource]; !ok {
+		c.addresses[resource] = map[int]string{}
+	}
+
+	n := resourceName(resource, name, id)
+	body := map[string]interface{}{}
+	for k, v := range attrs {
+		switch t := v.(type) {
+		case nil:
+			continue
+		case string:
+			if t == "" {
+				continue
+			}
+		case int:
+			if t == 0 {
+				continue
+			}
+		case []string:
+			if len(t) == 0 {
+				continue
+			}
+		case []interface{}:
+			if len(t) == 0 {
+				continue
+			}
+		}
+		body[k] = v
+	}
+
+	c.Resource[resource][n] = body
+	c.addresses[resource][id] = resource + "." + n
+	c.Import = append(c.Import, Import{To: resource + "." + n, ID: strconv.Itoa(id)})
+}
+
+// ref will return a reference to an attribute of an exported resource
+func (c *Config) ref(resource string, id int, attr string) string {
+	return "${" + c.addresses[resource][id] + "." + attr + "}"
+}
+
+// refOrID will return a reference to an exported resource's id, or the
+// device42 id when the resource is not part of the export
+func (c *Config) refOrID(resource string, id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	if _, ok := c.addresses[resource][id]; ok {
+		return c.ref(resource, id, "id")
+	}
+	return id
+}
+
+// subnetName will name a subnet by its network when it has no name
+func subnetName(s device42.Subnet) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return device42.NormalizeAddress(s.Network) + "_" + strconv.Itoa(s.MaskBits)
+}
+
+// resourceName will build a stable terraform resource name
+func resourceName(resource, name string, id int) string {
+	n := strings.Trim(nameReplacer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if n == "" || (n[0] >= '0' && n[0] <= '9') {
+		n = strings.TrimPrefix(strings.TrimPrefix(resource, "device42_"), "ipam_") + "_" + n
+	}
+	return strings.TrimSuffix(n, "_") + "_" + strconv.Itoa(id)
+}
@@ -0,0 +1,120 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+func TestResourceName(t *testing.T) {
+	tests := []struct {
+		resource, name string
+		id             int
+		want           string
+	}{
+		{resource: ResourceBuilding, name: "HQ", id: 1, want: "hq_1"},
+		{resource: ResourceSubnet, name: "Web Servers!", id: 2, want: "web_servers_2"},
+		{resource: ResourceSubnet, name: "10.0.0.0_24", id: 3, want: "subnet_10_0_0_0_24_3"},
+		{resource: ResourceVRFGroup, name: "", id: 4, want: "vrfgroup_4"},
+		{resource: ResourceVLAN, name: "_100", id: 5, want: "vlan_100_5"},
+	}
+
+	for _, tt := range tests {
+		if got := resourceName(tt.resource, tt.name, tt.id); got != tt.want {
+			t.Errorf("resourceName(%q, %q, %d) = %q, want %q", tt.resource, tt.name, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestSubnetName(t *testing.T) {
+	tests := []struct {
+		subnet device42.Subnet
+		want   string
+	}{
+		{subnet: device42.Subnet{Name: "web", Network: "10.0.0.0", MaskBits: 24}, want: "web"},
+		{subnet: device42.Subnet{Network: "10.0.0.0", MaskBits: 24}, want: "10.0.0.0_24"},
+		{subnet: device42.Subnet{Network: "2001:DB8:0:0::", MaskBits: 64}, want: "2001:db8::_64"},
+	}
+
+	for _, tt := range tests {
+		if got := subnetName(tt.subnet); got != tt.want {
+			t.Errorf("subnetName(%s/%d) = %q, want %q", tt.subnet.Network, tt.subnet.MaskBits, got, tt.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	c := Build(
+		[]device42.Building{{BuildingID: 1, Name: "HQ"}},
+		[]device42.VRFGroup{{ID: 2, Name: "prod", Buildings: []string{"HQ", "Remote"}}},
+		[]device42.VLAN{{VlanID: 3, Name: "web", Number: 100}},
+		[]device42.Subnet{
+			{SubnetID: 5, Name: "hosts", Network: "10.0.0.0", MaskBits: 25, ParentSubnetID: 4, VrfGroupID: 2, ParentVlanID: 3, Gateway: "10.0.0.1"},
+			{SubnetID: 4, Network: "10.0.0.0", MaskBits: 24, VrfGroupID: 9},
+		},
+	)
+
+	tests := []struct {
+		resource, name string
+		want           map[string]interface{}
+	}{
+		{
+			resource: ResourceBuilding,
+			name:     "hq_1",
+			want:     map[string]interface{}{"name": "HQ"},
+		},
+		{
+			resource: ResourceVRFGroup,
+			name:     "prod_2",
+			want: map[string]interface{}{
+				"name":      "prod",
+				"buildings": []interface{}{"${device42_building.hq_1.name}", "Remote"},
+			},
+		},
+		{
+			resource: ResourceVLAN,
+			name:     "web_100_3",
+			want:     map[string]interface{}{"name": "web", "number": 100},
+		},
+		{
+			resource: ResourceSubnet,
+			name:     "hosts_5",
+			want: map[string]interface{}{
+				"network":          "10.0.0.0",
+				"mask_bits":        25,
+				"name":             "hosts",
+				"gateway":          "10.0.0.1",
+				"vrf_group_id":     "${device42_ipam_vrfgroup.prod_2.id}",
+				"parent_vlan_id":   "${device42_ipam_vlan.web_100_3.id}",
+				"parent_subnet_id": "${device42_ipam_subnet.subnet_10_0_0_0_24_4.id}",
+			},
+		},
+		{
+			resource: ResourceSubnet,
+			name:     "subnet_10_0_0_0_24_4",
+			want: map[string]interface{}{
+				"network":      "10.0.0.0",
+				"mask_bits":    24,
+				"vrf_group_id": 9,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := c.Resource[tt.resource][tt.name]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s.%s = %v, want %v", tt.resource, tt.name, got, tt.want)
+		}
+	}
+
+	imports := []Import{
+		{To: "device42_building.hq_1", ID: "1"},
+		{To: "device42_ipam_subnet.hosts_5", ID: "5"},
+		{To: "device42_ipam_subnet.subnet_10_0_0_0_24_4", ID: "4"},
+		{To: "device42_ipam_vlan.web_100_3", ID: "3"},
+		{To: "device42_ipam_vrfgroup.prod_2", ID: "2"},
+	}
+	if !reflect.DeepEqual(c.Import, imports) {
+		t.Errorf("Import = %v, want %v", c.Import, imports)
+	}
+}
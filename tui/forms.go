@@ -0,0 +1,251 @@
+package tui
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newIP will open a form to add an ip to the selected subnet
+func (a *App) newIP() {
+	if a.subnet == nil {
+		a.setError(errors.New("select a subnet to add an ip to"))
+		return
+	}
+	a.ipForm("Add IP to "+subnetText(a.subnet), &device42.IP{SubnetID: a.subnet.SubnetID})
+}
+
+// editIP will open a form to update an ip
+func (a *App) editIP(ip *device42.IP) {
+	edit := *ip
+	a.ipForm("Edit IP "+ip.Address, &edit)
+}
+
+func (a *App) ipForm(title string, ip *device42.IP) {
+	create := ip.ID == 0
+
+	f := tview.NewForm()
+	if create {
+		f.AddInputField("Address", ip.Address, 40, nil, nil)
+	}
+	f.AddInputField("Label", ip.Label, 40, nil, nil).
+		AddInputField("MAC Address", ip.MacAddress, 20, nil, nil).
+		AddInputField("Notes", ip.Notes, 40, nil, nil)
+
+	f.AddButton("Save", func() {
+		if create {
+			ip.Address = formText(f, "Address")
+		} else if l := blankedField(f, map[string]string{
+			"Label":       ip.Label,
+			"MAC Address": ip.MacAddress,
+			"Notes":       ip.Notes,
+		}, "Label", "MAC Address", "Notes"); l != "" {
+			a.setError(errors.New(strings.ToLower(l) + " can not be emptied, clear the ip (c) to remove its label, mac address and notes"))
+			return
+		}
+		ip.Label = formText(f, "Label")
+		ip.MacAddress = formText(f, "MAC Address")
+		ip.MAC = ip.MacAddress
+		ip.Notes = formText(f, "Notes")
+
+		if _, err := ip.Addr(); err != nil {
+			a.setError(err)
+			return
+		}
+
+		a.closeForm()
+		s := a.subnet
+		a.load("saving ip "+ip.Address, func() (func(), error) {
+			var err error
+			if create {
+				_, err = a.api.SetIP(ip)
+			} else {
+				_, err = a.api.UpdateIP(ip)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return func() { a.selectSubnet(s, true) }, nil
+		})
+	})
+	a.showForm(title, f)
+}
+
+// clearIP will ask before clearing an ip
+func (a *App) clearIP(ip *device42.IP) {
+	address := ip.Address
+	a.confirm("Clear "+address+"? Its label, mac address and notes are removed and it becomes available.", func() {
+		s := a.subnet
+		a.load("clearing ip "+address, func() (func(), error) {
+			err := a.api.ClearIP(address)
+			if err != nil {
+				return nil, err
+			}
+			return func() { a.selectSubnet(s, true) }, nil
+		})
+	})
+}
+
+// newSubnet will open a form to add a subnet to the selected vrf group,
+// within the subnet under the cursor
+func (a *App) newSubnet() {
+	if a.group == nil {
+		a.setError(errors.New("select a vrf group to add a subnet to"))
+		return
+	}
+
+	s := device42.Subnet{VrfGroup: a.group.Name}
+	title := "Add subnet to " + a.group.Name
+	if p := a.currentSubnet(); p != nil {
+		s.ParentSubnetID = p.SubnetID
+		title = "Add subnet to " + subnetText(p)
+	}
+	a.subnetForm(title, &s)
+}
+
+// editSubnet will open a form to update a subnet
+func (a *App) editSubnet(s *device42.Subnet) {
+	edit := *s
+	if edit.VrfGroup == "" {
+		edit.VrfGroup = edit.VrfGroupName
+	}
+	a.subnetForm("Edit subnet "+subnetText(s), &edit)
+}
+
+func (a *App) subnetForm(title string, s *device42.Subnet) {
+	create := s.SubnetID == 0
+
+	f := tview.NewForm()
+	if create {
+		f.AddInputField("Network", s.Network, 40, nil, nil).
+			AddInputField("Mask Bits", "", 4, tview.InputFieldInteger, nil)
+	}
+	f.AddInputField("Name", s.Name, 40, nil, nil).
+		AddInputField("Description", s.Description, 40, nil, nil).
+		AddInputField("Tags", strings.Join(s.Tags, ","), 40, nil, nil)
+
+	f.AddButton("Save", func() {
+		if create {
+			s.Network = formText(f, "Network")
+			bits, err := strconv.Atoi(formText(f, "Mask Bits"))
+			if err != nil {
+				a.setError(errors.New("mask bits must be a number"))
+				return
+			}
+			s.MaskBits = bits
+		} else if l := blankedField(f, map[string]string{
+			"Name":        s.Name,
+			"Description": s.Description,
+			"Tags":        strings.Join(s.Tags, ","),
+		}, "Name", "Description", "Tags"); l != "" {
+			a.setError(errors.New(strings.ToLower(l) + " can not be emptied, device42 keeps the old value of empty fields"))
+			return
+		}
+		s.Name = formText(f, "Name")
+		s.Description = formText(f, "Description")
+		s.Tags = []string{}
+		for _, t := range strings.Split(formText(f, "Tags"), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				s.Tags = append(s.Tags, t)
+			}
+		}
+
+		if _, err := s.Prefix(); err != nil {
+			a.setError(err)
+			return
+		}
+
+		a.closeForm()
+		g := *a.group
+		a.load("saving subnet "+subnetText(s), func() (func(), error) {
+			_, err := a.api.SetSubnet(s)
+			if err != nil {
+				return nil, err
+			}
+			return func() { a.selectVRFGroup(g, true) }, nil
+		})
+	})
+	a.showForm(title, f)
+}
+
+// showForm will show a form above the main page. escape or cancel closes it
+func (a *App) showForm(title string, f *tview.Form) {
+	f.AddButton("Cancel", a.closeForm).
+		SetCancelFunc(a.closeForm)
+	f.SetBorder(true).SetTitle(" " + tview.Escape(title) + " ")
+
+	a.returnTo = a.app.GetFocus()
+	a.pages.AddPage(pageForm, centered(f, 64, f.GetFormItemCount()*2+5), true, true)
+	a.app.SetFocus(f)
+}
+
+func (a *App) closeForm() {
+	a.pages.RemovePage(pageForm)
+	a.app.SetFocus(a.returnTo)
+}
+
+// confirm will ask a yes or no question, calling yes on yes
+func (a *App) confirm(question string, yes func()) {
+	m := tview.NewModal().
+		SetText(question).
+		AddButtons([]string{"No", "Yes"}).
+		SetDoneFunc(func(_ int, label string) {
+			a.closeModal()
+			if label == "Yes" {
+				yes()
+			}
+		})
+	m.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEscape {
+			a.closeModal()
+			return nil
+		}
+		return ev
+	})
+
+	a.returnTo = a.app.GetFocus()
+	a.pages.AddPage(pageModal, m, false, true)
+	a.app.SetFocus(m)
+}
+
+func (a *App) closeModal() {
+	a.pages.RemovePage(pageModal)
+	a.app.SetFocus(a.returnTo)
+}
+
+// centered will place a primitive of a fixed size in the middle of the screen
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 0, true).
+			AddItem(nil, 0, 1, false), width, 0, true).
+		AddItem(nil, 0, 1, false)
+}
+
+// formText returns the trimmed text of a form's input field
+func formText(f *tview.Form, label string) string {
+	field, ok := f.GetFormItemByLabel(label).(*tview.InputField)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(field.GetText())
+}
+
+// blankedField returns the first of labels whose field held a value before
+// and is empty now. empty values are not posted, so device42 would keep the
+// old value rather than remove it
+func blankedField(f *tview.Form, before map[string]string, labels ...string) string {
+	for _, l := range labels {
+		if before[l] != "" && formText(f, l) == "" {
+			return l
+		}
+	}
+	return ""
+}
@@ -0,0 +1,549 @@
+// Package tui is an interactive terminal interface for browsing and editing
+// device42 ipam
+package tui
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	device42 "github.com/chopnico/device42-go"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	pageMain  = "main"
+	pageForm  = "form"
+	pageModal = "modal"
+
+	hints = "[yellow]tab[white] pane  [yellow]enter[white] open  [yellow]/[white] search  " +
+		"[yellow]n[white] new  [yellow]e[white] edit  [yellow]c[white] clear ip  [yellow]r[white] reload  [yellow]q[white] quit"
+)
+
+// App type
+// the vrf groups, vlans, subnet tree and ips of the selected subnet
+type App struct {
+	api *device42.API
+	app *tview.Application
+
+	pages     *tview.Pages
+	vrfGroups *tview.List
+	vlans     *tview.Table
+	subnets   *tview.TreeView
+	ips       *tview.Table
+	footer    *tview.Flex
+	status    *tview.TextView
+	search    *tview.InputField
+
+	// panes in tab order
+	panes []tview.Primitive
+	// search text by pane
+	filters map[tview.Primitive]string
+	// pane being searched
+	searching tview.Primitive
+	// pane focused before a form or question
+	returnTo tview.Primitive
+	// log lines shown in the footer
+	logs int
+
+	groups   []device42.VRFGroup
+	shown    []device42.VRFGroup
+	group    *device42.VRFGroup
+	tree     []*device42.SubnetNode
+	subnet   *device42.Subnet
+	ipList   []device42.IP
+	vlanList []device42.VLAN
+}
+
+// New will create the interface
+func New(api *device42.API) *App {
+	a := App{
+		api:     api,
+		app:     tview.NewApplication(),
+		filters: map[tview.Primitive]string{},
+	}
+
+	a.vrfGroups = tview.NewList().ShowSecondaryText(false)
+	a.vrfGroups.SetBorder(true).SetTitle(" VRF Groups ")
+	a.vrfGroups.SetSelectedFunc(func(i int, _ string, _ string, _ rune) {
+		if i < len(a.shown) {
+			a.selectVRFGroup(a.shown[i], true)
+		}
+	})
+
+	a.vlans = tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	a.vlans.SetBorder(true).SetTitle(" VLANs ")
+
+	a.subnets = tview.NewTreeView().SetTopLevel(1)
+	a.subnets.SetBorder(true).SetTitle(" Subnets ")
+	a.subnets.SetSelectedFunc(func(n *tview.TreeNode) {
+		if s, ok := n.GetReference().(*device42.Subnet); ok {
+			a.selectSubnet(s, true)
+		}
+	})
+
+	a.ips = tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	a.ips.SetBorder(true).SetTitle(" IPs ")
+
+	a.status = tview.NewTextView().SetDynamicColors(true)
+	a.search = tview.NewInputField().SetLabel("/")
+	a.search.SetChangedFunc(a.searchChanged)
+	a.search.SetDoneFunc(a.searchDone)
+
+	a.footer = tview.NewFlex().AddItem(a.status, 0, 1, false)
+
+	left := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(a.vrfGroups, 0, 1, true).
+		AddItem(a.vlans, 0, 1, false)
+	body := tview.NewFlex().
+		AddItem(left, 0, 1, true).
+		AddItem(a.subnets, 0, 2, false).
+		AddItem(a.ips, 0, 2, false)
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(body, 0, 1, true).
+		AddItem(a.footer, 1, 0, false)
+
+	a.panes = []tview.Primitive{a.vrfGroups, a.subnets, a.ips, a.vlans}
+	a.pages = tview.NewPages().AddPage(pageMain, root, true, true)
+	a.app.SetRoot(a.pages, true).SetInputCapture(a.keys)
+
+	return &a
+}
+
+// Run will load device42 and run the interface until it is quit
+func (a *App) Run() error {
+	a.reload()
+	return a.app.Run()
+}
+
+// Stop will quit the interface
+func (a *App) Stop() {
+	a.app.Stop()
+}
+
+// keys handles the keys of the main page. forms and the search field receive
+// their keys untouched
+func (a *App) keys(ev *tcell.EventKey) *tcell.EventKey {
+	if name, _ := a.pages.GetFrontPage(); name != pageMain || a.searching != nil {
+		return ev
+	}
+
+	switch ev.Key() {
+	case tcell.KeyTab:
+		a.cycle(1)
+		return nil
+	case tcell.KeyBacktab:
+		a.cycle(-1)
+		return nil
+	case tcell.KeyRune:
+	default:
+		return ev
+	}
+
+	switch ev.Rune() {
+	case 'q':
+		a.Stop()
+	case '/':
+		a.startSearch()
+	case 'r':
+		a.reload()
+	case 'n':
+		switch a.app.GetFocus() {
+		case a.subnets:
+			a.newSubnet()
+		case a.ips:
+			a.newIP()
+		}
+	case 'e':
+		switch a.app.GetFocus() {
+		case a.subnets:
+			if s := a.currentSubnet(); s != nil {
+				a.editSubnet(s)
+			}
+		case a.ips:
+			if ip := a.currentIP(); ip != nil {
+				a.editIP(ip)
+			}
+		}
+	case 'c':
+		if ip := a.currentIP(); ip != nil && a.app.GetFocus() == a.ips {
+			a.clearIP(ip)
+		}
+	default:
+		return ev
+	}
+	return nil
+}
+
+// cycle will move the focus between panes
+func (a *App) cycle(step int) {
+	focus := a.app.GetFocus()
+	for i, p := range a.panes {
+		if p == focus {
+			a.app.SetFocus(a.panes[(i+step+len(a.panes))%len(a.panes)])
+			return
+		}
+	}
+	a.app.SetFocus(a.panes[0])
+}
+
+// setStatus will replace the footer text
+func (a *App) setStatus(msg string) {
+	a.status.SetText(msg)
+}
+
+// Write implements io.Writer so the api's loggers can write to the footer
+// instead of over the interface
+func (a *App) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	a.app.QueueUpdateDraw(func() {
+		a.logs++
+		a.setStatus(tview.Escape(msg))
+	})
+	return len(p), nil
+}
+
+// setError will show an error in the footer
+func (a *App) setError(err error) {
+	a.status.SetText("[red]" + tview.Escape(err.Error()))
+}
+
+// load will fetch from device42 without blocking the interface. fetch runs
+// in the background and the function it returns is applied on the
+// interface's goroutine
+func (a *App) load(msg string, fetch func() (func(), error)) {
+	a.setStatus(msg + "...")
+	logs := a.logs
+	go func() {
+		apply, err := fetch()
+		a.app.QueueUpdateDraw(func() {
+			if err != nil {
+				a.setError(err)
+				return
+			}
+			// keep what was logged while fetching, such as dry runs
+			if a.logs == logs {
+				a.setStatus(hints)
+			}
+			if apply != nil {
+				apply()
+			}
+		})
+	}()
+}
+
+// reload will fetch the vrf groups and vlans, and the subnets and ips of
+// the current selection
+func (a *App) reload() {
+	a.load("loading vrf groups and vlans", func() (func(), error) {
+		groups, err := a.api.GetVRFGroups()
+		if err != nil {
+			return nil, err
+		}
+		vlans, err := a.api.GetVLANs()
+		if err != nil {
+			return nil, err
+		}
+
+		return func() {
+			a.groups = *groups
+			sort.SliceStable(a.groups, func(i, j int) bool { return a.groups[i].Name < a.groups[j].Name })
+			a.vlanList = *vlans
+			sort.SliceStable(a.vlanList, func(i, j int) bool { return a.vlanList[i].Number < a.vlanList[j].Number })
+			a.renderVRFGroups()
+			a.renderVLANs()
+
+			if a.group != nil {
+				a.selectVRFGroup(*a.group, false)
+			}
+		}, nil
+	})
+}
+
+// selectVRFGroup will load the subnet tree of a vrf group, and the ips of
+// the selected subnet when it is still in the group
+func (a *App) selectVRFGroup(g device42.VRFGroup, focus bool) {
+	a.load("loading subnets of "+g.Name, func() (func(), error) {
+		tree, err := a.api.GetSubnetTree(g.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() {
+			if a.group == nil || a.group.ID != g.ID {
+				a.subnet = nil
+				a.ipList = nil
+				a.renderIPs()
+			}
+			a.group = &g
+			a.tree = tree
+			a.renderSubnets()
+			if focus {
+				a.app.SetFocus(a.subnets)
+			}
+
+			if s := a.currentSubnet(); s != nil && a.subnet != nil && s.SubnetID == a.subnet.SubnetID {
+				a.selectSubnet(s, false)
+			}
+		}, nil
+	})
+}
+
+// selectSubnet will load the ips of a subnet. ips are listed by subnet name,
+// so they are matched to the subnet by id
+func (a *App) selectSubnet(s *device42.Subnet, focus bool) {
+	a.load("loading ips of "+subnetText(s), func() (func(), error) {
+		var ips *[]device42.IP
+		var err error
+		if s.Name != "" {
+			ips, err = a.api.GetIPsBySubnet(url.QueryEscape(s.Name))
+		} else {
+			ips, err = a.api.GetIPs()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		list := []device42.IP{}
+		for _, ip := range *ips {
+			if ip.SubnetID == s.SubnetID {
+				list = append(list, ip)
+			}
+		}
+		sortIPs(list)
+
+		return func() {
+			a.subnet = s
+			a.ipList = list
+			a.renderIPs()
+			if focus {
+				a.app.SetFocus(a.ips)
+			}
+		}, nil
+	})
+}
+
+// renderVRFGroups will fill the vrf group pane
+func (a *App) renderVRFGroups() {
+	current := a.vrfGroups.GetCurrentItem()
+	a.vrfGroups.Clear()
+	a.shown = []device42.VRFGroup{}
+
+	for _, g := range a.groups {
+		if !matches(a.filters[a.vrfGroups], g.Name, g.Description, strings.Join(g.Buildings, " ")) {
+			continue
+		}
+		a.shown = append(a.shown, g)
+		a.vrfGroups.AddItem(tview.Escape(g.Name), "", 0, nil)
+	}
+	if current < len(a.shown) {
+		a.vrfGroups.SetCurrentItem(current)
+	}
+}
+
+// renderVLANs will fill the vlan pane
+func (a *App) renderVLANs() {
+	a.vlans.Clear()
+	header(a.vlans, "Number", "Name", "Description")
+
+	row := 1
+	for _, v := range a.vlanList {
+		n := strconv.Itoa(v.Number)
+		if !matches(a.filters[a.vlans], n, v.Name, v.Description, strings.Join(v.Tags, " ")) {
+			continue
+		}
+		a.vlans.SetCell(row, 0, tview.NewTableCell(n))
+		a.vlans.SetCell(row, 1, tview.NewTableCell(tview.Escape(v.Name)))
+		a.vlans.SetCell(row, 2, tview.NewTableCell(tview.Escape(v.Description)).SetExpansion(1))
+		row++
+	}
+	a.vlans.ScrollToBeginning()
+}
+
+// renderSubnets will fill the subnet tree, keeping the selected subnet
+func (a *App) renderSubnets() {
+	title := ""
+	if a.group != nil {
+		title = a.group.Name
+	}
+	root := tview.NewTreeNode(title)
+	a.subnets.SetRoot(root).SetCurrentNode(nil)
+
+	filter := a.filters[a.subnets]
+	tree := device42.FilterSubnetTree(a.tree, func(s *device42.Subnet) bool {
+		return matches(filter, subnetText(s), s.Description, s.VrfGroupName, s.ParentVlanName, strings.Join(s.Tags, " "))
+	})
+
+	var add func(parent *tview.TreeNode, nodes []*device42.SubnetNode)
+	add = func(parent *tview.TreeNode, nodes []*device42.SubnetNode) {
+		for _, n := range nodes {
+			s := n.Subnet
+			node := tview.NewTreeNode(tview.Escape(subnetText(&s))).
+				SetReference(&s).
+				SetSelectable(true)
+			parent.AddChild(node)
+
+			if a.subnet != nil && a.subnet.SubnetID == s.SubnetID {
+				a.subnets.SetCurrentNode(node)
+			}
+			add(node, n.Children)
+		}
+	}
+	add(root, tree)
+
+	if a.subnets.GetCurrentNode() == nil && len(root.GetChildren()) > 0 {
+		a.subnets.SetCurrentNode(root.GetChildren()[0])
+	}
+}
+
+// renderIPs will fill the ip pane
+func (a *App) renderIPs() {
+	title := " IPs "
+	if a.subnet != nil {
+		title = " IPs of " + tview.Escape(subnetText(a.subnet)) + " "
+	}
+	a.ips.SetTitle(title)
+
+	a.ips.Clear()
+	header(a.ips, "Address", "Label", "MAC Address", "Device", "Notes")
+
+	row := 1
+	for i := range a.ipList {
+		ip := &a.ipList[i]
+		if !matches(a.filters[a.ips], ip.Address, ip.Label, ip.MacAddress, ip.Device, ip.Notes) {
+			continue
+		}
+		a.ips.SetCell(row, 0, tview.NewTableCell(ip.Address).SetReference(ip))
+		a.ips.SetCell(row, 1, tview.NewTableCell(tview.Escape(ip.Label)))
+		a.ips.SetCell(row, 2, tview.NewTableCell(ip.MacAddress))
+		a.ips.SetCell(row, 3, tview.NewTableCell(tview.Escape(ip.Device)))
+		a.ips.SetCell(row, 4, tview.NewTableCell(tview.Escape(ip.Notes)).SetExpansion(1))
+		row++
+	}
+	a.ips.ScrollToBeginning()
+}
+
+// currentSubnet returns the subnet under the cursor of the subnet tree
+func (a *App) currentSubnet() *device42.Subnet {
+	n := a.subnets.GetCurrentNode()
+	if n == nil {
+		return nil
+	}
+	s, _ := n.GetReference().(*device42.Subnet)
+	return s
+}
+
+// currentIP returns the ip under the cursor of the ip pane
+func (a *App) currentIP() *device42.IP {
+	row, _ := a.ips.GetSelection()
+	if row < 1 {
+		return nil
+	}
+	ip, _ := a.ips.GetCell(row, 0).GetReference().(*device42.IP)
+	return ip
+}
+
+// startSearch will open the search field for the focused pane
+func (a *App) startSearch() {
+	focus := a.app.GetFocus()
+	if !a.isPane(focus) {
+		return
+	}
+
+	a.searching = focus
+	a.search.SetText(a.filters[focus])
+	a.footer.Clear().AddItem(a.search, 0, 1, true)
+	a.app.SetFocus(a.search)
+}
+
+// searchChanged filters the searched pane as the search is typed
+func (a *App) searchChanged(text string) {
+	if a.searching == nil {
+		return
+	}
+	a.filters[a.searching] = text
+	a.render(a.searching)
+}
+
+// searchDone keeps the filter on enter and drops it on escape
+func (a *App) searchDone(key tcell.Key) {
+	pane := a.searching
+	a.searching = nil
+	if key == tcell.KeyEscape {
+		delete(a.filters, pane)
+		a.render(pane)
+	}
+
+	a.footer.Clear().AddItem(a.status, 0, 1, false)
+	a.app.SetFocus(pane)
+}
+
+// render will redraw a single pane
+func (a *App) render(pane tview.Primitive) {
+	switch pane {
+	case a.vrfGroups:
+		a.renderVRFGroups()
+	case a.vlans:
+		a.renderVLANs()
+	case a.subnets:
+		a.renderSubnets()
+	case a.ips:
+		a.renderIPs()
+	}
+}
+
+func (a *App) isPane(p tview.Primitive) bool {
+	for _, pane := range a.panes {
+		if pane == p {
+			return true
+		}
+	}
+	return false
+}
+
+// header will set the header row of a table
+func header(t *tview.Table, columns ...string) {
+	for i, c := range columns {
+		t.SetCell(0, i, tview.NewTableCell(c).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+}
+
+// matches will check whether any field contains the filter, ignoring case
+func matches(filter string, fields ...string) bool {
+	if filter == "" {
+		return true
+	}
+	filter = strings.ToLower(filter)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// subnetText returns the network and name of a subnet
+func subnetText(s *device42.Subnet) string {
+	t := device42.NormalizeAddress(s.Network) + "/" + strconv.Itoa(s.MaskBits)
+	if s.Name != "" {
+		t += " " + s.Name
+	}
+	return t
+}
+
+// sortIPs will sort ips by address, unparsable addresses last
+func sortIPs(ips []device42.IP) {
+	sort.SliceStable(ips, func(i, j int) bool {
+		a, errA := ips[i].Addr()
+		b, errB := ips[j].Addr()
+		if errA != nil || errB != nil {
+			return errA == nil
+		}
+		return a.Less(b)
+	})
+}
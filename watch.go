@@ -0,0 +1,216 @@
+package device42
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// watch resources
+const (
+	WatchResourceIPs     = "ips"
+	WatchResourceSubnets = "subnets"
+)
+
+// watch event types
+const (
+	WatchEventAdded   = "added"
+	WatchEventUpdated = "updated"
+	WatchEventRemoved = "removed"
+	WatchEventError   = "error"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+// WatchEvent type
+type WatchEvent struct {
+	Type     string      `json:"type"`
+	Resource string      `json:"resource"`
+	ID       int         `json:"id,omitempty"`
+	Time     time.Time   `json:"time"`
+	Object   interface{} `json:"object,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// WatchFilter type
+// empty fields are not filtered on
+type WatchFilter struct {
+	// SubnetID of the ips to watch, or of the subnet and its children
+	SubnetID   int
+	VrfGroupID int
+	// Interval between polls (default 30s)
+	Interval time.Duration
+	// Initial emits an added event for every object found by the first poll
+	Initial bool
+}
+
+// watchObject is a snapshot of a single watched object
+type watchObject struct {
+	version string
+	object  interface{}
+}
+
+// Watch will poll a resource and emit an event whenever an object is added,
+// updated or removed. ips are compared by last_updated, other resources by
+// their content. poll errors are emitted as error events and the channel is
+// closed when the context is done
+func (api *API) Watch(ctx context.Context, resource string, f WatchFilter) (<-chan WatchEvent, error) {
+	var poll func() (map[int]watchObject, error)
+	switch resource {
+	case WatchResourceIPs:
+		poll = func() (map[int]watchObject, error) { return api.pollIPs(f) }
+	case WatchResourceSubnets:
+		poll = func() (map[int]watchObject, error) { return api.pollSubnets(f) }
+	default:
+		return nil, errors.New("unable to watch resource " + resource)
+	}
+
+	if f.Interval <= 0 {
+		f.Interval = defaultWatchInterval
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+
+		send := func(e WatchEvent) bool {
+			e.Resource = resource
+			e.Time = time.Now()
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var known map[int]watchObject
+		for {
+			current, err := poll()
+			if err != nil {
+				if !send(WatchEvent{Type: WatchEventError, Error: err.Error()}) {
+					return
+				}
+			} else {
+				if known == nil && !f.Initial {
+					known = current
+				}
+				for _, e := range diffWatchObjects(known, current) {
+					if !send(e) {
+						return
+					}
+				}
+				known = current
+			}
+
+			if err := sleepContext(ctx, f.Interval); err != nil {
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func (api *API) pollIPs(f WatchFilter) (map[int]watchObject, error) {
+	q := url.Values{}
+	if f.SubnetID != 0 {
+		q.Set("subnet_id", strconv.Itoa(f.SubnetID))
+	}
+	if f.VrfGroupID != 0 {
+		q.Set("vrf_group_id", strconv.Itoa(f.VrfGroupID))
+	}
+
+	path := "/ips/"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	b, err := api.Do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := IPs{}
+	err = json.Unmarshal(b, &ips)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[int]watchObject, len(ips.List))
+	for i := range ips.List {
+		ip := ips.List[i]
+		if f.SubnetID != 0 && ip.SubnetID != f.SubnetID {
+			continue
+		}
+		if f.VrfGroupID != 0 && ip.VRFGroupID != f.VrfGroupID {
+			continue
+		}
+
+		v := ip.LastUpdated.String()
+		if ip.LastUpdated.IsZero() {
+			v = watchVersion(ip)
+		}
+		objects[ip.ID] = watchObject{version: v, object: ip}
+	}
+
+	return objects, nil
+}
+
+func (api *API) pollSubnets(f WatchFilter) (map[int]watchObject, error) {
+	subnets, err := api.GetSubnets()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[int]watchObject, len(*subnets))
+	for _, s := range *subnets {
+		if f.SubnetID != 0 && s.SubnetID != f.SubnetID && s.ParentSubnetID != f.SubnetID {
+			continue
+		}
+		if f.VrfGroupID != 0 && s.VrfGroupID != f.VrfGroupID {
+			continue
+		}
+		objects[s.SubnetID] = watchObject{version: watchVersion(s), object: s}
+	}
+
+	return objects, nil
+}
+
+// diffWatchObjects will return the events between two snapshots, ordered by id
+func diffWatchObjects(known, current map[int]watchObject) []WatchEvent {
+	events := []WatchEvent{}
+
+	for id, c := range current {
+		k, ok := known[id]
+		switch {
+		case !ok:
+			events = append(events, WatchEvent{Type: WatchEventAdded, ID: id, Object: c.object})
+		case k.version != c.version:
+			events = append(events, WatchEvent{Type: WatchEventUpdated, ID: id, Object: c.object})
+		}
+	}
+	for id, k := range known {
+		if _, ok := current[id]; !ok {
+			events = append(events, WatchEvent{Type: WatchEventRemoved, ID: id, Object: k.object})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+
+	return events
+}
+
+// watchVersion fingerprints an object without a last updated time
+func watchVersion(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
@@ -0,0 +1,59 @@
+package device42
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestDiffWatchObjects(t *testing.T) {
+	snapshot := func(versions map[int]string) map[int]watchObject {
+		m := make(map[int]watchObject, len(versions))
+		for id, v := range versions {
+			m[id] = watchObject{version: v, object: id}
+		}
+		return m
+	}
+
+	tests := []struct {
+		name           string
+		known, current map[int]string
+		want           []string
+	}{
+		{name: "nothing", known: nil, current: nil, want: []string{}},
+		{name: "unchanged", known: map[int]string{1: "a"}, current: map[int]string{1: "a"}, want: []string{}},
+		{name: "first poll", known: nil, current: map[int]string{2: "a", 1: "b"}, want: []string{"added 1", "added 2"}},
+		{
+			name:    "added, updated and removed",
+			known:   map[int]string{1: "a", 2: "b", 3: "c"},
+			current: map[int]string{1: "a", 2: "x", 4: "d"},
+			want:    []string{"updated 2", "removed 3", "added 4"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := []string{}
+		for _, e := range diffWatchObjects(snapshot(tt.known), snapshot(tt.current)) {
+			if e.Object != e.ID {
+				t.Errorf("%s: event %s %d carries object %v", tt.name, e.Type, e.ID, e.Object)
+			}
+			got = append(got, e.Type+" "+strconv.Itoa(e.ID))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: diffWatchObjects = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWatchVersion(t *testing.T) {
+	a := Subnet{SubnetID: 1, Name: "web"}
+	b := Subnet{SubnetID: 1, Name: "web"}
+	c := Subnet{SubnetID: 1, Name: "db"}
+
+	if watchVersion(a) != watchVersion(b) {
+		t.Error("watchVersion differs for equal subnets")
+	}
+	if watchVersion(a) == watchVersion(c) {
+		t.Error("watchVersion is equal for different subnets")
+	}
+}
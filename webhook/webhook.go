@@ -0,0 +1,328 @@
+// Package webhook receives device42 webhook notifications and dispatches them
+// as typed events
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	device42 "github.com/chopnico/device42-go"
+)
+
+// object types
+const (
+	ObjectIP       = "ip"
+	ObjectSubnet   = "subnet"
+	ObjectVLAN     = "vlan"
+	ObjectBuilding = "building"
+)
+
+// actions
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// TokenHeader is the header checked against the handler's token
+const TokenHeader = "X-Device42-Token"
+
+const defaultMaxBodySize = 1 << 20
+
+// ErrUnsupported is returned for payloads of a category or action that is
+// not turned into events
+var ErrUnsupported = errors.New("unsupported webhook event")
+
+// objectTypes maps device42 categories to object types
+var objectTypes = map[string]string{
+	"ip":         ObjectIP,
+	"ips":        ObjectIP,
+	"ip address": ObjectIP,
+	"ipaddress":  ObjectIP,
+	"subnet":     ObjectSubnet,
+	"subnets":    ObjectSubnet,
+	"vlan":       ObjectVLAN,
+	"vlans":      ObjectVLAN,
+	"building":   ObjectBuilding,
+	"buildings":  ObjectBuilding,
+}
+
+// actions maps device42 actions to actions
+var actions = map[string]string{
+	"add":     ActionCreated,
+	"create":  ActionCreated,
+	"created": ActionCreated,
+	"insert":  ActionCreated,
+	"change":  ActionUpdated,
+	"changed": ActionUpdated,
+	"update":  ActionUpdated,
+	"updated": ActionUpdated,
+	"delete":  ActionDeleted,
+	"deleted": ActionDeleted,
+	"remove":  ActionDeleted,
+}
+
+// Event type
+// exactly one of IP, Subnet, VLAN or Building is set
+type Event struct {
+	// Type is "<object>.<action>", e.g. "subnet.updated"
+	Type     string             `json:"type"`
+	Object   string             `json:"object"`
+	Action   string             `json:"action"`
+	ObjectID int                `json:"object_id"`
+	Time     time.Time          `json:"time"`
+	IP       *device42.IP       `json:"ip,omitempty"`
+	Subnet   *device42.Subnet   `json:"subnet,omitempty"`
+	VLAN     *device42.VLAN     `json:"vlan,omitempty"`
+	Building *device42.Building `json:"building,omitempty"`
+	Raw      json.RawMessage    `json:"raw"`
+}
+
+// payload is a device42 webhook body
+type payload struct {
+	Category   string          `json:"category"`
+	ObjectType string          `json:"object_type"`
+	Action     string          `json:"action"`
+	Timestamp  string          `json:"timestamp"`
+	Data       json.RawMessage `json:"data"`
+	Object     json.RawMessage `json:"object"`
+	Objects    json.RawMessage `json:"objects"`
+}
+
+// Decode will decode a webhook body into events. a body may carry a single
+// payload or a list of them, and each payload a single object or a list.
+// payloads of unsupported categories or actions are skipped
+func Decode(b []byte) ([]Event, error) {
+	payloads := []payload{}
+	if err := json.Unmarshal(b, &payloads); err != nil {
+		p := payload{}
+		if err := json.Unmarshal(b, &p); err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, p)
+	}
+
+	events := []Event{}
+	for _, p := range payloads {
+		e, err := p.events()
+		if errors.Is(err, ErrUnsupported) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e...)
+	}
+
+	return events, nil
+}
+
+func (p *payload) events() ([]Event, error) {
+	category := p.Category
+	if category == "" {
+		category = p.ObjectType
+	}
+	object, ok := objectTypes[strings.ToLower(category)]
+	if !ok {
+		return nil, fmt.Errorf("%w: category %s", ErrUnsupported, category)
+	}
+	action, ok := actions[strings.ToLower(p.Action)]
+	if !ok {
+		return nil, fmt.Errorf("%w: action %s", ErrUnsupported, p.Action)
+	}
+
+	t := time.Now()
+	if p.Timestamp != "" {
+		if s, err := strconv.ParseInt(p.Timestamp, 10, 64); err == nil {
+			t = time.Unix(s, 0)
+		} else if pt, err := time.Parse(time.RFC3339, p.Timestamp); err == nil {
+			t = pt
+		}
+	}
+
+	raw := []json.RawMessage{}
+	for _, d := range []json.RawMessage{p.Data, p.Object, p.Objects} {
+		if len(d) == 0 || string(d) == "null" {
+			continue
+		}
+		list := []json.RawMessage{}
+		if err := json.Unmarshal(d, &list); err != nil {
+			list = append(list, d)
+		}
+		raw = append(raw, list...)
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("webhook payload has no object")
+	}
+
+	events := []Event{}
+	for _, r := range raw {
+		e := Event{
+			Type:   object + "." + action,
+			Object: object,
+			Action: action,
+			Time:   t,
+			Raw:    r,
+		}
+
+		var err error
+		switch object {
+		case ObjectIP:
+			e.IP = &device42.IP{}
+			err = json.Unmarshal(r, e.IP)
+			e.ObjectID = e.IP.ID
+		case ObjectSubnet:
+			e.Subnet = &device42.Subnet{}
+			err = json.Unmarshal(r, e.Subnet)
+			e.ObjectID = e.Subnet.SubnetID
+		case ObjectVLAN:
+			e.VLAN = &device42.VLAN{}
+			err = json.Unmarshal(r, e.VLAN)
+			e.ObjectID = e.VLAN.VlanID
+		case ObjectBuilding:
+			e.Building = &device42.Building{}
+			err = json.Unmarshal(r, e.Building)
+			e.ObjectID = e.Building.BuildingID
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// HandlerFunc handles a single event
+type HandlerFunc func(ctx context.Context, e Event) error
+
+// Dispatcher type
+// routes events to the handlers registered for their type
+type Dispatcher struct {
+	mu       sync.RWMutex
+	handlers map[string][]HandlerFunc
+}
+
+// NewDispatcher creates a new dispatcher
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		handlers: make(map[string][]HandlerFunc),
+	}
+}
+
+// On will register a handler for an event type. the type may be an exact
+// type ("ip.created"), every action of an object ("ip.*") or every event ("*")
+func (d *Dispatcher) On(eventType string, fn HandlerFunc) *Dispatcher {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.handlers[eventType] = append(d.handlers[eventType], fn)
+	return d
+}
+
+// Match checks if an event type as given to On matches an event
+func Match(eventType string, e Event) bool {
+	return eventType == e.Type || eventType == e.Object+".*" || eventType == "*"
+}
+
+// Dispatch will call every handler matching the event. all handlers are
+// called and the first error is returned
+func (d *Dispatcher) Dispatch(ctx context.Context, e Event) error {
+	d.mu.RLock()
+	handlers := []HandlerFunc{}
+	for _, k := range []string{e.Type, e.Object + ".*", "*"} {
+		handlers = append(handlers, d.handlers[k]...)
+	}
+	d.mu.RUnlock()
+
+	var first error
+	for _, fn := range handlers {
+		if err := fn(ctx, e); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Handler type
+// an http.Handler that decodes device42 webhooks and dispatches their events
+type Handler struct {
+	dispatcher  *Dispatcher
+	token       string
+	maxBodySize int64
+}
+
+// NewHandler creates a new handler dispatching to d
+func NewHandler(d *Dispatcher) *Handler {
+	return &Handler{
+		dispatcher:  d,
+		maxBodySize: defaultMaxBodySize,
+	}
+}
+
+// Token will require requests to carry the token in the TokenHeader header
+// or as a bearer token
+func (h *Handler) Token(v string) *Handler {
+	h.token = v
+	return h
+}
+
+// MaxBodySize sets the largest accepted body in bytes
+func (h *Handler) MaxBodySize(v int64) *Handler {
+	h.maxBodySize = v
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.token != "" && !h.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	events, err := Decode(b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, e := range events {
+		if err := h.dispatcher.Dispatch(r.Context(), e); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	t := r.Header.Get(TokenHeader)
+	if t == "" {
+		t = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(t), []byte(h.token)) == 1
+}
@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "single object",
+			body: `{"category": "IP Address", "action": "add", "data": {"id": 5, "ip": "10.0.0.1"}}`,
+			want: []string{"ip.created 5"},
+		},
+		{
+			name: "object type and list of objects",
+			body: `{"object_type": "subnets", "action": "change", "objects": [{"subnet_id": 1}, {"subnet_id": 2}]}`,
+			want: []string{"subnet.updated 1", "subnet.updated 2"},
+		},
+		{
+			name: "list of payloads",
+			body: `[{"category": "vlan", "action": "delete", "object": {"vlan_id": 3}}, {"category": "building", "action": "created", "data": {"building_id": 4}}]`,
+			want: []string{"vlan.deleted 3", "building.created 4"},
+		},
+		{
+			name: "unsupported payloads are skipped",
+			body: `[{"category": "device", "action": "add", "data": {"id": 1}}, {"category": "ip", "action": "archive", "data": {"id": 2}}, {"category": "ip", "action": "remove", "data": {"id": 3}}]`,
+			want: []string{"ip.deleted 3"},
+		},
+		{
+			name: "nothing supported",
+			body: `{"category": "device", "action": "add", "data": {"id": 1}}`,
+			want: []string{},
+		},
+		{name: "no object", body: `{"category": "ip", "action": "add"}`, wantErr: true},
+		{name: "invalid json", body: `{`, wantErr: true},
+		{name: "invalid object", body: `{"category": "ip", "action": "add", "data": {"id": "x"}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		events, err := Decode([]byte(tt.body))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Decode error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		got := []string{}
+		for _, e := range events {
+			got = append(got, e.Type+" "+strconv.Itoa(e.ObjectID))
+			if e.Type != e.Object+"."+e.Action || len(e.Raw) == 0 {
+				t.Errorf("%s: malformed event %+v", tt.name, e)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Decode = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeTimestamp(t *testing.T) {
+	tests := []struct {
+		timestamp string
+		want      time.Time
+	}{
+		{timestamp: `"1600000000"`, want: time.Unix(1600000000, 0)},
+		{timestamp: `"2020-09-13T12:26:40Z"`, want: time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		events, err := Decode([]byte(`{"category": "ip", "action": "add", "timestamp": ` + tt.timestamp + `, "data": {"id": 1}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !events[0].Time.Equal(tt.want) {
+			t.Errorf("timestamp %s = %s, want %s", tt.timestamp, events[0].Time, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	e := Event{Type: "ip.created", Object: ObjectIP, Action: ActionCreated}
+
+	tests := []struct {
+		eventType string
+		want      bool
+	}{
+		{eventType: "ip.created", want: true},
+		{eventType: "ip.*", want: true},
+		{eventType: "*", want: true},
+		{eventType: "ip.deleted", want: false},
+		{eventType: "subnet.*", want: false},
+		{eventType: "*.created", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.eventType, e); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.eventType, got, tt.want)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	called := []string{}
+	record := func(name string, err error) HandlerFunc {
+		return func(ctx context.Context, e Event) error {
+			called = append(called, name)
+			return err
+		}
+	}
+
+	first := errors.New("first")
+	d := NewDispatcher().
+		On("*", record("all", nil)).
+		On("ip.created", record("exact", first)).
+		On("ip.*", record("object", errors.New("second"))).
+		On("subnet.*", record("other", nil))
+
+	err := d.Dispatch(context.Background(), Event{Type: "ip.created", Object: ObjectIP, Action: ActionCreated})
+	if !errors.Is(err, first) {
+		t.Errorf("Dispatch error = %v, want %v", err, first)
+	}
+	if want := []string{"exact", "object", "all"}; !reflect.DeepEqual(called, want) {
+		t.Errorf("Dispatch called %v, want %v", called, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	failing := errors.New("failed")
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		header map[string]string
+		body   string
+		fail   bool
+		want   int
+		events int
+	}{
+		{name: "event", method: "POST", body: `{"category": "ip", "action": "add", "data": {"id": 1}}`, want: http.StatusNoContent, events: 1},
+		{name: "unsupported event", method: "POST", body: `{"category": "device", "action": "add", "data": {"id": 1}}`, want: http.StatusNoContent},
+		{name: "get", method: "GET", want: http.StatusMethodNotAllowed},
+		{name: "invalid body", method: "POST", body: `{`, want: http.StatusBadRequest},
+		{name: "too large", method: "POST", body: `{"category": "ip", "action": "add", "data": {"id": 1, "notes": "` + strings.Repeat("x", 2<<20) + `"}}`, want: http.StatusRequestEntityTooLarge},
+		{name: "handler error", method: "POST", body: `{"category": "ip", "action": "add", "data": {"id": 1}}`, fail: true, want: http.StatusInternalServerError, events: 1},
+		{name: "missing token", method: "POST", token: "secret", body: `{"category": "ip", "action": "add", "data": {"id": 1}}`, want: http.StatusUnauthorized},
+		{name: "wrong token", method: "POST", token: "secret", header: map[string]string{TokenHeader: "nope"}, body: `[]`, want: http.StatusUnauthorized},
+		{name: "token header", method: "POST", token: "secret", header: map[string]string{TokenHeader: "secret"}, body: `[]`, want: http.StatusNoContent},
+		{name: "bearer token", method: "POST", token: "secret", header: map[string]string{"Authorization": "Bearer secret"}, body: `[]`, want: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		events := 0
+		d := NewDispatcher().On("*", func(ctx context.Context, e Event) error {
+			events++
+			if tt.fail {
+				return failing
+			}
+			return nil
+		})
+		h := NewHandler(d).Token(tt.token)
+
+		r := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+		for k, v := range tt.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if events != tt.events {
+			t.Errorf("%s: handled %d events, want %d", tt.name, events, tt.events)
+		}
+	}
+}